@@ -0,0 +1,107 @@
+// Package apperror defines the domain errors returned by services. Every
+// error has a Kind, which the HTTP layer maps to a status, and a stable Code
+// that clients can match on instead of the message:
+//
+//	var ErrReportNotFound = apperror.New(apperror.NotFound, "report_not_found", "report not found")
+//
+//	if errors.Is(err, apperror.NotFound) { ... }
+//	if errors.Is(err, ErrReportNotFound) { ... }
+//
+// Errors without a Kind are internal errors.
+package apperror
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Kind classifies an error. It is itself an error so that errors.Is matches
+// any error of a kind.
+type Kind string
+
+const (
+	Internal       Kind = "internal"
+	NotFound       Kind = "not_found"
+	Inactive       Kind = "inactive"
+	Validation     Kind = "validation"
+	Conflict       Kind = "conflict"
+	Upstream       Kind = "upstream"
+	Unauthorized   Kind = "unauthorized"
+	Gone           Kind = "gone"
+	NotImplemented Kind = "not_implemented"
+	// TooManyRequests refuses attempts until a lockout passes.
+	TooManyRequests Kind = "too_many_requests"
+	// TooLarge and UnsupportedMediaType reject uploads.
+	TooLarge             Kind = "too_large"
+	UnsupportedMediaType Kind = "unsupported_media_type"
+)
+
+func (k Kind) Error() string { return string(k) }
+
+type Error struct {
+	Kind    Kind
+	Code    string
+	Message string
+	// Err is the underlying cause, appended to the message.
+	Err error
+}
+
+// New returns an error of the given kind and code.
+func New(kind Kind, code, message string) *Error {
+	return &Error{Kind: kind, Code: code, Message: message}
+}
+
+// Wrap classifies err. The message defaults to the one of err.
+func Wrap(kind Kind, code string, err error) *Error {
+	return &Error{Kind: kind, Code: code, Err: err}
+}
+
+// Validationf is a shorthand for the many input checks of the services. The
+// message is formatted as with fmt.Sprintf.
+func Validationf(code, format string, args ...any) *Error {
+	return New(Validation, code, fmt.Sprintf(format, args...))
+}
+
+func (e *Error) Error() string {
+	switch {
+	case e.Err == nil:
+		return e.Message
+	case e.Message == "":
+		return e.Err.Error()
+	default:
+		return e.Message + ": " + e.Err.Error()
+	}
+}
+
+func (e *Error) Unwrap() error { return e.Err }
+
+// Is matches the kind of the error as well as the error itself.
+func (e *Error) Is(target error) bool {
+	kind, ok := target.(Kind)
+	return ok && kind == e.Kind
+}
+
+// As returns the first Error in err's chain.
+func As(err error) (*Error, bool) {
+	var appErr *Error
+	ok := errors.As(err, &appErr)
+	return appErr, ok
+}
+
+// KindOf returns the kind of the first Error in err's chain, Internal when
+// there is none.
+func KindOf(err error) Kind {
+	if appErr, ok := As(err); ok {
+		return appErr.Kind
+	}
+	return Internal
+}
+
+// CodeOf returns the code of the first Error in err's chain, or the code of
+// its kind when it has none.
+func CodeOf(err error) string {
+	if appErr, ok := As(err); ok && appErr.Code != "" {
+		return appErr.Code
+	}
+	return string(KindOf(err))
+}
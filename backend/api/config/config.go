@@ -1,19 +1,247 @@
+// Package config holds the settings of the API. Every setting is a field of
+// Config and can be given, from lowest to highest precedence, as its default,
+// in a YAML file, as an environment variable or as a command line flag:
+//
+//	server:
+//	  port: "8080"        # PORT, --server.port
+//
+// The struct tags drive the loading: yaml is the key in the file and, joined
+// with the keys of the enclosing sections, the name of the flag; env is the
+// environment variable; default is the value used when no source sets one;
+// secret masks the value in "config print".
 package config
 
 import (
-	"reportia/helper"
+	"crypto/rand"
+	"encoding/hex"
+	"reportia/database"
+	"reportia/upload"
+	"time"
+)
+
+const (
+	StorageModeDatabase = "database"
+	StorageModeInMemory = "in-memory"
 )
 
 type Config struct {
-	Port          string
-	DbURL         string
-	AllowedOrigin string
+	Server      ServerConfig      `yaml:"server"`
+	Database    DatabaseConfig    `yaml:"database"`
+	CORS        CORSConfig        `yaml:"cors"`
+	LLM         LLMConfig         `yaml:"llm"`
+	Limits      LimitsConfig      `yaml:"limits"`
+	Scheduler   SchedulerConfig   `yaml:"scheduler"`
+	DataSources DataSourcesConfig `yaml:"data_sources"`
+	SMTP        SMTPConfig        `yaml:"smtp"`
+	Delivery    DeliveryConfig    `yaml:"delivery"`
+	Webhooks    WebhooksConfig    `yaml:"webhooks"`
+	Share       ShareConfig       `yaml:"share"`
+	Assets      AssetsConfig      `yaml:"assets"`
+	Retention   RetentionConfig   `yaml:"retention"`
+	Renderer    RendererConfig    `yaml:"renderer"`
+	Admin       AdminConfig       `yaml:"admin"`
+	Log         LogConfig         `yaml:"log"`
+	Tracing     TracingConfig     `yaml:"tracing"`
+}
+
+type ServerConfig struct {
+	Port          string `yaml:"port" env:"PORT" default:"8080"`
+	PublicBaseURL string `yaml:"public_base_url" env:"PUBLIC_BASE_URL" default:"http://localhost:8080"`
+	// StorageMode in-memory serves the report routes without a database.
+	StorageMode       string        `yaml:"storage_mode" env:"STORAGE_MODE" default:"database"`
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout" env:"HTTP_READ_HEADER_TIMEOUT" default:"10s"`
+	ReadTimeout       time.Duration `yaml:"read_timeout" env:"HTTP_READ_TIMEOUT" default:"1m"`
+	WriteTimeout      time.Duration `yaml:"write_timeout" env:"HTTP_WRITE_TIMEOUT" default:"5m"`
+	IdleTimeout       time.Duration `yaml:"idle_timeout" env:"HTTP_IDLE_TIMEOUT" default:"2m"`
+	ShutdownTimeout   time.Duration `yaml:"shutdown_timeout" env:"SHUTDOWN_TIMEOUT" default:"2m"`
+	ShutdownDelay     time.Duration `yaml:"shutdown_delay" env:"SHUTDOWN_DELAY" default:"0s"`
+}
+
+type DatabaseConfig struct {
+	Driver          string        `yaml:"driver" env:"DB_DRIVER" default:"postgres"`
+	URL             string        `yaml:"url" env:"DB_URL" secret:"true"`
+	MigrateOnStart  bool          `yaml:"migrate_on_start" env:"MIGRATE_ON_START" default:"true"`
+	MaxOpenConns    int           `yaml:"max_open_conns" env:"DB_MAX_OPEN_CONNS" default:"25"`
+	MaxIdleConns    int           `yaml:"max_idle_conns" env:"DB_MAX_IDLE_CONNS" default:"10"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime" env:"DB_CONN_MAX_LIFETIME" default:"30m"`
+	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time" env:"DB_CONN_MAX_IDLE_TIME" default:"5m"`
+	ConnectTimeout  time.Duration `yaml:"connect_timeout" env:"DB_CONNECT_TIMEOUT" default:"30s"`
+}
+
+type CORSConfig struct {
+	// AllowedOrigins are exact origins or patterns such as
+	// https://*.example.com for preview deployments.
+	AllowedOrigins   []string      `yaml:"allowed_origins" env:"CORS_ALLOWED_ORIGINS" default:"http://localhost:5173"`
+	AllowedMethods   []string      `yaml:"allowed_methods" env:"CORS_ALLOWED_METHODS" default:"GET,POST,PUT,PATCH,DELETE"`
+	AllowedHeaders   []string      `yaml:"allowed_headers" env:"CORS_ALLOWED_HEADERS" default:"Content-Type,Authorization,X-Request-ID"`
+	ExposedHeaders   []string      `yaml:"exposed_headers" env:"CORS_EXPOSED_HEADERS" default:"X-Request-ID,Deprecation,Link,RateLimit-Limit,RateLimit-Remaining,RateLimit-Reset,Retry-After"`
+	AllowCredentials bool          `yaml:"allow_credentials" env:"CORS_ALLOW_CREDENTIALS" default:"true"`
+	MaxAge           time.Duration `yaml:"max_age" env:"CORS_MAX_AGE" default:"10m"`
+}
+
+type LLMConfig struct {
+	OpenAI    LLMProviderConfig `yaml:"openai" env:"OPENAI"`
+	Anthropic LLMProviderConfig `yaml:"anthropic" env:"ANTHROPIC"`
+	Gemini    GeminiConfig      `yaml:"gemini" env:"GEMINI"`
+}
+
+// LLMProviderConfig is shared by the providers; the env tag of the provider
+// prefixes the variables, as in OPENAI_API_KEY.
+type LLMProviderConfig struct {
+	APIKey       string `yaml:"api_key" env:"API_KEY" secret:"true"`
+	DefaultModel string `yaml:"default_model" env:"MODEL_DEFAULT"`
+	// Models are labelled by name in the metrics besides DefaultModel; the
+	// others are counted as "other".
+	Models []string `yaml:"models" env:"MODELS"`
+}
+
+// GeminiConfig adds the generation settings of Gemini to those of the other
+// providers. Unset settings are left to the model.
+type GeminiConfig struct {
+	APIKey       string   `yaml:"api_key" env:"API_KEY" secret:"true"`
+	DefaultModel string   `yaml:"default_model" env:"MODEL_DEFAULT"`
+	Models       []string `yaml:"models" env:"MODELS"`
+	// BaseURL replaces the endpoint of the Gemini API, for a proxy or a
+	// local stand-in.
+	BaseURL         string   `yaml:"base_url" env:"BASE_URL"`
+	Temperature     *float64 `yaml:"temperature" env:"TEMPERATURE"`
+	MaxOutputTokens int      `yaml:"max_output_tokens" env:"MAX_OUTPUT_TOKENS" default:"0"`
+	// SafetyThreshold blocks the answers rated at or above it in every harm
+	// category, such as BLOCK_ONLY_HIGH.
+	SafetyThreshold string `yaml:"safety_threshold" env:"SAFETY_THRESHOLD"`
+	// FileTimeout bounds the wait for an uploaded file to be processed.
+	FileTimeout time.Duration `yaml:"file_timeout" env:"FILE_TIMEOUT" default:"2m"`
+}
+
+type LimitsConfig struct {
+	// MaxUploadSize bounds the report files, template bundles and assets uploaded.
+	MaxUploadSize int64 `yaml:"max_upload_size" env:"MAX_UPLOAD_SIZE" default:"10485760"`
+	// MaxZipEntries and MaxUncompressedSize reject zip bombs among uploaded
+	// archives and office documents.
+	MaxZipEntries       int   `yaml:"max_zip_entries" env:"UPLOAD_MAX_ZIP_ENTRIES" default:"1000"`
+	MaxUncompressedSize int64 `yaml:"max_uncompressed_size" env:"UPLOAD_MAX_UNCOMPRESSED_SIZE" default:"104857600"`
+	// UploadFileTypes are the extensions and content types accepted for the
+	// reports that do not set their own.
+	UploadFileTypes []string `yaml:"upload_file_types" env:"UPLOAD_FILE_TYPES" default:".csv,.tsv,.txt,.md,.json,.pdf,.xlsx,.docx,text/*,application/json,application/pdf,application/vnd.openxmlformats-officedocument.spreadsheetml.sheet,application/vnd.openxmlformats-officedocument.wordprocessingml.document"`
+}
+
+type SchedulerConfig struct {
+	Enabled  bool          `yaml:"enabled" env:"SCHEDULER_ENABLED" default:"true"`
+	Interval time.Duration `yaml:"interval" env:"SCHEDULER_INTERVAL" default:"1m"`
+	// SourceDir is the only directory the source_path of a schedule may
+	// point into.
+	SourceDir string `yaml:"source_dir" env:"SCHEDULE_SOURCE_DIR" default:"./data/schedules"`
+}
+
+type DataSourcesConfig struct {
+	// DirectoryRoot is the directory that directory data sources must be in.
+	DirectoryRoot string `yaml:"directory_root" env:"DATASOURCE_DIRECTORY_ROOT" default:"./data/sources"`
+	// AllowedHosts, when set, are the only hosts HTTP and S3 data sources may
+	// reach; an entry such as .example.com matches its subdomains.
+	AllowedHosts []string `yaml:"allowed_hosts" env:"DATASOURCE_ALLOWED_HOSTS"`
+	// AllowPrivateNetworks lets them reach loopback, private and link-local
+	// addresses, which are refused by default.
+	AllowPrivateNetworks bool `yaml:"allow_private_networks" env:"DATASOURCE_ALLOW_PRIVATE_NETWORKS" default:"false"`
+}
+
+type SMTPConfig struct {
+	Host     string `yaml:"host" env:"SMTP_HOST"`
+	Port     string `yaml:"port" env:"SMTP_PORT" default:"1025"`
+	Username string `yaml:"username" env:"SMTP_USERNAME"`
+	Password string `yaml:"password" env:"SMTP_PASSWORD" secret:"true"`
+	From     string `yaml:"from" env:"SMTP_FROM" default:"reportia@localhost"`
+	TLSMode  string `yaml:"tls_mode" env:"SMTP_TLS_MODE" default:"none"`
+}
+
+type DeliveryConfig struct {
+	DryRun      bool `yaml:"dry_run" env:"DELIVERY_DRY_RUN" default:"false"`
+	MaxAttempts int  `yaml:"max_attempts" env:"DELIVERY_MAX_ATTEMPTS" default:"3"`
 }
 
-func Load() *Config {
-	return &Config{
-		Port:          helper.GetEnv("PORT", "8080"),
-		DbURL:         helper.GetEnv("DB_URL", ""),
-		AllowedOrigin: helper.GetEnv("CORS_ALLOWED_ORIGIN", "http://localhost:5173"),
+type WebhooksConfig struct {
+	DispatchInterval time.Duration `yaml:"dispatch_interval" env:"WEBHOOK_DISPATCH_INTERVAL" default:"5s"`
+	MaxAttempts      int           `yaml:"max_attempts" env:"WEBHOOK_MAX_ATTEMPTS" default:"8"`
+	// AllowedHosts and AllowPrivateNetworks bound the subscription URLs, as
+	// for data sources.
+	AllowedHosts         []string `yaml:"allowed_hosts" env:"WEBHOOK_ALLOWED_HOSTS"`
+	AllowPrivateNetworks bool     `yaml:"allow_private_networks" env:"WEBHOOK_ALLOW_PRIVATE_NETWORKS" default:"false"`
+}
+
+type ShareConfig struct {
+	// LinkSecret signs share links. When empty a random secret is generated
+	// at startup, which invalidates the links on every restart.
+	LinkSecret string `yaml:"link_secret" env:"SHARE_LINK_SECRET" secret:"true"`
+}
+
+type AssetsConfig struct {
+	Storage string         `yaml:"storage" env:"ASSET_STORAGE" default:"local"`
+	Dir     string         `yaml:"dir" env:"ASSET_DIR" default:"./data/assets"`
+	S3      AssetsS3Config `yaml:"s3" env:"ASSET_S3"`
+}
+
+type AssetsS3Config struct {
+	Endpoint  string `yaml:"endpoint" env:"ENDPOINT"`
+	Bucket    string `yaml:"bucket" env:"BUCKET"`
+	Prefix    string `yaml:"prefix" env:"PREFIX"`
+	Region    string `yaml:"region" env:"REGION"`
+	UseSSL    bool   `yaml:"use_ssl" env:"USE_SSL" default:"true"`
+	AccessKey string `yaml:"access_key" env:"ACCESS_KEY" secret:"true"`
+	SecretKey string `yaml:"secret_key" env:"SECRET_KEY" secret:"true"`
+}
+
+type RetentionConfig struct {
+	// ReportRetention is how long deleted reports stay in the trash; 0 keeps
+	// them forever.
+	ReportRetention time.Duration `yaml:"report_retention" env:"REPORT_RETENTION" default:"720h"`
+	Interval        time.Duration `yaml:"interval" env:"RETENTION_INTERVAL" default:"1h"`
+}
+
+type RendererConfig struct {
+	ChromePath string `yaml:"chrome_path" env:"RENDERER_CHROME_PATH"`
+}
+
+type AdminConfig struct {
+	// Token enables the admin routes when set.
+	Token string `yaml:"token" env:"ADMIN_TOKEN" secret:"true"`
+}
+
+type LogConfig struct {
+	Level string `yaml:"level" env:"LOG_LEVEL" default:"info"`
+	// Redacted on top of the credentials that are always redacted.
+	RedactHeaders []string `yaml:"redact_headers" env:"LOG_REDACT_HEADERS"`
+	RedactFields  []string `yaml:"redact_fields" env:"LOG_REDACT_FIELDS"`
+	BodyLimit     int      `yaml:"body_limit" env:"LOG_BODY_LIMIT" default:"500"`
+}
+
+type TracingConfig struct {
+	Exporter string `yaml:"exporter" env:"OTEL_TRACES_EXPORTER" default:"none"`
+}
+
+// DatabaseOptions returns the settings of the connection pool.
+func (c *Config) DatabaseOptions() database.Options {
+	return database.Options{
+		Driver:          c.Database.Driver,
+		URL:             c.Database.URL,
+		MaxOpenConns:    c.Database.MaxOpenConns,
+		MaxIdleConns:    c.Database.MaxIdleConns,
+		ConnMaxLifetime: c.Database.ConnMaxLifetime,
+		ConnMaxIdleTime: c.Database.ConnMaxIdleTime,
+		ConnectTimeout:  c.Database.ConnectTimeout,
 	}
 }
+
+// UploadLimits returns the limits applied to uploaded files.
+func (c *Config) UploadLimits() upload.Limits {
+	return upload.Limits{
+		MaxSize:             c.Limits.MaxUploadSize,
+		MaxZipEntries:       c.Limits.MaxZipEntries,
+		MaxUncompressedSize: c.Limits.MaxUncompressedSize,
+	}
+}
+
+// RandomSecret returns a secret for the current process only.
+func RandomSecret() string {
+	random := make([]byte, 32)
+	rand.Read(random)
+	return hex.EncodeToString(random)
+}
@@ -0,0 +1,251 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FileEnv names the YAML file to load when --config is not given.
+const FileEnv = "REPORTIA_CONFIG"
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// setting is a leaf field of Config.
+type setting struct {
+	// path is the dotted YAML path, which is also the flag name.
+	path   string
+	env    string
+	def    string
+	secret bool
+	value  reflect.Value
+}
+
+// settings lists the leaf fields of the struct v points into.
+func settings(v reflect.Value, path, env string) []setting {
+	var list []setting
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		fieldPath := field.Tag.Get("yaml")
+		if path != "" {
+			fieldPath = path + "." + fieldPath
+		}
+		fieldEnv := field.Tag.Get("env")
+		if env != "" && fieldEnv != "" {
+			fieldEnv = env + "_" + fieldEnv
+		}
+		if field.Type.Kind() == reflect.Struct && field.Type != durationType {
+			list = append(list, settings(v.Field(i), fieldPath, fieldEnv)...)
+			continue
+		}
+		list = append(list, setting{
+			path:   fieldPath,
+			env:    fieldEnv,
+			def:    field.Tag.Get("default"),
+			secret: field.Tag.Get("secret") == "true",
+			value:  v.Field(i),
+		})
+	}
+	return list
+}
+
+// set parses s into the field.
+func (s setting) set(value string) error {
+	v := s.value
+	switch {
+	case v.Type() == durationType:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", value)
+		}
+		v.SetInt(int64(d))
+	case v.Kind() == reflect.String:
+		v.SetString(value)
+	case v.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", value)
+		}
+		v.SetBool(b)
+	case v.Kind() == reflect.Int || v.Kind() == reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", value)
+		}
+		v.SetInt(n)
+	case v.Kind() == reflect.Float32 || v.Kind() == reflect.Float64:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", value)
+		}
+		v.SetFloat(f)
+	case v.Kind() == reflect.Pointer:
+		// Pointers are optional settings, nil until set to a value.
+		if value == "" {
+			v.SetZero()
+			break
+		}
+		elem := reflect.New(v.Type().Elem())
+		if err := (setting{value: elem.Elem()}).set(value); err != nil {
+			return err
+		}
+		v.Set(elem)
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.String:
+		var list []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+		v.Set(reflect.ValueOf(list))
+	default:
+		return fmt.Errorf("unsupported setting type %s", v.Type())
+	}
+	return nil
+}
+
+func (s setting) String() string {
+	v := s.value
+	switch {
+	case v.Type() == durationType:
+		return time.Duration(v.Int()).String()
+	case v.Kind() == reflect.Slice:
+		return strings.Join(v.Interface().([]string), ",")
+	case v.Kind() == reflect.Pointer:
+		if v.IsNil() {
+			return ""
+		}
+		return setting{value: v.Elem()}.String()
+	default:
+		return fmt.Sprint(v.Interface())
+	}
+}
+
+func (s setting) name() string {
+	if s.env == "" {
+		return s.path
+	}
+	return s.path + " (" + s.env + ")"
+}
+
+// flagValue defers a flag until the other sources are applied, since flags
+// take precedence over them.
+type flagValue struct {
+	setting setting
+	pending *[]func() error
+}
+
+func (f flagValue) String() string {
+	if !f.setting.value.IsValid() {
+		return ""
+	}
+	return f.setting.String()
+}
+
+func (f flagValue) Set(value string) error {
+	// Fail on malformed values right away, for the usage message of flag.
+	probe := setting{value: reflect.New(f.setting.value.Type()).Elem()}
+	if err := probe.set(value); err != nil {
+		return err
+	}
+	*f.pending = append(*f.pending, func() error { return f.setting.set(value) })
+	return nil
+}
+
+// Load builds the configuration from the defaults, the YAML file given with
+// --config or REPORTIA_CONFIG, the environment and the flags in args, in
+// increasing order of precedence, and validates it. It returns the arguments
+// left after the flags, such as a subcommand. With -h it prints the usage to
+// stderr and returns flag.ErrHelp.
+func Load(args []string) (*Config, []string, error) {
+	cfg := &Config{}
+	all := settings(reflect.ValueOf(cfg).Elem(), "", "")
+	for _, s := range all {
+		if s.def == "" {
+			continue
+		}
+		if err := s.set(s.def); err != nil {
+			return nil, nil, fmt.Errorf("default of %s: %w", s.name(), err)
+		}
+	}
+
+	fs := flag.NewFlagSet("reportia", flag.ContinueOnError)
+	// Errors are returned to the caller rather than printed with the long
+	// usage, which is only printed for -h.
+	fs.SetOutput(io.Discard)
+	fs.Usage = func() {}
+	file := fs.String("config", os.Getenv(FileEnv), "YAML configuration file (env "+FileEnv+")")
+	var pending []func() error
+	for _, s := range all {
+		usage := "env " + s.env
+		if s.env == "" {
+			usage = "no environment variable"
+		}
+		fs.Var(flagValue{setting: s, pending: &pending}, s.path, usage)
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			fs.SetOutput(os.Stderr)
+			fmt.Fprintf(os.Stderr, "Usage: reportia [flags] [migrate up | down [steps] | status | config print]\n\nFlags:\n")
+			fs.PrintDefaults()
+		}
+		return nil, nil, err
+	}
+
+	if *file != "" {
+		if err := loadFile(cfg, *file); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	var errs []error
+	for _, s := range all {
+		if s.env == "" {
+			continue
+		}
+		if value, ok := os.LookupEnv(s.env); ok {
+			if err := s.set(value); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", s.env, err))
+			}
+		}
+	}
+	for _, apply := range pending {
+		if err := apply(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return nil, nil, fmt.Errorf("invalid configuration:\n%w", errors.Join(errs...))
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, nil, err
+	}
+	return cfg, fs.Args(), nil
+}
+
+// loadFile overlays the YAML file at path. Unknown keys are rejected so that
+// typos do not go unnoticed.
+func loadFile(cfg *Config, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("could not read the configuration file: %w", err)
+	}
+	defer f.Close()
+
+	dec := yaml.NewDecoder(f)
+	dec.KnownFields(true)
+	if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("invalid configuration file %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package config
+
+import (
+	"io"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const masked = "********"
+
+// Print writes the effective configuration as YAML, in the format Load reads,
+// with each setting annotated with its environment variable. Secrets that
+// are set are masked.
+func (c *Config) Print(w io.Writer) error {
+	out := *c
+	envs := make(map[string]string)
+	for _, s := range settings(reflect.ValueOf(&out).Elem(), "", "") {
+		envs[s.path] = s.env
+		if s.secret && s.value.String() != "" {
+			s.value.SetString(masked)
+		}
+	}
+
+	var doc yaml.Node
+	if err := doc.Encode(&out); err != nil {
+		return err
+	}
+	annotate(&doc, "", envs)
+
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	defer enc.Close()
+	return enc.Encode(&doc)
+}
+
+// annotate adds the environment variable of every setting as a comment.
+func annotate(node *yaml.Node, path string, envs map[string]string) {
+	if node.Kind != yaml.MappingNode {
+		return
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		keyPath := strings.TrimPrefix(path+"."+key.Value, ".")
+		if env := envs[keyPath]; env != "" {
+			key.LineComment = env
+		}
+		annotate(value, keyPath, envs)
+	}
+}
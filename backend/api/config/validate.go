@@ -0,0 +1,119 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"reportia/database"
+	"reportia/integration/llm/google"
+	"reportia/integration/mail"
+	"reportia/integration/storage"
+	"reportia/logging"
+	"reportia/middleware"
+	"reportia/tracing"
+	"reportia/upload"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Validate reports every invalid setting at once, each prefixed with its
+// path and environment variable.
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, name, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s: %s", name, fmt.Sprintf(format, args...)))
+		}
+	}
+	oneOf := func(value, name string, allowed ...string) {
+		check(slices.Contains(allowed, value), name, "must be one of %v, got %q", allowed, value)
+	}
+	positive := func(d time.Duration, name string) {
+		check(d > 0, name, "must be a positive duration, got %s", d)
+	}
+
+	port, err := strconv.Atoi(c.Server.Port)
+	check(err == nil && port > 0 && port < 65536, "server.port (PORT)", "must be a port number, got %q", c.Server.Port)
+	baseURL, err := url.Parse(c.Server.PublicBaseURL)
+	check(err == nil && baseURL.Scheme != "" && baseURL.Host != "", "server.public_base_url (PUBLIC_BASE_URL)", "must be an absolute URL, got %q", c.Server.PublicBaseURL)
+	oneOf(c.Server.StorageMode, "server.storage_mode (STORAGE_MODE)", StorageModeDatabase, StorageModeInMemory)
+	positive(c.Server.ReadHeaderTimeout, "server.read_header_timeout (HTTP_READ_HEADER_TIMEOUT)")
+	positive(c.Server.ReadTimeout, "server.read_timeout (HTTP_READ_TIMEOUT)")
+	positive(c.Server.WriteTimeout, "server.write_timeout (HTTP_WRITE_TIMEOUT)")
+	positive(c.Server.IdleTimeout, "server.idle_timeout (HTTP_IDLE_TIMEOUT)")
+	positive(c.Server.ShutdownTimeout, "server.shutdown_timeout (SHUTDOWN_TIMEOUT)")
+	check(c.Server.ShutdownDelay >= 0, "server.shutdown_delay (SHUTDOWN_DELAY)", "must not be negative")
+
+	if c.Server.StorageMode == StorageModeDatabase {
+		_, err := database.ParseDialect(c.Database.Driver)
+		check(err == nil, "database.driver (DB_DRIVER)", "must be postgres or sqlite, got %q", c.Database.Driver)
+		check(c.Database.URL != "", "database.url (DB_URL)", "is required unless server.storage_mode is in-memory")
+		check(c.Database.MaxOpenConns >= 0, "database.max_open_conns (DB_MAX_OPEN_CONNS)", "must not be negative, 0 means unlimited")
+		check(c.Database.MaxIdleConns >= 0, "database.max_idle_conns (DB_MAX_IDLE_CONNS)", "must not be negative")
+		check(c.Database.ConnMaxLifetime >= 0, "database.conn_max_lifetime (DB_CONN_MAX_LIFETIME)", "must not be negative, 0 means unlimited")
+		check(c.Database.ConnMaxIdleTime >= 0, "database.conn_max_idle_time (DB_CONN_MAX_IDLE_TIME)", "must not be negative, 0 means unlimited")
+		positive(c.Database.ConnectTimeout, "database.connect_timeout (DB_CONNECT_TIMEOUT)")
+	}
+
+	check(len(c.CORS.AllowedOrigins) > 0, "cors.allowed_origins (CORS_ALLOWED_ORIGINS)", "is required")
+	for _, origin := range c.CORS.AllowedOrigins {
+		err := middleware.ValidOriginPattern(origin)
+		check(err == nil, "cors.allowed_origins (CORS_ALLOWED_ORIGINS)", "%v", err)
+		check(origin != "*" || !c.CORS.AllowCredentials, "cors.allowed_origins (CORS_ALLOWED_ORIGINS)", "cannot be * while cors.allow_credentials is true")
+	}
+	check(len(c.CORS.AllowedMethods) > 0, "cors.allowed_methods (CORS_ALLOWED_METHODS)", "is required")
+	check(c.CORS.MaxAge >= 0, "cors.max_age (CORS_MAX_AGE)", "must not be negative")
+	gemini := c.LLM.Gemini
+	if gemini.BaseURL != "" {
+		u, err := url.Parse(gemini.BaseURL)
+		check(err == nil && u.Scheme != "" && u.Host != "", "llm.gemini.base_url (GEMINI_BASE_URL)", "must be an absolute URL, got %q", gemini.BaseURL)
+	}
+	if gemini.Temperature != nil {
+		check(*gemini.Temperature >= 0 && *gemini.Temperature <= 2, "llm.gemini.temperature (GEMINI_TEMPERATURE)", "must be between 0 and 2, got %g", *gemini.Temperature)
+	}
+	check(gemini.MaxOutputTokens >= 0 && gemini.MaxOutputTokens <= math.MaxInt32, "llm.gemini.max_output_tokens (GEMINI_MAX_OUTPUT_TOKENS)", "must be between 0 and %d, 0 leaves it to the model", math.MaxInt32)
+	if gemini.SafetyThreshold != "" {
+		oneOf(gemini.SafetyThreshold, "llm.gemini.safety_threshold (GEMINI_SAFETY_THRESHOLD)", google.SafetyThresholds...)
+	}
+	positive(gemini.FileTimeout, "llm.gemini.file_timeout (GEMINI_FILE_TIMEOUT)")
+
+	check(c.Limits.MaxUploadSize > 0, "limits.max_upload_size (MAX_UPLOAD_SIZE)", "must be a positive number of bytes")
+	check(c.Limits.MaxZipEntries > 0, "limits.max_zip_entries (UPLOAD_MAX_ZIP_ENTRIES)", "must be positive")
+	check(c.Limits.MaxUncompressedSize > 0, "limits.max_uncompressed_size (UPLOAD_MAX_UNCOMPRESSED_SIZE)", "must be a positive number of bytes")
+	check(len(c.Limits.UploadFileTypes) > 0, "limits.upload_file_types (UPLOAD_FILE_TYPES)", "is required")
+	err = upload.ValidAllowlist(c.Limits.UploadFileTypes)
+	check(err == nil, "limits.upload_file_types (UPLOAD_FILE_TYPES)", "%v", err)
+
+	positive(c.Scheduler.Interval, "scheduler.interval (SCHEDULER_INTERVAL)")
+	check(c.Scheduler.SourceDir != "", "scheduler.source_dir (SCHEDULE_SOURCE_DIR)", "is required")
+	check(c.DataSources.DirectoryRoot != "", "data_sources.directory_root (DATASOURCE_DIRECTORY_ROOT)", "is required")
+	oneOf(c.SMTP.TLSMode, "smtp.tls_mode (SMTP_TLS_MODE)", mail.TLSModeNone, mail.TLSModeStartTLS, mail.TLSModeImplicit)
+	check(c.Delivery.MaxAttempts >= 1, "delivery.max_attempts (DELIVERY_MAX_ATTEMPTS)", "must be at least 1")
+	positive(c.Webhooks.DispatchInterval, "webhooks.dispatch_interval (WEBHOOK_DISPATCH_INTERVAL)")
+	check(c.Webhooks.MaxAttempts >= 1, "webhooks.max_attempts (WEBHOOK_MAX_ATTEMPTS)", "must be at least 1")
+	// The example value once shipped in .env is public, and links signed
+	// with it could be forged by anyone.
+	check(!strings.HasPrefix(c.Share.LinkSecret, "change-me"), "share.link_secret (SHARE_LINK_SECRET)", "must be a random secret, not the example value")
+
+	oneOf(c.Assets.Storage, "assets.storage (ASSET_STORAGE)", storage.BackendLocal, storage.BackendS3)
+	if c.Assets.Storage == storage.BackendS3 {
+		check(c.Assets.S3.Endpoint != "", "assets.s3.endpoint (ASSET_S3_ENDPOINT)", "is required when assets.storage is s3")
+		check(c.Assets.S3.Bucket != "", "assets.s3.bucket (ASSET_S3_BUCKET)", "is required when assets.storage is s3")
+	}
+
+	check(c.Retention.ReportRetention >= 0, "retention.report_retention (REPORT_RETENTION)", "must not be negative, 0 keeps deleted reports forever")
+	positive(c.Retention.Interval, "retention.interval (RETENTION_INTERVAL)")
+
+	_, err = logging.ParseLevel(c.Log.Level)
+	check(err == nil, "log.level (LOG_LEVEL)", "must be debug, info, warn or error, got %q", c.Log.Level)
+	check(c.Log.BodyLimit >= 0, "log.body_limit (LOG_BODY_LIMIT)", "must not be negative")
+	oneOf(c.Tracing.Exporter, "tracing.exporter (OTEL_TRACES_EXPORTER)", tracing.ExporterNone, tracing.ExporterOTLP, tracing.ExporterStdout)
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid configuration:\n%w", errors.Join(errs...))
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/XSAM/otelsql"
+	_ "github.com/lib/pq"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	_ "modernc.org/sqlite"
+)
+
+const (
+	pingTimeout    = 5 * time.Second
+	initialBackoff = 500 * time.Millisecond
+	maxBackoff     = 5 * time.Second
+)
+
+type Options struct {
+	Driver          string
+	URL             string
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+	// ConnectTimeout bounds the retries of the startup ping, for databases
+	// that start along with the API.
+	ConnectTimeout time.Duration
+}
+
+// DB is a connection pool and the dialect of its backend.
+type DB struct {
+	*sql.DB
+	Dialect Dialect
+}
+
+// Open connects to the database, retrying the first ping with exponential
+// backoff until ConnectTimeout or ctx is done. Queries are traced, each as a
+// span carrying its statement.
+func Open(ctx context.Context, opts Options) (*DB, error) {
+	dialect, err := ParseDialect(opts.Driver)
+	if err != nil {
+		return nil, err
+	}
+	system := semconv.DBSystemPostgreSQL
+	if dialect == SQLite {
+		system = semconv.DBSystemSqlite
+	}
+	db, err := otelsql.Open(dialect.DriverName(), opts.URL,
+		otelsql.WithAttributes(system),
+		// Row iteration and session resets would add a span per row and per
+		// pooled connection reuse without telling anything about latency.
+		otelsql.WithSpanOptions(otelsql.SpanOptions{OmitRows: true, OmitConnResetSession: true}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(opts.MaxOpenConns)
+	db.SetMaxIdleConns(opts.MaxIdleConns)
+	db.SetConnMaxLifetime(opts.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(opts.ConnMaxIdleTime)
+
+	if err := ping(ctx, db, opts.ConnectTimeout); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("could not connect to the %s database: %w", dialect, err)
+	}
+	return &DB{DB: db, Dialect: dialect}, nil
+}
+
+func ping(ctx context.Context, db *sql.DB, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	backoff := initialBackoff
+	for attempt := 1; ; attempt++ {
+		pingCtx, cancelPing := context.WithTimeout(ctx, pingTimeout)
+		err := db.PingContext(pingCtx)
+		cancelPing()
+		if err == nil {
+			return nil
+		}
+		slog.WarnContext(ctx, "database not reachable, retrying", "attempt", attempt, "retry_in", backoff.String(), "error", err)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxBackoff)
+	}
+}
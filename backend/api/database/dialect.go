@@ -0,0 +1,49 @@
+// Package database holds what differs between the supported SQL backends.
+package database
+
+import (
+	"fmt"
+	"time"
+)
+
+// Dialect identifies a SQL backend. Both backends accept $n placeholders and
+// RETURNING, so queries only need the dialect for the few functions that
+// differ.
+type Dialect string
+
+const (
+	Postgres Dialect = "postgres"
+	SQLite   Dialect = "sqlite"
+)
+
+func ParseDialect(driver string) (Dialect, error) {
+	switch Dialect(driver) {
+	case Postgres, SQLite:
+		return Dialect(driver), nil
+	default:
+		return "", fmt.Errorf("unsupported database driver %q, expected postgres or sqlite", driver)
+	}
+}
+
+// DriverName is the database/sql driver registered for the dialect.
+func (d Dialect) DriverName() string {
+	return string(d)
+}
+
+// Now is the SQL expression for the current timestamp.
+func (d Dialect) Now() string {
+	if d == SQLite {
+		return "CURRENT_TIMESTAMP"
+	}
+	return "NOW()"
+}
+
+// Time converts a timestamp argument for comparisons. SQLite stores
+// CURRENT_TIMESTAMP as UTC text with second precision and compares text, so
+// arguments must use the same layout.
+func (d Dialect) Time(t time.Time) any {
+	if d == SQLite {
+		return t.UTC().Format("2006-01-02 15:04:05")
+	}
+	return t
+}
@@ -0,0 +1,103 @@
+package datasource
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reportia/netguard"
+	"strings"
+)
+
+const (
+	KindSQL       = "sql"
+	KindHTTP      = "http"
+	KindDirectory = "directory"
+	KindS3        = "s3"
+)
+
+// SecretEnvPrefix starts the names of the environment variables data sources
+// may read their credentials from, so that a data source cannot name the
+// server's own secrets.
+const SecretEnvPrefix = "DATASOURCE_SECRET_"
+
+// maxFetchSize mirrors the upload limit so fetched data goes through the same
+// constraints as a file sent by a user.
+const maxFetchSize = 10 * 1024 * 1024 // 10 MB
+
+var ErrUnknownKind = errors.New("unknown data source kind")
+
+// Data is a fetched snapshot, shaped like an uploaded file so it can be fed to
+// the same generation pipeline.
+type Data struct {
+	Reader   io.ReadCloser
+	FileName string
+	FileType string
+}
+
+type Connector interface {
+	Fetch(ctx context.Context) (*Data, error)
+}
+
+// Policy bounds what data sources may read on behalf of the API clients that
+// configure them.
+type Policy struct {
+	// DirectoryRoot is the directory that directory data sources must be in.
+	DirectoryRoot string
+	// Network bounds the hosts of HTTP and S3 data sources.
+	Network netguard.Policy
+}
+
+// New builds the connector for kind from its JSON configuration, validating
+// it against policy without contacting the remote system.
+func New(kind string, config json.RawMessage, policy Policy) (Connector, error) {
+	switch kind {
+	case KindSQL:
+		return newSQLConnector(config)
+	case KindHTTP:
+		return newHTTPConnector(config, policy.Network)
+	case KindDirectory:
+		return newDirectoryConnector(config, policy.DirectoryRoot)
+	case KindS3:
+		return newS3Connector(config, policy.Network)
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownKind, kind)
+	}
+}
+
+func decodeConfig(config json.RawMessage, dest any) error {
+	if len(config) == 0 {
+		return errors.New("data source config is required")
+	}
+	if err := json.Unmarshal(config, dest); err != nil {
+		return fmt.Errorf("invalid data source config: %w", err)
+	}
+	return nil
+}
+
+// validSecretEnv accepts the names of environment variables starting with
+// SecretEnvPrefix, or no name at all.
+func validSecretEnv(name string) error {
+	if name != "" && (!strings.HasPrefix(name, SecretEnvPrefix) || name == SecretEnvPrefix) {
+		return fmt.Errorf("environment variable %s must start with %s", name, SecretEnvPrefix)
+	}
+	return nil
+}
+
+// secretFromEnv resolves credentials by environment variable name so that
+// secrets are never persisted alongside the data source definition.
+func secretFromEnv(name string) (string, error) {
+	if name == "" {
+		return "", nil
+	}
+	if err := validSecretEnv(name); err != nil {
+		return "", err
+	}
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return "", fmt.Errorf("environment variable %s is not set", name)
+	}
+	return value, nil
+}
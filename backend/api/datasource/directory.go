@@ -0,0 +1,99 @@
+package datasource
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"os"
+	"path/filepath"
+	"reportia/helper"
+)
+
+type directoryConfig struct {
+	Path    string `json:"path"`
+	Pattern string `json:"pattern"`
+}
+
+// DirectoryConnector picks the most recently modified file matching a glob
+// pattern in a local directory, which suits exports dropped by other systems.
+// The directory must be inside the directory root of the policy.
+type DirectoryConnector struct {
+	cfg  directoryConfig
+	root string
+}
+
+func newDirectoryConnector(config json.RawMessage, root string) (*DirectoryConnector, error) {
+	var cfg directoryConfig
+	if err := decodeConfig(config, &cfg); err != nil {
+		return nil, err
+	}
+	if cfg.Path == "" {
+		return nil, errors.New("directory path is required")
+	}
+	if _, err := helper.ResolveWithin(root, cfg.Path); err != nil {
+		return nil, err
+	}
+	if cfg.Pattern == "" {
+		cfg.Pattern = "*"
+	}
+	if _, err := filepath.Match(cfg.Pattern, ""); err != nil {
+		return nil, fmt.Errorf("invalid directory pattern: %w", err)
+	}
+	return &DirectoryConnector{cfg: cfg, root: root}, nil
+}
+
+func (c *DirectoryConnector) Fetch(ctx context.Context) (*Data, error) {
+	// Resolved again, since a symlink may have replaced the directory.
+	dir, err := helper.ResolveWithin(c.root, c.cfg.Path)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("could not read directory data source: %w", err)
+	}
+
+	var latest os.FileInfo
+	for _, entry := range entries {
+		// Symlinks are skipped, as they may point out of the root.
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		if matched, _ := filepath.Match(c.cfg.Pattern, entry.Name()); !matched {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+		if latest == nil || info.ModTime().After(latest.ModTime()) {
+			latest = info
+		}
+	}
+	if latest == nil {
+		return nil, fmt.Errorf("no file matching %q in %s", c.cfg.Pattern, c.cfg.Path)
+	}
+	if latest.Size() > maxFetchSize {
+		return nil, fmt.Errorf("file %s exceeds %d bytes", latest.Name(), maxFetchSize)
+	}
+
+	file, err := os.Open(filepath.Join(dir, latest.Name()))
+	if err != nil {
+		return nil, err
+	}
+	return &Data{
+		Reader:   file,
+		FileName: latest.Name(),
+		FileType: fileTypeFromName(latest.Name()),
+	}, nil
+}
+
+func fileTypeFromName(name string) string {
+	fileType := mime.TypeByExtension(filepath.Ext(name))
+	if fileType == "" {
+		return "text/plain"
+	}
+	return fileType
+}
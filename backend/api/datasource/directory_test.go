@@ -0,0 +1,139 @@
+package datasource
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeFile(t *testing.T, path, content string, modTime time.Time) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func directoryConfigJSON(path, pattern string) json.RawMessage {
+	config, _ := json.Marshal(directoryConfig{Path: path, Pattern: pattern})
+	return config
+}
+
+func TestNewDirectoryConnector(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+	if err := os.Mkdir(filepath.Join(root, "exports"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(root, "escape")); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name    string
+		root    string
+		path    string
+		pattern string
+		wantErr string
+	}{
+		{"relative to the root", root, "exports", "*.csv", ""},
+		{"absolute inside the root", root, filepath.Join(root, "exports"), "", ""},
+		{"not created yet", root, "later", "", ""},
+		{"parent directory", root, "../etc", "", ".."},
+		{"parent inside the path", root, "exports/../../etc", "", ".."},
+		{"absolute outside the root", root, outside, "", "outside"},
+		{"symlink out of the root", root, "escape", "", "outside"},
+		{"no path", root, "", "", "path is required"},
+		{"no root", "", "exports", "", "no base directory"},
+		{"invalid pattern", root, "exports", "[", "pattern"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := newDirectoryConnector(directoryConfigJSON(tc.path, tc.pattern), tc.root)
+			switch {
+			case tc.wantErr == "" && err != nil:
+				t.Errorf("got %v, want no error", err)
+			case tc.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tc.wantErr)):
+				t.Errorf("got %v, want an error with %q", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestDirectoryConnectorFetch(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+	dir := filepath.Join(root, "exports")
+	now := time.Now()
+	writeFile(t, filepath.Join(dir, "sales-1.csv"), "old", now.Add(-2*time.Hour))
+	writeFile(t, filepath.Join(dir, "sales-2.csv"), "latest", now.Add(-time.Hour))
+	writeFile(t, filepath.Join(dir, "notes.txt"), "not matched", now)
+	// A newer symlink to a file out of the root is skipped.
+	writeFile(t, filepath.Join(outside, "secret.csv"), "secret", now)
+	if err := os.Symlink(filepath.Join(outside, "secret.csv"), filepath.Join(dir, "sales-3.csv")); err != nil {
+		t.Fatal(err)
+	}
+
+	connector, err := newDirectoryConnector(directoryConfigJSON("exports", "sales-*.csv"), root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := connector.Fetch(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(data.Reader)
+	data.Reader.Close()
+	if data.FileName != "sales-2.csv" || string(body) != "latest" {
+		t.Errorf("got %s %q, want sales-2.csv", data.FileName, body)
+	}
+	if data.FileType != "text/csv; charset=utf-8" {
+		t.Errorf("got file type %q", data.FileType)
+	}
+
+	// The directory is replaced by a symlink out of the root after the data
+	// source was saved.
+	if err := os.RemoveAll(dir); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, dir); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := connector.Fetch(context.Background()); err == nil || !strings.Contains(err.Error(), "outside") {
+		t.Errorf("got %v, want the symlinked directory refused", err)
+	}
+}
+
+func TestSecretFromEnv(t *testing.T) {
+	t.Setenv("DATASOURCE_SECRET_CRM_TOKEN", "token")
+	t.Setenv("SHARE_LINK_SECRET", "server secret")
+	for _, tc := range []struct {
+		name    string
+		want    string
+		wantErr string
+	}{
+		{"", "", ""},
+		{"DATASOURCE_SECRET_CRM_TOKEN", "token", ""},
+		{"DATASOURCE_SECRET_MISSING", "", "is not set"},
+		{"DATASOURCE_SECRET_", "", "must start with"},
+		{"SHARE_LINK_SECRET", "", "must start with"},
+		{"datasource_secret_crm_token", "", "must start with"},
+	} {
+		got, err := secretFromEnv(tc.name)
+		if got != tc.want {
+			t.Errorf("%s: got %q, want %q", tc.name, got, tc.want)
+		}
+		if (tc.wantErr == "") != (err == nil) || (err != nil && !strings.Contains(err.Error(), tc.wantErr)) {
+			t.Errorf("%s: got %v, want an error with %q", tc.name, err, tc.wantErr)
+		}
+	}
+}
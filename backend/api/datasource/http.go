@@ -0,0 +1,103 @@
+package datasource
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"reportia/netguard"
+	"strings"
+	"time"
+)
+
+const defaultHTTPTimeout = 30 * time.Second
+
+type httpConfig struct {
+	URL            string            `json:"url"`
+	Headers        map[string]string `json:"headers"`
+	HeaderEnvs     map[string]string `json:"header_envs"`
+	TimeoutSeconds int               `json:"timeout_seconds"`
+}
+
+// HTTPConnector fetches a JSON document with a GET request.
+type HTTPConnector struct {
+	cfg    httpConfig
+	client *http.Client
+}
+
+func newHTTPConnector(config json.RawMessage, network netguard.Policy) (*HTTPConnector, error) {
+	var cfg httpConfig
+	if err := decodeConfig(config, &cfg); err != nil {
+		return nil, err
+	}
+	if err := network.CheckURL(cfg.URL); err != nil {
+		return nil, fmt.Errorf("http %w", err)
+	}
+	for _, envName := range cfg.HeaderEnvs {
+		if err := validSecretEnv(envName); err != nil {
+			return nil, err
+		}
+	}
+	timeout := defaultHTTPTimeout
+	if cfg.TimeoutSeconds > 0 {
+		timeout = time.Duration(cfg.TimeoutSeconds) * time.Second
+	}
+	return &HTTPConnector{cfg: cfg, client: network.Client(timeout)}, nil
+}
+
+func (c *HTTPConnector) Fetch(ctx context.Context) (*Data, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.cfg.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	for name, value := range c.cfg.Headers {
+		req.Header.Set(name, value)
+	}
+	for name, envName := range c.cfg.HeaderEnvs {
+		value, err := secretFromEnv(envName)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set(name, value)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("http data source request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("http data source returned status %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxFetchSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(body) > maxFetchSize {
+		return nil, fmt.Errorf("http data source response exceeds %d bytes", maxFetchSize)
+	}
+	if !json.Valid(body) {
+		return nil, errors.New("http data source did not return valid JSON")
+	}
+
+	return &Data{
+		Reader:   io.NopCloser(bytes.NewReader(body)),
+		FileName: jsonFileName(req.URL.Path),
+		FileType: "application/json",
+	}, nil
+}
+
+func jsonFileName(urlPath string) string {
+	base := strings.TrimSuffix(path.Base(urlPath), path.Ext(urlPath))
+	if base == "" || base == "." || base == "/" {
+		base = "response"
+	}
+	return base + ".json"
+}
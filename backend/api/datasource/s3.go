@@ -0,0 +1,113 @@
+package datasource
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"path"
+	"reportia/netguard"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+type s3Config struct {
+	Endpoint     string `json:"endpoint"`
+	Bucket       string `json:"bucket"`
+	Prefix       string `json:"prefix"`
+	Pattern      string `json:"pattern"`
+	Region       string `json:"region"`
+	UseSSL       bool   `json:"use_ssl"`
+	AccessKeyEnv string `json:"access_key_env"`
+	SecretKeyEnv string `json:"secret_key_env"`
+}
+
+// S3Connector picks the most recently modified object under a prefix in an
+// S3-compatible bucket (AWS S3, MinIO, ...).
+type S3Connector struct {
+	cfg     s3Config
+	network netguard.Policy
+}
+
+func newS3Connector(config json.RawMessage, network netguard.Policy) (*S3Connector, error) {
+	var cfg s3Config
+	if err := decodeConfig(config, &cfg); err != nil {
+		return nil, err
+	}
+	if cfg.Endpoint == "" || cfg.Bucket == "" {
+		return nil, errors.New("s3 endpoint and bucket are required")
+	}
+	host, _, err := net.SplitHostPort(cfg.Endpoint)
+	if err != nil {
+		host = cfg.Endpoint
+	}
+	if err := network.CheckHost(host); err != nil {
+		return nil, err
+	}
+	for _, envName := range []string{cfg.AccessKeyEnv, cfg.SecretKeyEnv} {
+		if err := validSecretEnv(envName); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.Pattern == "" {
+		cfg.Pattern = "*"
+	}
+	if _, err := path.Match(cfg.Pattern, ""); err != nil {
+		return nil, fmt.Errorf("invalid s3 pattern: %w", err)
+	}
+	return &S3Connector{cfg: cfg, network: network}, nil
+}
+
+func (c *S3Connector) Fetch(ctx context.Context) (*Data, error) {
+	accessKey, err := secretFromEnv(c.cfg.AccessKeyEnv)
+	if err != nil {
+		return nil, err
+	}
+	secretKey, err := secretFromEnv(c.cfg.SecretKeyEnv)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := minio.New(c.cfg.Endpoint, &minio.Options{
+		Creds:     credentials.NewStaticV4(accessKey, secretKey, ""),
+		Secure:    c.cfg.UseSSL,
+		Region:    c.cfg.Region,
+		Transport: c.network.Transport(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var latest *minio.ObjectInfo
+	for object := range client.ListObjects(ctx, c.cfg.Bucket, minio.ListObjectsOptions{Prefix: c.cfg.Prefix, Recursive: true}) {
+		if object.Err != nil {
+			return nil, fmt.Errorf("could not list s3 data source: %w", object.Err)
+		}
+		if matched, _ := path.Match(c.cfg.Pattern, path.Base(object.Key)); !matched {
+			continue
+		}
+		if latest == nil || object.LastModified.After(latest.LastModified) {
+			current := object
+			latest = &current
+		}
+	}
+	if latest == nil {
+		return nil, fmt.Errorf("no object matching %q under %s/%s", c.cfg.Pattern, c.cfg.Bucket, c.cfg.Prefix)
+	}
+	if latest.Size > maxFetchSize {
+		return nil, fmt.Errorf("object %s exceeds %d bytes", latest.Key, maxFetchSize)
+	}
+
+	object, err := client.GetObject(ctx, c.cfg.Bucket, latest.Key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, err
+	}
+	name := path.Base(latest.Key)
+	return &Data{
+		Reader:   object,
+		FileName: name,
+		FileType: fileTypeFromName(name),
+	}, nil
+}
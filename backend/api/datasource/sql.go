@@ -0,0 +1,184 @@
+package datasource
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/lib/pq"
+)
+
+const (
+	defaultSQLMaxRows = 100000
+	defaultSQLTimeout = 30 * time.Second
+)
+
+type sqlConfig struct {
+	Driver         string `json:"driver"`
+	DSNEnv         string `json:"dsn_env"`
+	Query          string `json:"query"`
+	MaxRows        int    `json:"max_rows"`
+	TimeoutSeconds int    `json:"timeout_seconds"`
+}
+
+// SQLConnector runs a read query against a Postgres or MySQL database and
+// returns the result set as CSV. The query runs in a read-only transaction
+// that is always rolled back, so a stored DELETE or DROP fails instead of
+// changing the database.
+type SQLConnector struct {
+	cfg sqlConfig
+}
+
+func newSQLConnector(config json.RawMessage) (*SQLConnector, error) {
+	var cfg sqlConfig
+	if err := decodeConfig(config, &cfg); err != nil {
+		return nil, err
+	}
+	if cfg.Driver != "postgres" && cfg.Driver != "mysql" {
+		return nil, fmt.Errorf("sql driver must be postgres or mysql, got %q", cfg.Driver)
+	}
+	if cfg.DSNEnv == "" {
+		return nil, errors.New("sql dsn_env is required")
+	}
+	if err := validSecretEnv(cfg.DSNEnv); err != nil {
+		return nil, err
+	}
+	if cfg.Query == "" {
+		return nil, errors.New("sql query is required")
+	}
+	if cfg.MaxRows <= 0 {
+		cfg.MaxRows = defaultSQLMaxRows
+	}
+	if cfg.TimeoutSeconds < 0 {
+		return nil, errors.New("sql timeout_seconds must not be negative")
+	}
+	return &SQLConnector{cfg: cfg}, nil
+}
+
+func (c *SQLConnector) Fetch(ctx context.Context) (*Data, error) {
+	dsn, err := secretFromEnv(c.cfg.DSNEnv)
+	if err != nil {
+		return nil, err
+	}
+	db, err := sql.Open(c.cfg.Driver, dsn)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	timeout := defaultSQLTimeout
+	if c.cfg.TimeoutSeconds > 0 {
+		timeout = time.Duration(c.cfg.TimeoutSeconds) * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("sql data source could not start a read-only transaction: %w", err)
+	}
+	defer tx.Rollback()
+	if err := c.setStatementTimeout(ctx, tx, timeout); err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.QueryContext(ctx, c.cfg.Query)
+	if err != nil {
+		return nil, fmt.Errorf("sql data source query failed: %w", err)
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	if err := writer.Write(columns); err != nil {
+		return nil, err
+	}
+
+	values := make([]any, len(columns))
+	pointers := make([]any, len(columns))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+	record := make([]string, len(columns))
+
+	rowCount := 0
+	for rows.Next() {
+		rowCount++
+		if rowCount > c.cfg.MaxRows {
+			return nil, fmt.Errorf("sql data source returned more than %d rows", c.cfg.MaxRows)
+		}
+		if err := rows.Scan(pointers...); err != nil {
+			return nil, err
+		}
+		for i, value := range values {
+			record[i] = formatSQLValue(value)
+		}
+		if err := writer.Write(record); err != nil {
+			return nil, err
+		}
+		if buf.Len() > maxFetchSize {
+			return nil, fmt.Errorf("sql data source result exceeds %d bytes", maxFetchSize)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return nil, err
+	}
+
+	return &Data{
+		Reader:   io.NopCloser(&buf),
+		FileName: "query.csv",
+		FileType: "text/csv",
+	}, nil
+}
+
+// setStatementTimeout has the server stop the query once timeout passes, as
+// canceling the context alone may leave it running there.
+func (c *SQLConnector) setStatementTimeout(ctx context.Context, tx *sql.Tx, timeout time.Duration) error {
+	var statement string
+	switch c.cfg.Driver {
+	case "postgres":
+		statement = fmt.Sprintf("SET LOCAL statement_timeout = %d", timeout.Milliseconds())
+	case "mysql":
+		statement = fmt.Sprintf("SET SESSION max_execution_time = %d", timeout.Milliseconds())
+	default:
+		return nil
+	}
+	if _, err := tx.ExecContext(ctx, statement); err != nil {
+		return fmt.Errorf("sql data source could not set the statement timeout: %w", err)
+	}
+	return nil
+}
+
+func formatSQLValue(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case []byte:
+		return string(v)
+	case string:
+		return v
+	case time.Time:
+		return v.Format(time.RFC3339)
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,172 @@
+package datasource
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// readOnlyDB is a database/sql driver that behaves like Postgres in a
+// read-only transaction: it answers SELECT statements and refuses the others.
+type readOnlyDB struct {
+	mu         sync.Mutex
+	writes     []string
+	readOnly   bool
+	rolledBack bool
+}
+
+var fakeSQL = &readOnlyDB{}
+
+func init() {
+	sql.Register("readonlytest", fakeSQL)
+}
+
+func (d *readOnlyDB) Open(string) (driver.Conn, error) { return &readOnlyConn{db: d}, nil }
+
+type readOnlyConn struct {
+	db *readOnlyDB
+	tx bool
+}
+
+func (c *readOnlyConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("prepared statements are not supported")
+}
+
+func (c *readOnlyConn) Close() error { return nil }
+
+func (c *readOnlyConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *readOnlyConn) BeginTx(_ context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+	c.tx = true
+	c.db.readOnly = opts.ReadOnly
+	return &readOnlyTx{conn: c}, nil
+}
+
+func (c *readOnlyConn) QueryContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+	verb := strings.ToUpper(strings.Fields(query)[0])
+	if verb == "SELECT" {
+		return &readOnlyRows{values: [][]driver.Value{{int64(1), "jan"}, {int64(2), "feb"}}}, nil
+	}
+	if c.tx && c.db.readOnly {
+		return nil, fmt.Errorf("cannot execute %s in a read-only transaction", verb)
+	}
+	c.db.writes = append(c.db.writes, query)
+	return &readOnlyRows{}, nil
+}
+
+type readOnlyTx struct{ conn *readOnlyConn }
+
+func (tx *readOnlyTx) Commit() error {
+	tx.conn.tx = false
+	return nil
+}
+
+func (tx *readOnlyTx) Rollback() error {
+	tx.conn.db.mu.Lock()
+	defer tx.conn.db.mu.Unlock()
+	tx.conn.tx = false
+	tx.conn.db.rolledBack = true
+	return nil
+}
+
+type readOnlyRows struct{ values [][]driver.Value }
+
+func (r *readOnlyRows) Columns() []string { return []string{"id", "month"} }
+func (r *readOnlyRows) Close() error      { return nil }
+
+func (r *readOnlyRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
+
+func fetchSQL(t *testing.T, query string) (string, error) {
+	t.Helper()
+	t.Setenv("DATASOURCE_SECRET_TEST_DSN", "test")
+	fakeSQL.mu.Lock()
+	fakeSQL.writes, fakeSQL.readOnly, fakeSQL.rolledBack = nil, false, false
+	fakeSQL.mu.Unlock()
+
+	connector := &SQLConnector{cfg: sqlConfig{Driver: "readonlytest", DSNEnv: "DATASOURCE_SECRET_TEST_DSN", Query: query, MaxRows: 10}}
+	data, err := connector.Fetch(context.Background())
+	if err != nil {
+		return "", err
+	}
+	body, err := io.ReadAll(data.Reader)
+	return string(body), err
+}
+
+func TestSQLConnectorReadsInReadOnlyTransaction(t *testing.T) {
+	body, err := fetchSQL(t, "SELECT id, month FROM sales")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "id,month\n1,jan\n2,feb\n"; body != want {
+		t.Errorf("got %q, want %q", body, want)
+	}
+	if !fakeSQL.readOnly {
+		t.Error("query ran outside a read-only transaction")
+	}
+	if !fakeSQL.rolledBack {
+		t.Error("transaction was not rolled back")
+	}
+}
+
+func TestSQLConnectorRejectsWrites(t *testing.T) {
+	for _, query := range []string{
+		"DELETE FROM sales",
+		"DROP TABLE sales",
+		"UPDATE sales SET month = 'mar'",
+		"INSERT INTO sales VALUES (3, 'mar') RETURNING id",
+	} {
+		t.Run(query, func(t *testing.T) {
+			_, err := fetchSQL(t, query)
+			if err == nil || !strings.Contains(err.Error(), "read-only transaction") {
+				t.Errorf("got %v, want the write refused", err)
+			}
+			if len(fakeSQL.writes) > 0 {
+				t.Errorf("ran %v", fakeSQL.writes)
+			}
+		})
+	}
+}
+
+func TestNewSQLConnector(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		config  string
+		wantErr string
+	}{
+		{"postgres", `{"driver":"postgres","dsn_env":"DATASOURCE_SECRET_DSN","query":"SELECT 1"}`, ""},
+		{"mysql with timeout", `{"driver":"mysql","dsn_env":"DATASOURCE_SECRET_DSN","query":"SELECT 1","timeout_seconds":5}`, ""},
+		{"unknown driver", `{"driver":"sqlite","dsn_env":"DATASOURCE_SECRET_DSN","query":"SELECT 1"}`, "driver"},
+		{"dsn outside the secret prefix", `{"driver":"postgres","dsn_env":"GEMINI_API_KEY","query":"SELECT 1"}`, "GEMINI_API_KEY"},
+		{"no query", `{"driver":"postgres","dsn_env":"DATASOURCE_SECRET_DSN"}`, "query is required"},
+		{"negative timeout", `{"driver":"postgres","dsn_env":"DATASOURCE_SECRET_DSN","query":"SELECT 1","timeout_seconds":-1}`, "timeout_seconds"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := newSQLConnector([]byte(tc.config))
+			switch {
+			case tc.wantErr == "" && err != nil:
+				t.Errorf("got %v, want no error", err)
+			case tc.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tc.wantErr)):
+				t.Errorf("got %v, want an error with %q", err, tc.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+// Package gallery holds the starter templates embedded in the binary. Each
+// starter is a directory under starters/ laid out like a template bundle
+// entry, named after the starter's slug.
+package gallery
+
+import (
+	"embed"
+	"io/fs"
+	"reportia/model"
+	"reportia/templatebundle"
+	"sync"
+)
+
+//go:embed starters
+var files embed.FS
+
+// Starter is a template users can clone into their own account.
+type Starter struct {
+	Slug string `json:"slug"`
+	model.TemplateMetadata
+	Template string `json:"template"`
+}
+
+var load = sync.OnceValues(func() ([]Starter, error) {
+	starters, err := fs.Sub(files, "starters")
+	if err != nil {
+		return nil, err
+	}
+	dirs, err := fs.ReadDir(starters, ".")
+	if err != nil {
+		return nil, err
+	}
+	list := make([]Starter, 0, len(dirs))
+	for _, dir := range dirs {
+		entry, err := templatebundle.ReadEntry(starters, dir.Name())
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, Starter{Slug: dir.Name(), TemplateMetadata: entry.Metadata, Template: entry.Template})
+	}
+	return list, nil
+})
+
+// List returns every starter, sorted by slug.
+func List() ([]Starter, error) {
+	return load()
+}
+
+// Get returns nil when no starter has the slug.
+func Get(slug string) (*Starter, error) {
+	starters, err := load()
+	if err != nil {
+		return nil, err
+	}
+	for _, starter := range starters {
+		if starter.Slug == slug {
+			return &starter, nil
+		}
+	}
+	return nil, nil
+}
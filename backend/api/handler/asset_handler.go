@@ -0,0 +1,176 @@
+package handler
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"reportia/metrics"
+	"reportia/model"
+	request_report "reportia/model/request"
+	"reportia/problem"
+	"reportia/service"
+	"reportia/upload"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// assetCSP keeps scripts embedded in SVG assets from running when an asset
+// is opened directly.
+const assetCSP = "default-src 'none'; style-src 'unsafe-inline'; sandbox"
+
+type AssetHandler struct {
+	service *service.AssetService
+	uploads upload.Limits
+}
+
+// NewAssetHandler caps the size in uploads at model.MaxAssetSize.
+func NewAssetHandler(s *service.AssetService, uploads upload.Limits) *AssetHandler {
+	uploads.MaxSize = min(uploads.MaxSize, model.MaxAssetSize)
+	return &AssetHandler{service: s, uploads: uploads}
+}
+
+// List godoc
+// @Summary List template assets
+// @Description List the images and fonts uploaded for a template
+// @Tags assets
+// @Produce json
+// @Param report_id query int true "Report ID"
+// @Success 200 {array} model.Asset
+// @Failure 400 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /api/v1/reports/assets [get]
+func (h *AssetHandler) List(w http.ResponseWriter, r *http.Request) {
+	reportID, err := strconv.Atoi(r.URL.Query().Get("report_id"))
+	if err != nil || reportID <= 0 {
+		problem.Write(w, r, invalidParam("invalid report_id"))
+		return
+	}
+
+	assets, err := h.service.List(r.Context(), reportID)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(assets)
+}
+
+// Upload godoc
+// @Summary Upload a template asset
+// @Description Upload an image (png, jpg, gif, webp, svg) or font (woff, woff2, ttf, otf) for a template, referenced in it as {{ASSET:name}}. Uploading under an existing name replaces the asset
+// @Tags assets
+// @Accept multipart/form-data
+// @Produce json
+// @Param report_id formData int true "Report ID"
+// @Param name formData string true "Asset name used in the {{ASSET:name}} token"
+// @Param file formData file true "Image or font file"
+// @Success 200 {object} model.Asset
+// @Failure 400 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 413 {object} problem.Problem
+// @Failure 415 {object} problem.Problem
+// @Router /api/v1/reports/assets [post]
+func (h *AssetHandler) Upload(w http.ResponseWriter, r *http.Request) {
+	form, err := upload.Receive(w, r, "file", h.uploads)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	defer form.Close()
+	reportID, err := strconv.Atoi(form.Values.Get("report_id"))
+	if err != nil || reportID <= 0 {
+		problem.Write(w, r, invalidParam("invalid report_id"))
+		return
+	}
+	if form.File == nil {
+		problem.Write(w, r, invalidParam("file is required"))
+		return
+	}
+	metrics.UploadSize.WithLabelValues("asset").Observe(float64(form.File.Size))
+	content, err := io.ReadAll(form.File)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	asset, err := h.service.Upload(r.Context(), reportID, form.Values.Get("name"), form.File.Name, content)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(asset)
+}
+
+// Delete godoc
+// @Summary Delete a template asset
+// @Description Delete an asset and its stored file
+// @Tags assets
+// @Accept json
+// @Produce json
+// @Param request body request_report.DeleteAssetReq true "Delete Asset Request"
+// @Success 204
+// @Failure 400 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Router /api/v1/reports/assets/delete [post]
+func (h *AssetHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	var req request_report.DeleteAssetReq
+
+	if err := decodeJSON(r, &req); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	if err := h.service.Delete(r.Context(), req.ID); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Serve godoc
+// @Summary Get an asset
+// @Description Serve the content of a template asset. With the v parameter of the asset URL the response may be cached for good; without it, it is revalidated with the ETag
+// @Tags assets
+// @Produce octet-stream
+// @Param reportID path int true "Report ID"
+// @Param name path string true "Asset name"
+// @Param v query string false "Content version"
+// @Success 200 {file} file
+// @Success 304
+// @Failure 404 {object} problem.Problem
+// @Router /api/v1/assets/{reportID}/{name} [get]
+func (h *AssetHandler) Serve(w http.ResponseWriter, r *http.Request) {
+	reportID, _ := strconv.Atoi(mux.Vars(r)["reportID"])
+	asset, content, err := h.service.Open(r.Context(), reportID, mux.Vars(r)["name"])
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	defer content.Close()
+
+	etag := `"` + asset.Checksum + `"`
+	w.Header().Set("ETag", etag)
+	if r.URL.Query().Get("v") == service.AssetVersion(*asset) {
+		w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	} else {
+		w.Header().Set("Cache-Control", "public, max-age=300")
+	}
+	// Shared reports render in a sandbox with an opaque origin, which needs
+	// CORS to load fonts.
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Security-Policy", assetCSP)
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", asset.ContentType)
+	w.Header().Set("Content-Length", strconv.Itoa(asset.Size))
+	if r.Method == http.MethodHead {
+		return
+	}
+	io.Copy(w, content)
+}
@@ -0,0 +1,126 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"reportia/model"
+	request_report "reportia/model/request"
+	"reportia/problem"
+	"reportia/service"
+)
+
+type DataSourceHandler struct {
+	service *service.DataSourceService
+}
+
+func NewDataSourceHandler(s *service.DataSourceService) *DataSourceHandler {
+	return &DataSourceHandler{service: s}
+}
+
+// List godoc
+// @Summary List data sources
+// @Description Get all data source connectors
+// @Tags datasources
+// @Produce json
+// @Success 200 {array} model.DataSource
+// @Failure 500 {object} problem.Problem
+// @Router /api/v1/datasources [get]
+func (h *DataSourceHandler) List(w http.ResponseWriter, r *http.Request) {
+	dataSources, err := h.service.List(r.Context())
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(dataSources)
+}
+
+// Create godoc
+// @Summary Create data source
+// @Description Create a data source connector (sql, http, directory or s3). Credentials are referenced by environment variable name.
+// @Tags datasources
+// @Accept json
+// @Produce json
+// @Param datasource body request_report.CreateDataSourceReq true "Create Data Source Request"
+// @Success 200 {object} model.DataSource
+// @Failure 400 {object} problem.Problem
+// @Router /api/v1/datasources [post]
+func (h *DataSourceHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var req request_report.CreateDataSourceReq
+
+	if err := decodeJSON(r, &req); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	ds, err := h.service.Create(r.Context(), model.DataSource{
+		Name:     req.Name,
+		Kind:     req.Kind,
+		Config:   req.Config,
+		UserMail: req.UserMail,
+	})
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ds)
+}
+
+// Update godoc
+// @Summary Update data source
+// @Description Update an existing data source connector
+// @Tags datasources
+// @Accept json
+// @Produce json
+// @Param datasource body request_report.UpdateDataSourceReq true "Update Data Source Request"
+// @Success 200 {object} model.DataSource
+// @Failure 400 {object} problem.Problem
+// @Router /api/v1/datasources [put]
+func (h *DataSourceHandler) Update(w http.ResponseWriter, r *http.Request) {
+	var req request_report.UpdateDataSourceReq
+
+	if err := decodeJSON(r, &req); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	ds, err := h.service.Update(r.Context(), model.DataSource{
+		ID:     req.ID,
+		Name:   req.Name,
+		Kind:   req.Kind,
+		Config: req.Config,
+	})
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ds)
+}
+
+// TurnOnOff godoc
+// @Summary Turn data source on or off
+// @Description Activate or deactivate a data source
+// @Tags datasources
+// @Accept json
+// @Produce json
+// @Param request body request_report.TurnOnOffReq true "Turn On/Off Request"
+// @Success 204
+// @Failure 400 {object} problem.Problem
+// @Router /api/v1/datasources/turnonoff [post]
+func (h *DataSourceHandler) TurnOnOff(w http.ResponseWriter, r *http.Request) {
+	var req request_report.TurnOnOffReq
+
+	if err := decodeJSON(r, &req); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	if err := h.service.TurnOnOff(r.Context(), req.ID, req.Active); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,155 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"reportia/model"
+	request_report "reportia/model/request"
+	"reportia/problem"
+	"reportia/service"
+	"strconv"
+)
+
+type DeliveryHandler struct {
+	service *service.DeliveryService
+}
+
+func NewDeliveryHandler(s *service.DeliveryService) *DeliveryHandler {
+	return &DeliveryHandler{service: s}
+}
+
+// ListRules godoc
+// @Summary List delivery rules
+// @Description Get all email delivery rules
+// @Tags deliveries
+// @Produce json
+// @Success 200 {array} model.DeliveryRule
+// @Failure 500 {object} problem.Problem
+// @Router /api/v1/deliveries/rules [get]
+func (h *DeliveryHandler) ListRules(w http.ResponseWriter, r *http.Request) {
+	rules, err := h.service.ListRules(r.Context())
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rules)
+}
+
+// CreateRule godoc
+// @Summary Create delivery rule
+// @Description Email every successful generation of a template or schedule to the given recipients
+// @Tags deliveries
+// @Accept json
+// @Produce json
+// @Param rule body request_report.CreateDeliveryRuleReq true "Create Delivery Rule Request"
+// @Success 200 {object} model.DeliveryRule
+// @Failure 400 {object} problem.Problem
+// @Router /api/v1/deliveries/rules [post]
+func (h *DeliveryHandler) CreateRule(w http.ResponseWriter, r *http.Request) {
+	var req request_report.CreateDeliveryRuleReq
+
+	if err := decodeJSON(r, &req); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	rule, err := h.service.CreateRule(r.Context(), model.DeliveryRule{
+		ReportID:   req.ReportID,
+		ScheduleID: req.ScheduleID,
+		Recipients: req.Recipients,
+		Subject:    req.Subject,
+		AttachPDF:  req.AttachPDF,
+	})
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rule)
+}
+
+// UpdateRule godoc
+// @Summary Update delivery rule
+// @Description Update the recipients, subject or attachment setting of a delivery rule
+// @Tags deliveries
+// @Accept json
+// @Produce json
+// @Param rule body request_report.UpdateDeliveryRuleReq true "Update Delivery Rule Request"
+// @Success 200 {object} model.DeliveryRule
+// @Failure 400 {object} problem.Problem
+// @Router /api/v1/deliveries/rules [put]
+func (h *DeliveryHandler) UpdateRule(w http.ResponseWriter, r *http.Request) {
+	var req request_report.UpdateDeliveryRuleReq
+
+	if err := decodeJSON(r, &req); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	rule, err := h.service.UpdateRule(r.Context(), model.DeliveryRule{
+		ID:         req.ID,
+		Recipients: req.Recipients,
+		Subject:    req.Subject,
+		AttachPDF:  req.AttachPDF,
+	})
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rule)
+}
+
+// TurnOnOffRule godoc
+// @Summary Turn delivery rule on or off
+// @Description Activate or deactivate a delivery rule
+// @Tags deliveries
+// @Accept json
+// @Produce json
+// @Param request body request_report.TurnOnOffReq true "Turn On/Off Request"
+// @Success 204
+// @Failure 400 {object} problem.Problem
+// @Router /api/v1/deliveries/rules/turnonoff [post]
+func (h *DeliveryHandler) TurnOnOffRule(w http.ResponseWriter, r *http.Request) {
+	var req request_report.TurnOnOffReq
+
+	if err := decodeJSON(r, &req); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	if err := h.service.TurnOnOffRule(r.Context(), req.ID, req.Active); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListByGeneration godoc
+// @Summary List deliveries
+// @Description Get the email deliveries recorded for a generation, including bounces and errors
+// @Tags deliveries
+// @Produce json
+// @Param generation_id query int true "Generation ID"
+// @Success 200 {array} model.Delivery
+// @Failure 400 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /api/v1/deliveries [get]
+func (h *DeliveryHandler) ListByGeneration(w http.ResponseWriter, r *http.Request) {
+	generationID, _ := strconv.Atoi(r.URL.Query().Get("generation_id"))
+	if generationID == 0 {
+		problem.Write(w, r, invalidParam("generation_id is required"))
+		return
+	}
+
+	deliveries, err := h.service.ListByGeneration(r.Context(), generationID)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(deliveries)
+}
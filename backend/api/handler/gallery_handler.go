@@ -0,0 +1,66 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	request_report "reportia/model/request"
+	"reportia/problem"
+	"reportia/service"
+)
+
+type GalleryHandler struct {
+	service *service.ReportService
+}
+
+func NewGalleryHandler(s *service.ReportService) *GalleryHandler {
+	return &GalleryHandler{service: s}
+}
+
+// List godoc
+// @Summary List starter templates
+// @Description List the starter templates built into the application
+// @Tags gallery
+// @Produce json
+// @Success 200 {array} gallery.Starter
+// @Failure 500 {object} problem.Problem
+// @Router /api/v1/gallery [get]
+func (h *GalleryHandler) List(w http.ResponseWriter, r *http.Request) {
+	starters, err := h.service.ListStarters()
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.Encode(starters)
+}
+
+// Clone godoc
+// @Summary Clone a starter template
+// @Description Create a report owned by user_mail from a starter template
+// @Tags gallery
+// @Accept json
+// @Produce json
+// @Param request body request_report.CloneStarterReq true "Clone Starter Request"
+// @Success 200 {object} model.Report
+// @Failure 400 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Router /api/v1/gallery/clone [post]
+func (h *GalleryHandler) Clone(w http.ResponseWriter, r *http.Request) {
+	var req request_report.CloneStarterReq
+
+	if err := decodeJSON(r, &req); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	rep, err := h.service.CloneStarter(r.Context(), req.Slug, req.UserMail, req.Name)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.Encode(rep)
+}
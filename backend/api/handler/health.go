@@ -1,10 +1,69 @@
 package handler
 
 import (
+	"context"
+	"encoding/json"
 	"net/http"
+	"time"
 )
 
+// readyTimeout bounds the checks of a readiness probe, so that a hung
+// database fails the probe instead of stalling it.
+const readyTimeout = 2 * time.Second
+
+// Health godoc
+// @Summary Liveness probe
+// @Description Answers as long as the process serves requests. Also served on /live
+// @Tags health
+// @Produce plain
+// @Success 200 {string} string "OK"
+// @Router /health [get]
 func Health(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte("OK"))
 }
+
+// ReadinessCheck fails when a dependency needed to serve traffic is missing.
+type ReadinessCheck func(ctx context.Context) error
+
+type ReadinessHandler struct {
+	checks map[string]ReadinessCheck
+}
+
+func NewReadinessHandler(checks map[string]ReadinessCheck) *ReadinessHandler {
+	return &ReadinessHandler{checks: checks}
+}
+
+type readiness struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks"`
+}
+
+// Ready godoc
+// @Summary Readiness probe
+// @Description Checks the database, that at least one LLM provider is configured and that the server is not shutting down
+// @Tags health
+// @Produce json
+// @Success 200 {object} handler.readiness
+// @Failure 503 {object} handler.readiness
+// @Router /ready [get]
+func (h *ReadinessHandler) Ready(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), readyTimeout)
+	defer cancel()
+
+	res := readiness{Status: "ready", Checks: make(map[string]string, len(h.checks))}
+	status := http.StatusOK
+	for name, check := range h.checks {
+		if err := check(ctx); err != nil {
+			res.Checks[name] = err.Error()
+			res.Status = "unavailable"
+			status = http.StatusServiceUnavailable
+			continue
+		}
+		res.Checks[name] = "ok"
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(res)
+}
@@ -0,0 +1,76 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	request_report "reportia/model/request"
+	"reportia/problem"
+	"reportia/service"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+type PreviewHandler struct {
+	service *service.PreviewService
+}
+
+func NewPreviewHandler(s *service.PreviewService) *PreviewHandler {
+	return &PreviewHandler{service: s}
+}
+
+// Preview godoc
+// @Summary Preview a template
+// @Description Render a template without calling an LLM: system variables and assets are resolved and the body slot is filled with sample content, or with the content of a prior generation of the report. The format png returns a screenshot taken with the headless renderer
+// @Tags reports
+// @Accept json
+// @Produce html
+// @Produce png
+// @Param id path int true "Report ID"
+// @Param request body request_report.PreviewReportReq false "Preview Request"
+// @Success 200 {string} string "HTML page or PNG image"
+// @Failure 400 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 501 {object} problem.Problem
+// @Router /api/v1/reports/{id}/preview [post]
+func (h *PreviewHandler) Preview(w http.ResponseWriter, r *http.Request) {
+	reportID, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+	var req request_report.PreviewReportReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		problem.Write(w, r, errInvalidBody)
+		return
+	}
+
+	if err := validateRequest(&req); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	if req.Format == "png" {
+		png, err := h.service.RenderPNG(r.Context(), reportID, req.GenerationID, req.Width, req.Height)
+		if err != nil {
+			problem.Write(w, r, err)
+			return
+		}
+		w.Header().Set("Content-Type", "image/png")
+		w.Header().Set("Cache-Control", "no-store")
+		w.Write(png)
+		return
+	}
+
+	preview, err := h.service.Render(r.Context(), reportID, req.GenerationID)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	// Templates may carry scripts, so the preview runs sandboxed like a
+	// shared report.
+	w.Header().Set("Content-Security-Policy", sharedReportCSP)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	io.WriteString(w, preview)
+}
@@ -2,50 +2,68 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
 	"net/http"
+	"net/url"
+	"reportia/metrics"
+	"reportia/model"
 	request_report "reportia/model/request"
+	"reportia/problem"
 	"reportia/service"
+	"reportia/upload"
 	"strconv"
-)
+	"strings"
+	"time"
 
-const maxUploadSize = 10 * 1024 * 1024 //  10 MB
+	"github.com/gorilla/mux"
+)
 
 type ReportHandler struct {
 	service *service.ReportService
+	uploads upload.Limits
 }
 
-func NewReportHandler(s *service.ReportService) *ReportHandler {
-	return &ReportHandler{service: s}
+func NewReportHandler(s *service.ReportService, uploads upload.Limits) *ReportHandler {
+	return &ReportHandler{service: s, uploads: uploads}
 }
 
 // List godoc
 // @Summary List reports
-// @Description Get all reports with pagination
+// @Description Search, filter and sort reports with page or cursor pagination
 // @Tags reports
 // @Produce json
+// @Param q query string false "Words the name, description or template must all contain"
+// @Param tags query string false "Comma-separated tags the reports must all carry"
+// @Param category query string false "Category"
+// @Param user_mail query string false "Owner email"
+// @Param active query bool false "Active flag"
+// @Param deleted query bool false "List the reports in the trash instead"
+// @Param created_from query string false "Created on or after (RFC 3339 or YYYY-MM-DD)"
+// @Param created_to query string false "Created on or before (RFC 3339 or YYYY-MM-DD)"
+// @Param updated_from query string false "Updated on or after (RFC 3339 or YYYY-MM-DD)"
+// @Param updated_to query string false "Updated on or before (RFC 3339 or YYYY-MM-DD)"
+// @Param sort query string false "Sort column: id, name, category, template, user_mail, active, data_source_id, create_at, update_at (default: create_at)"
+// @Param order query string false "asc or desc (default: desc for create_at, asc otherwise)"
 // @Param page query int false "Page number (default: 1)"
 // @Param page_size query int false "Page size (default: 10, max: 100)"
+// @Param cursor query string false "Cursor returned as next_cursor, takes precedence over page"
 // @Success 200 {object} model.PaginatedReports
-// @Failure 400 {object} map[string]string
-// @Failure 500 {object} map[string]string
+// @Failure 400 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
 // @Router /api/v1/reports [get]
 func (h *ReportHandler) List(w http.ResponseWriter, r *http.Request) {
-	q := r.URL.Query()
-	page, _ := strconv.Atoi(q.Get("page"))
-	pageSize, _ := strconv.Atoi(q.Get("page_size"))
-
-	if page <= 0 {
-		page = 1
-	}
-	if pageSize <= 0 {
-		pageSize = 10
+	query, err := parseReportQuery(r.URL.Query())
+	if err != nil {
+		problem.Write(w, r, invalidParam(err.Error()))
+		return
 	}
 
-	paginatedReports, err := h.service.ListWithPagination(r.Context(), page, pageSize)
+	paginatedReports, err := h.service.Search(r.Context(), query)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		problem.Write(w, r, err)
 		return
 	}
 
@@ -55,20 +73,104 @@ func (h *ReportHandler) List(w http.ResponseWriter, r *http.Request) {
 	enc.Encode(paginatedReports)
 }
 
+func parseReportQuery(q url.Values) (model.ReportQuery, error) {
+	query := model.ReportQuery{
+		Search: q.Get("q"),
+		SortBy: q.Get("sort"),
+		Cursor: q.Get("cursor"),
+	}
+	query.Page, _ = strconv.Atoi(q.Get("page"))
+	query.PageSize, _ = strconv.Atoi(q.Get("page_size"))
+
+	for _, tag := range strings.Split(q.Get("tags"), ",") {
+		if tag = strings.ToLower(strings.TrimSpace(tag)); tag != "" {
+			query.Tags = append(query.Tags, tag)
+		}
+	}
+	if category := q.Get("category"); category != "" {
+		query.Category = &category
+	}
+	if userMail := q.Get("user_mail"); userMail != "" {
+		query.UserMail = &userMail
+	}
+	if deleted := q.Get("deleted"); deleted != "" {
+		value, err := strconv.ParseBool(deleted)
+		if err != nil {
+			return query, fmt.Errorf("invalid deleted %q", deleted)
+		}
+		query.Deleted = value
+	}
+	if active := q.Get("active"); active != "" {
+		value, err := strconv.ParseBool(active)
+		if err != nil {
+			return query, fmt.Errorf("invalid active %q", active)
+		}
+		query.Active = &value
+	}
+
+	switch q.Get("order") {
+	case "desc":
+		query.SortDesc = true
+	case "":
+		query.SortDesc = query.SortBy == "" || query.SortBy == "create_at"
+	case "asc":
+	default:
+		return query, fmt.Errorf("invalid order %q, expected asc or desc", q.Get("order"))
+	}
+
+	var err error
+	bounds := []struct {
+		param    string
+		target   **time.Time
+		endOfDay bool
+	}{
+		{"created_from", &query.CreatedFrom, false},
+		{"created_to", &query.CreatedTo, true},
+		{"updated_from", &query.UpdatedFrom, false},
+		{"updated_to", &query.UpdatedTo, true},
+	}
+	for _, bound := range bounds {
+		if *bound.target, err = parseTimeParam(q.Get(bound.param), bound.endOfDay); err != nil {
+			return query, fmt.Errorf("invalid %s: %w", bound.param, err)
+		}
+	}
+	return query, nil
+}
+
+// parseTimeParam accepts RFC 3339 timestamps and plain dates. A plain date
+// used as an upper bound covers the whole day.
+func parseTimeParam(value string, endOfDay bool) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return &t, nil
+	}
+	t, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return nil, errors.New("expected RFC 3339 or YYYY-MM-DD")
+	}
+	if endOfDay {
+		t = t.Add(24*time.Hour - time.Microsecond)
+	}
+	return &t, nil
+}
+
 // Filter godoc
 // @Summary Filter reports
-// @Description Get reports by id and user_mail with pagination
+// @Description Get reports by id and user_mail with pagination. Deprecated: use GET /api/v1/reports/{id}
 // @Tags reports
+// @Deprecated
 // @Produce json
 // @Param id query int true "Report ID"
 // @Param user_mail query string false "User Email"
 // @Param page query int false "Page number (default: 1)"
 // @Param page_size query int false "Page size (default: 10, max: 100)"
 // @Success 200 {object} model.PaginatedReports
-// @Failure 400 {object} map[string]string
-// @Failure 404 {object} map[string]string
-// @Failure 422 {object} map[string]string
-// @Failure 500 {object} map[string]string
+// @Failure 400 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 422 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
 // @Router /api/v1/reports/filter [get]
 func (h *ReportHandler) Filter(w http.ResponseWriter, r *http.Request) {
 	q := r.URL.Query()
@@ -77,8 +179,7 @@ func (h *ReportHandler) Filter(w http.ResponseWriter, r *http.Request) {
 	pageSize, _ := strconv.Atoi(q.Get("page_size"))
 
 	if id == 0 {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]string{"error": "id is required"})
+		problem.Write(w, r, invalidParam("id is required"))
 		return
 	}
 
@@ -99,18 +200,12 @@ func (h *ReportHandler) Filter(w http.ResponseWriter, r *http.Request) {
 
 	paginatedReports, err := h.service.FilterWithPagination(r.Context(), id, userMail, page, pageSize)
 	if err != nil {
-		if err.Error() == "the report that you select was inactive" {
-			w.WriteHeader(http.StatusUnprocessableEntity)
-		} else {
-			w.WriteHeader(http.StatusInternalServerError)
-		}
-		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		problem.Write(w, r, err)
 		return
 	}
 
 	if len(paginatedReports.Reports) == 0 {
-		w.WriteHeader(http.StatusNotFound)
-		json.NewEncoder(w).Encode(map[string]string{"error": "report not found"})
+		problem.Write(w, r, service.ErrReportNotFound)
 		return
 	}
 
@@ -128,27 +223,33 @@ func (h *ReportHandler) Filter(w http.ResponseWriter, r *http.Request) {
 // @Produce json
 // @Param report body request_report.CreateReportReq true "Create Report Request"
 // @Success 200 {object} model.Report
-// @Failure 400 {object} map[string]string
+// @Failure 400 {object} problem.Problem
+// @Failure 422 {object} problem.Problem
 // @Router /api/v1/reports [post]
 func (h *ReportHandler) Create(w http.ResponseWriter, r *http.Request) {
 	var req request_report.CreateReportReq
 
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]string{"error": "invalid body"})
-		return
-	}
-
-	if err := request_report.Validate(&req); err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+	if err := decodeJSON(r, &req); err != nil {
+		problem.Write(w, r, err)
 		return
 	}
 
-	rep, err := h.service.Create(r.Context(), req.Template, req.UserMail)
+	rep, err := h.service.Create(r.Context(), model.Report{
+		Name:             req.Name,
+		Description:      req.Description,
+		Tags:             req.Tags,
+		Category:         req.Category,
+		Template:         req.Template,
+		DefaultLLM:       req.DefaultLLM,
+		DefaultModel:     req.DefaultModel,
+		DefaultPrompt:    req.DefaultPrompt,
+		Thumbnail:        req.Thumbnail,
+		AllowedFileTypes: req.AllowedFileTypes,
+		UserMail:         req.UserMail,
+		DataSourceID:     req.DataSourceID,
+	})
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		problem.Write(w, r, err)
 		return
 	}
 	enc := json.NewEncoder(w)
@@ -156,75 +257,348 @@ func (h *ReportHandler) Create(w http.ResponseWriter, r *http.Request) {
 	enc.Encode(rep)
 }
 
+// Get godoc
+// @Summary Get a report
+// @Description Get a report, active or not
+// @Tags reports
+// @Produce json
+// @Param id path int true "Report ID"
+// @Success 200 {object} model.Report
+// @Failure 404 {object} problem.Problem
+// @Router /api/v1/reports/{id} [get]
+func (h *ReportHandler) Get(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(mux.Vars(r)["id"])
+	rep, err := h.service.Get(r.Context(), id)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	writeReport(w, rep)
+}
+
+// Replace godoc
+// @Summary Replace a report
+// @Description Replace the template and data source of a report. Omitted metadata fields are left unchanged
+// @Tags reports
+// @Accept json
+// @Produce json
+// @Param id path int true "Report ID"
+// @Param report body request_report.ReplaceReportReq true "Replace Report Request"
+// @Success 200 {object} model.Report
+// @Failure 400 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 422 {object} problem.Problem
+// @Router /api/v1/reports/{id} [put]
+func (h *ReportHandler) Replace(w http.ResponseWriter, r *http.Request) {
+	var req request_report.ReplaceReportReq
+
+	if err := decodeJSON(r, &req); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	id, _ := strconv.Atoi(mux.Vars(r)["id"])
+	rep, err := h.service.Update(r.Context(), reportUpdate(id, req))
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	writeReport(w, rep)
+}
+
+// Patch godoc
+// @Summary Update a report partially
+// @Description Change only the fields sent. Sending active turns the report on or off
+// @Tags reports
+// @Accept json
+// @Produce json
+// @Param id path int true "Report ID"
+// @Param report body request_report.PatchReportReq true "Patch Report Request"
+// @Success 200 {object} model.Report
+// @Failure 400 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 422 {object} problem.Problem
+// @Router /api/v1/reports/{id} [patch]
+func (h *ReportHandler) Patch(w http.ResponseWriter, r *http.Request) {
+	var req request_report.PatchReportReq
+
+	if err := decodeJSON(r, &req); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	id, _ := strconv.Atoi(mux.Vars(r)["id"])
+	rep, err := h.service.Patch(r.Context(), id, model.ReportPatch{
+		Template:         req.Template,
+		DataSourceID:     req.DataSourceID,
+		Name:             req.Name,
+		Description:      req.Description,
+		Tags:             req.Tags,
+		Category:         req.Category,
+		DefaultLLM:       req.DefaultLLM,
+		DefaultModel:     req.DefaultModel,
+		DefaultPrompt:    req.DefaultPrompt,
+		Thumbnail:        req.Thumbnail,
+		AllowedFileTypes: req.AllowedFileTypes,
+		Active:           req.Active,
+	})
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	writeReport(w, rep)
+}
+
 // Update godoc
 // @Summary Update report
-// @Description Update an existing report
+// @Description Update an existing report. Deprecated: use PUT /api/v1/reports/{id}
 // @Tags reports
+// @Deprecated
 // @Accept json
 // @Produce json
 // @Param report body request_report.UpdateReportReq true "Update Report Request"
 // @Success 200 {object} model.Report
-// @Failure 400 {object} map[string]string
+// @Failure 400 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 422 {object} problem.Problem
 // @Router /api/v1/reports [put]
 func (h *ReportHandler) Update(w http.ResponseWriter, r *http.Request) {
 	var req request_report.UpdateReportReq
 
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]string{"error": "invalid body"})
+	if err := decodeJSON(r, &req); err != nil {
+		problem.Write(w, r, err)
 		return
 	}
 
-	if err := request_report.Validate(&req); err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+	rep, err := h.service.Update(r.Context(), reportUpdate(req.ID, req.ReplaceReportReq))
+	if err != nil {
+		problem.Write(w, r, err)
 		return
 	}
+	writeReport(w, rep)
+}
 
-	rep, err := h.service.Update(r.Context(), req.ID, req.Template)
+func reportUpdate(id int, req request_report.ReplaceReportReq) model.ReportUpdate {
+	return model.ReportUpdate{
+		ID:               id,
+		Template:         req.Template,
+		DataSourceID:     req.DataSourceID,
+		Name:             req.Name,
+		Description:      req.Description,
+		Tags:             req.Tags,
+		Category:         req.Category,
+		DefaultLLM:       req.DefaultLLM,
+		DefaultModel:     req.DefaultModel,
+		DefaultPrompt:    req.DefaultPrompt,
+		Thumbnail:        req.Thumbnail,
+		AllowedFileTypes: req.AllowedFileTypes,
+	}
+}
+
+func writeReport(w http.ResponseWriter, rep *model.Report) {
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.Encode(rep)
+}
+
+// ValidateTemplate godoc
+// @Summary Validate a template
+// @Description Lint a template without saving it. Errors would make create and update fail; warnings are advisory
+// @Tags reports
+// @Accept json
+// @Produce json
+// @Param request body request_report.ValidateTemplateReq true "Template to validate"
+// @Success 200 {object} templatelint.Result
+// @Failure 400 {object} problem.Problem
+// @Router /api/v1/reports/validate [post]
+func (h *ReportHandler) ValidateTemplate(w http.ResponseWriter, r *http.Request) {
+	var req request_report.ValidateTemplateReq
+
+	if err := decodeJSON(r, &req); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.Encode(h.service.ValidateTemplate(req.Template))
+}
+
+// Export godoc
+// @Summary Export templates
+// @Description Download templates as a zip bundle with their metadata, to import them in another environment. Give ids, or a user_mail to export all of that user's templates
+// @Tags reports
+// @Produce application/zip
+// @Param ids query string false "Comma-separated report ids"
+// @Param user_mail query string false "Owner email"
+// @Success 200 {file} file
+// @Failure 400 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Router /api/v1/reports/export [get]
+func (h *ReportHandler) Export(w http.ResponseWriter, r *http.Request) {
+	var ids []int
+	if value := r.URL.Query().Get("ids"); value != "" {
+		for _, part := range strings.Split(value, ",") {
+			id, err := strconv.Atoi(strings.TrimSpace(part))
+			if err != nil || id <= 0 {
+				problem.Write(w, r, invalidParam("invalid ids"))
+				return
+			}
+			ids = append(ids, id)
+		}
+	}
+	var userMail *string
+	if value := r.URL.Query().Get("user_mail"); value != "" {
+		userMail = &value
+	}
+
+	bundle, err := h.service.Export(r.Context(), ids, userMail)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", `attachment; filename="reportia-templates.zip"`)
+	w.Write(bundle)
+}
+
+// Import godoc
+// @Summary Import templates
+// @Description Create templates from a zip bundle made by the export endpoint. A template named like one of the user's reports is skipped, overwrites it or is renamed, depending on conflict
+// @Tags reports
+// @Accept multipart/form-data
+// @Produce json
+// @Param file formData file true "Zip bundle"
+// @Param user_mail formData string true "Owner of the imported templates"
+// @Param conflict formData string false "skip, overwrite or rename (default: skip)"
+// @Success 200 {array} service.ImportResult
+// @Failure 400 {object} problem.Problem
+// @Failure 413 {object} problem.Problem
+// @Failure 415 {object} problem.Problem
+// @Router /api/v1/reports/import [post]
+func (h *ReportHandler) Import(w http.ResponseWriter, r *http.Request) {
+	form, err := upload.Receive(w, r, "file", h.uploads)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		problem.Write(w, r, err)
 		return
 	}
+	defer form.Close()
+	if form.File == nil {
+		problem.Write(w, r, invalidParam("file is required"))
+		return
+	}
+	metrics.UploadSize.WithLabelValues("bundle").Observe(float64(form.File.Size))
+	if err := upload.Allowed(form.File, []string{upload.TypeZip}); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	bundle, err := io.ReadAll(form.File)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	results, err := h.service.Import(r.Context(), bundle, form.Values.Get("user_mail"), form.Values.Get("conflict"))
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
 	enc := json.NewEncoder(w)
 	enc.SetEscapeHTML(false)
-	enc.Encode(rep)
+	enc.Encode(results)
 }
 
 // TurnOnOff godoc
 // @Summary Turn report on or off
-// @Description Activate or deactivate a report
+// @Description Activate or deactivate a report. Deprecated: use PATCH /api/v1/reports/{id} with active
 // @Tags reports
+// @Deprecated
 // @Accept json
 // @Produce json
 // @Param request body request_report.TurnOnOffReq true "Turn On/Off Request"
 // @Success 204
-// @Failure 400 {object} map[string]string
+// @Failure 400 {object} problem.Problem
 // @Router /api/v1/reports/turnonoff [post]
 func (h *ReportHandler) TurnOnOff(w http.ResponseWriter, r *http.Request) {
 	var req request_report.TurnOnOffReq
 
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]string{"error": "invalid body"})
+	if err := decodeJSON(r, &req); err != nil {
+		problem.Write(w, r, err)
 		return
 	}
 
-	if err := request_report.Validate(&req); err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+	if req.ID == 0 {
+		problem.Write(w, r, invalidParam("id is required"))
+		return
+	}
+	if err := h.service.TurnOnOff(r.Context(), req.ID, req.Active); err != nil {
+		problem.Write(w, r, err)
 		return
 	}
+	w.WriteHeader(http.StatusNoContent)
+}
 
-	if req.ID == 0 {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]string{"error": "id is required"})
+// Delete godoc
+// @Summary Delete a report
+// @Description Move a report to the trash. It can be restored until the retention job purges it
+// @Tags reports
+// @Produce json
+// @Param id path int true "Report ID"
+// @Success 204
+// @Failure 404 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /api/v1/reports/{id} [delete]
+func (h *ReportHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(mux.Vars(r)["id"])
+	if err := h.service.Delete(r.Context(), id); err != nil {
+		problem.Write(w, r, err)
 		return
 	}
-	if err := h.service.TurnOnOff(r.Context(), req.ID, req.Active); err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Restore godoc
+// @Summary Restore a deleted report
+// @Description Take a report back out of the trash
+// @Tags reports
+// @Produce json
+// @Param id path int true "Report ID"
+// @Success 200 {object} model.Report
+// @Failure 404 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /api/v1/reports/{id}/restore [post]
+func (h *ReportHandler) Restore(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(mux.Vars(r)["id"])
+	rep, err := h.service.Restore(r.Context(), id)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.Encode(rep)
+}
+
+// HardDelete godoc
+// @Summary Permanently delete a report
+// @Description Remove a report, in the trash or not, with its schedules, generations, deliveries, share links and assets. Requires the admin token
+// @Tags admin
+// @Produce json
+// @Security AdminToken
+// @Param id path int true "Report ID"
+// @Success 204
+// @Failure 401 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /api/v1/admin/reports/{id} [delete]
+func (h *ReportHandler) HardDelete(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(mux.Vars(r)["id"])
+	if err := h.service.HardDelete(r.Context(), id); err != nil {
+		problem.Write(w, r, err)
 		return
 	}
 	w.WriteHeader(http.StatusNoContent)
@@ -232,55 +606,74 @@ func (h *ReportHandler) TurnOnOff(w http.ResponseWriter, r *http.Request) {
 
 // GenerateReportFromFile godoc
 // @Summary Generate report from file
-// @Description Generate a report by uploading a file
+// @Description Generate a report by uploading a file, or from a data source when no file is sent
 // @Tags reports
 // @Accept multipart/form-data
 // @Produce html
 // @Param idReport formData int true "Report ID"
 // @Param prompt formData string false "Prompt"
+// @Param llm formData string false "LLM provider"
 // @Param model formData string false "Model"
-// @Param file formData file true "File to upload"
+// @Param idDataSource formData int false "Data source ID (defaults to the report's data source)"
+// @Param file formData file false "File to upload"
 // @Success 200 {string} string "HTML report"
-// @Failure 400 {object} map[string]string
-// @Failure 500 {object} map[string]string
+// @Header 200 {int} X-Generation-ID "ID of the stored generation"
+// @Failure 400 {object} problem.Problem
+// @Failure 413 {object} problem.Problem
+// @Failure 415 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
 // @Router /api/v1/reports/generate [post]
 func (h *ReportHandler) GenerateReportFromFile(w http.ResponseWriter, r *http.Request) {
-	err := r.ParseMultipartForm(maxUploadSize)
+	form, err := upload.Receive(w, r, "file", h.uploads)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf("Could not parse multipart form: %v", err)})
+		problem.Write(w, r, err)
 		return
 	}
-	idReport, err := strconv.Atoi(r.FormValue("idReport"))
+	defer form.Close()
+	idReport, err := strconv.Atoi(form.Values.Get("idReport"))
 	if err != nil || idReport <= 0 {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid idReport"})
+		problem.Write(w, r, invalidParam("Invalid idReport"))
 		return
 	}
-	promptUser := r.FormValue("prompt")
-	llm := r.FormValue("llm")
-	model := r.FormValue("model")
-	file, fileHeader, err := r.FormFile("file")
-	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf("Could not get file from form: %v", err)})
-		return
+	in := service.GenerationInput{
+		ReportID: idReport,
+		Prompt:   form.Values.Get("prompt"),
+		LLM:      form.Values.Get("llm"),
+		Model:    form.Values.Get("model"),
 	}
-	if fileHeader.Size > maxUploadSize {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf("File is too large: %d > %d", fileHeader.Size, maxUploadSize)})
+	if form.File == nil {
+		h.generateReportFromDataSource(w, r, in, form.Values.Get("idDataSource"))
 		return
 	}
-	defer file.Close()
-	file.Seek(0, 0)
-	fmt.Printf("Received file: %s with size: %d bytes\n", fileHeader.Filename, fileHeader.Size)
-	rep, err := h.service.GenerateReportFromFile(r.Context(), idReport, promptUser, llm, model, file, fileHeader.Filename, fileHeader.Header.Get("Content-Type"))
+	metrics.UploadSize.WithLabelValues("report_file").Observe(float64(form.File.Size))
+	slog.DebugContext(r.Context(), "received report file", "report_id", in.ReportID, "size", form.File.Size, "content_type", form.File.ContentType)
+	gen, err := h.service.GenerateReportFromFile(r.Context(), in, form.File)
+	writeGeneration(w, r, gen, err)
+}
+
+func (h *ReportHandler) generateReportFromDataSource(w http.ResponseWriter, r *http.Request, in service.GenerationInput, idDataSource string) {
+	var dataSourceID *int
+	if idDataSource != "" {
+		id, err := strconv.Atoi(idDataSource)
+		if err != nil || id <= 0 {
+			problem.Write(w, r, invalidParam("Invalid idDataSource"))
+			return
+		}
+		dataSourceID = &id
+	}
+	gen, err := h.service.GenerateReportFromDataSource(r.Context(), in, dataSourceID)
+	writeGeneration(w, r, gen, err)
+}
+
+func writeGeneration(w http.ResponseWriter, r *http.Request, gen *model.Generation, err error) {
+	if gen != nil {
+		w.Header().Set("X-Generation-ID", strconv.Itoa(gen.ID))
+	}
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf("Could not generate report: %v", err)})
+		problem.Write(w, r, err)
 		return
 	}
 	w.Header().Set("Content-Type", "text/html")
 	w.WriteHeader(http.StatusOK)
-	w.Write([]byte(rep))
+	w.Write([]byte(gen.Output))
 }
@@ -0,0 +1,30 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"reportia/apperror"
+	request_report "reportia/model/request"
+)
+
+var errInvalidBody = apperror.Validationf("invalid_body", "invalid body")
+
+// decodeJSON decodes a JSON request body into req and validates it.
+func decodeJSON(r *http.Request, req any) error {
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		return errInvalidBody
+	}
+	return validateRequest(req)
+}
+
+func validateRequest(req any) error {
+	if err := request_report.Validate(req); err != nil {
+		return apperror.Wrap(apperror.Validation, "invalid_request", err)
+	}
+	return nil
+}
+
+// invalidParam reports a missing or malformed query, path or form parameter.
+func invalidParam(message string) error {
+	return apperror.Validationf("invalid_parameter", "%s", message)
+}
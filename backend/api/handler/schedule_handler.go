@@ -0,0 +1,171 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"reportia/model"
+	request_report "reportia/model/request"
+	"reportia/problem"
+	"reportia/service"
+	"strconv"
+)
+
+type ScheduleHandler struct {
+	service *service.ScheduleService
+}
+
+func NewScheduleHandler(s *service.ScheduleService) *ScheduleHandler {
+	return &ScheduleHandler{service: s}
+}
+
+// List godoc
+// @Summary List schedules
+// @Description Get all recurring report schedules
+// @Tags schedules
+// @Produce json
+// @Success 200 {array} model.Schedule
+// @Failure 500 {object} problem.Problem
+// @Router /api/v1/schedules [get]
+func (h *ScheduleHandler) List(w http.ResponseWriter, r *http.Request) {
+	schedules, err := h.service.List(r.Context())
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(schedules)
+}
+
+// Create godoc
+// @Summary Create schedule
+// @Description Create a recurring report generation schedule
+// @Tags schedules
+// @Accept json
+// @Produce json
+// @Param schedule body request_report.CreateScheduleReq true "Create Schedule Request"
+// @Success 200 {object} model.Schedule
+// @Failure 400 {object} problem.Problem
+// @Router /api/v1/schedules [post]
+func (h *ScheduleHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var req request_report.CreateScheduleReq
+
+	if err := decodeJSON(r, &req); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	sch, err := h.service.Create(r.Context(), model.Schedule{
+		ReportID:       req.ReportID,
+		Name:           req.Name,
+		CronExpression: req.CronExpression,
+		Timezone:       req.Timezone,
+		LLM:            req.LLM,
+		Model:          req.Model,
+		Prompt:         req.Prompt,
+		SourcePath:     req.SourcePath,
+		DataSourceID:   req.DataSourceID,
+		UserMail:       req.UserMail,
+	})
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(sch)
+}
+
+// Update godoc
+// @Summary Update schedule
+// @Description Update an existing schedule and recompute its next run
+// @Tags schedules
+// @Accept json
+// @Produce json
+// @Param schedule body request_report.UpdateScheduleReq true "Update Schedule Request"
+// @Success 200 {object} model.Schedule
+// @Failure 400 {object} problem.Problem
+// @Router /api/v1/schedules [put]
+func (h *ScheduleHandler) Update(w http.ResponseWriter, r *http.Request) {
+	var req request_report.UpdateScheduleReq
+
+	if err := decodeJSON(r, &req); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	sch, err := h.service.Update(r.Context(), model.Schedule{
+		ID:             req.ID,
+		Name:           req.Name,
+		CronExpression: req.CronExpression,
+		Timezone:       req.Timezone,
+		LLM:            req.LLM,
+		Model:          req.Model,
+		Prompt:         req.Prompt,
+		SourcePath:     req.SourcePath,
+		DataSourceID:   req.DataSourceID,
+	})
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(sch)
+}
+
+// TurnOnOff godoc
+// @Summary Turn schedule on or off
+// @Description Activate or deactivate a schedule
+// @Tags schedules
+// @Accept json
+// @Produce json
+// @Param request body request_report.TurnOnOffReq true "Turn On/Off Request"
+// @Success 204
+// @Failure 400 {object} problem.Problem
+// @Router /api/v1/schedules/turnonoff [post]
+func (h *ScheduleHandler) TurnOnOff(w http.ResponseWriter, r *http.Request) {
+	var req request_report.TurnOnOffReq
+
+	if err := decodeJSON(r, &req); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	if err := h.service.TurnOnOff(r.Context(), req.ID, req.Active); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListRuns godoc
+// @Summary List schedule runs
+// @Description Get the most recent generations produced by a schedule
+// @Tags schedules
+// @Produce json
+// @Param id query int true "Schedule ID"
+// @Param limit query int false "Maximum number of runs (default: 20, max: 100)"
+// @Success 200 {array} model.Generation
+// @Failure 400 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /api/v1/schedules/runs [get]
+func (h *ScheduleHandler) ListRuns(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	id, _ := strconv.Atoi(q.Get("id"))
+	limit, _ := strconv.Atoi(q.Get("limit"))
+
+	if id == 0 {
+		problem.Write(w, r, invalidParam("id is required"))
+		return
+	}
+
+	runs, err := h.service.ListRuns(r.Context(), id, limit)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.Encode(runs)
+}
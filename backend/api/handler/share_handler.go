@@ -0,0 +1,184 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"html/template"
+	"net/http"
+	request_report "reportia/model/request"
+	"reportia/problem"
+	"reportia/service"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// sharedReportCSP renders shared reports in a sandbox with an opaque origin:
+// the report's own inline scripts (charts) run, but it cannot read cookies,
+// submit forms, call APIs or be framed by other sites.
+const sharedReportCSP = "sandbox allow-scripts; default-src 'none'; script-src 'unsafe-inline' https:; style-src 'unsafe-inline' https:; img-src data: https:; font-src data: https:; connect-src 'none'; form-action 'none'; frame-ancestors 'none'; base-uri 'none'"
+
+const sharePasswordCSP = "default-src 'none'; style-src 'unsafe-inline'; form-action 'self'; frame-ancestors 'none'; base-uri 'none'"
+
+var sharePasswordPage = template.Must(template.New("share-password").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Protected report</title>
+<style>body{font-family:sans-serif;display:flex;justify-content:center;margin-top:15vh}form{display:flex;flex-direction:column;gap:8px;width:280px}p.error{color:#b00020}</style>
+</head>
+<body>
+<form method="POST">
+<label for="password">This report is password protected</label>
+<input id="password" name="password" type="password" autofocus required>
+{{if .}}<p class="error">{{.}}</p>{{end}}
+<button type="submit">Open report</button>
+</form>
+</body>
+</html>`))
+
+type ShareHandler struct {
+	service *service.ShareService
+}
+
+func NewShareHandler(s *service.ShareService) *ShareHandler {
+	return &ShareHandler{service: s}
+}
+
+// Create godoc
+// @Summary Create share link
+// @Description Mint a signed public link to a stored generation, with an expiry, optional password and view limit. The token is only returned once.
+// @Tags shares
+// @Accept json
+// @Produce json
+// @Param share body request_report.CreateShareLinkReq true "Create Share Link Request"
+// @Success 200 {object} model.ShareLink
+// @Failure 400 {object} problem.Problem
+// @Router /api/v1/shares [post]
+func (h *ShareHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var req request_report.CreateShareLinkReq
+
+	if err := decodeJSON(r, &req); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	link, err := h.service.Create(r.Context(), req.GenerationID, req.ExpiresInHours, req.Password, req.MaxViews, req.UserMail)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(link)
+}
+
+// ListByGeneration godoc
+// @Summary List share links
+// @Description Get the share links minted for a generation
+// @Tags shares
+// @Produce json
+// @Param generation_id query int true "Generation ID"
+// @Success 200 {array} model.ShareLink
+// @Failure 400 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /api/v1/shares [get]
+func (h *ShareHandler) ListByGeneration(w http.ResponseWriter, r *http.Request) {
+	generationID, _ := strconv.Atoi(r.URL.Query().Get("generation_id"))
+	if generationID == 0 {
+		problem.Write(w, r, invalidParam("generation_id is required"))
+		return
+	}
+
+	links, err := h.service.ListByGeneration(r.Context(), generationID)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(links)
+}
+
+// Revoke godoc
+// @Summary Revoke share link
+// @Description Revoke a share link so it can no longer be opened
+// @Tags shares
+// @Accept json
+// @Produce json
+// @Param request body request_report.RevokeShareLinkReq true "Revoke Share Link Request"
+// @Success 204
+// @Failure 400 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Router /api/v1/shares/revoke [post]
+func (h *ShareHandler) Revoke(w http.ResponseWriter, r *http.Request) {
+	var req request_report.RevokeShareLinkReq
+
+	if err := decodeJSON(r, &req); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	if err := h.service.Revoke(r.Context(), req.ID); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// View serves a shared report to anonymous visitors. Password-protected links
+// show a password form first; the password is then submitted with POST.
+func (h *ShareHandler) View(w http.ResponseWriter, r *http.Request) {
+	token := mux.Vars(r)["token"]
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("X-Robots-Tag", "noindex, nofollow")
+
+	if r.Method == http.MethodGet {
+		link, err := h.service.Inspect(r.Context(), token)
+		if err != nil {
+			writeSharePageError(w, err)
+			return
+		}
+		if link.PasswordProtected {
+			writeSharePasswordPage(w, http.StatusOK, "")
+			return
+		}
+	}
+
+	output, err := h.service.View(r.Context(), token, r.PostFormValue("password"))
+	if errors.Is(err, service.ErrShareLinkPasswordInvalid) {
+		writeSharePasswordPage(w, http.StatusUnauthorized, "Invalid password")
+		return
+	}
+	if errors.Is(err, service.ErrShareLinkLocked) {
+		writeSharePasswordPage(w, http.StatusTooManyRequests, "Too many wrong passwords, try again in a few minutes")
+		return
+	}
+	if err != nil {
+		writeSharePageError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Security-Policy", sharedReportCSP)
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(output))
+}
+
+func writeSharePasswordPage(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Security-Policy", sharePasswordCSP)
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	sharePasswordPage.Execute(w, message)
+}
+
+func writeSharePageError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, service.ErrShareLinkNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, service.ErrShareLinkGone):
+		status = http.StatusGone
+	}
+	w.Header().Set("Content-Security-Policy", "default-src 'none'")
+	http.Error(w, http.StatusText(status), status)
+}
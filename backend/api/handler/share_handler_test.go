@@ -0,0 +1,228 @@
+package handler_test
+
+import (
+	"context"
+	"database/sql"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"reportia/database"
+	"reportia/handler"
+	"reportia/migrations"
+	"reportia/model"
+	"reportia/repository"
+	"reportia/service"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+const sharedOutput = "<html><body>shared report</body></html>"
+
+type shareFixture struct {
+	db         *sql.DB
+	service    *service.ShareService
+	server     *httptest.Server
+	generation int
+}
+
+// newShareFixture serves share links from a SQLite database holding one
+// successful generation.
+func newShareFixture(t *testing.T) *shareFixture {
+	t.Helper()
+	ctx := context.Background()
+	dsn := "file:" + filepath.Join(t.TempDir(), "reportia.db") + "?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)"
+	db, err := database.Open(ctx, database.Options{Driver: "sqlite", URL: dsn, MaxOpenConns: 1, ConnectTimeout: 5 * time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	migrator, err := migrations.New(db.DB, db.Dialect)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrator.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := repository.NewReportRepository(db.DB, db.Dialect).Create(ctx, model.Report{
+		Name:     "Sales",
+		Template: "<html><body><!-- DYNAMIC BODY CONTENT HERE --></body></html>",
+		UserMail: "owner@example.com",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	generations := repository.NewGenerationRepository(db.DB, db.Dialect)
+	generation, err := generations.Start(ctx, report.ID, nil, "gemini", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := generations.Finish(ctx, generation.ID, model.GenerationStatusSucceeded, sharedOutput, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	shares := service.NewShareService(repository.NewShareRepository(db.DB, db.Dialect), generations, "test-secret", "http://reportia.test")
+	router := mux.NewRouter()
+	router.HandleFunc("/share/{token}", handler.NewShareHandler(shares).View).Methods("GET", "POST")
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+	return &shareFixture{db: db.DB, service: shares, server: server, generation: generation.ID}
+}
+
+func (f *shareFixture) create(t *testing.T, password string, maxViews *int) *model.ShareLink {
+	t.Helper()
+	link, err := f.service.Create(context.Background(), f.generation, 1, password, maxViews, "owner@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return link
+}
+
+// open requests the link, with the password form when password is set, and
+// returns the status and body.
+func (f *shareFixture) open(t *testing.T, token string, password string) (int, string) {
+	t.Helper()
+	var resp *http.Response
+	var err error
+	if password == "" {
+		resp, err = http.Get(f.server.URL + "/share/" + token)
+	} else {
+		resp, err = http.PostForm(f.server.URL+"/share/"+token, url.Values{"password": {password}})
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, string(body)
+}
+
+func (f *shareFixture) exec(t *testing.T, query string, args ...any) {
+	t.Helper()
+	if _, err := f.db.Exec(query, args...); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestShareViewWithoutPassword(t *testing.T) {
+	f := newShareFixture(t)
+	link := f.create(t, "", nil)
+
+	status, body := f.open(t, link.Token, "")
+	if status != http.StatusOK || body != sharedOutput {
+		t.Fatalf("got %d %q, want the shared report", status, body)
+	}
+
+	// A token with a valid id but another signature is refused.
+	forged := link.Token[:strings.LastIndex(link.Token, ".")] + ".forged"
+	if status, _ := f.open(t, forged, ""); status != http.StatusNotFound {
+		t.Errorf("forged token: got %d, want %d", status, http.StatusNotFound)
+	}
+}
+
+func TestShareViewPassword(t *testing.T) {
+	f := newShareFixture(t)
+	link := f.create(t, "s3cret", nil)
+
+	status, body := f.open(t, link.Token, "")
+	if status != http.StatusOK || !strings.Contains(body, `type="password"`) || strings.Contains(body, "shared report") {
+		t.Fatalf("got %d %q, want the password form", status, body)
+	}
+	status, body = f.open(t, link.Token, "wrong")
+	if status != http.StatusUnauthorized || !strings.Contains(body, "Invalid password") {
+		t.Errorf("wrong password: got %d %q", status, body)
+	}
+	if status, body := f.open(t, link.Token, "s3cret"); status != http.StatusOK || body != sharedOutput {
+		t.Errorf("right password: got %d %q, want the shared report", status, body)
+	}
+}
+
+func TestShareViewPasswordLockout(t *testing.T) {
+	f := newShareFixture(t)
+	link := f.create(t, "s3cret", nil)
+
+	// The right password clears the wrong ones before it.
+	for range 4 {
+		f.open(t, link.Token, "wrong")
+	}
+	if status, _ := f.open(t, link.Token, "s3cret"); status != http.StatusOK {
+		t.Fatalf("right password after 4 wrong ones: got %d, want %d", status, http.StatusOK)
+	}
+
+	for i := range 5 {
+		if status, _ := f.open(t, link.Token, "wrong"); status != http.StatusUnauthorized {
+			t.Fatalf("wrong password %d: got %d, want %d", i+1, status, http.StatusUnauthorized)
+		}
+	}
+	status, body := f.open(t, link.Token, "s3cret")
+	if status != http.StatusTooManyRequests || strings.Contains(body, "shared report") {
+		t.Fatalf("right password while locked: got %d %q, want %d", status, body, http.StatusTooManyRequests)
+	}
+
+	// Once the lockout passes, a single attempt is allowed before the next.
+	f.exec(t, `UPDATE ShareLink SET locked_until = $1 WHERE id = $2`, database.SQLite.Time(time.Now().Add(-time.Minute)), link.ID)
+	if status, _ := f.open(t, link.Token, "wrong"); status != http.StatusUnauthorized {
+		t.Errorf("wrong password after the lockout: got %d, want %d", status, http.StatusUnauthorized)
+	}
+	if status, _ := f.open(t, link.Token, "s3cret"); status != http.StatusTooManyRequests {
+		t.Errorf("right password after a new wrong one: got %d, want %d", status, http.StatusTooManyRequests)
+	}
+	f.exec(t, `UPDATE ShareLink SET locked_until = $1 WHERE id = $2`, database.SQLite.Time(time.Now().Add(-time.Minute)), link.ID)
+	if status, body := f.open(t, link.Token, "s3cret"); status != http.StatusOK || body != sharedOutput {
+		t.Errorf("right password after the lockout: got %d %q, want the shared report", status, body)
+	}
+}
+
+func TestShareViewExpired(t *testing.T) {
+	f := newShareFixture(t)
+	link := f.create(t, "", nil)
+	f.exec(t, `UPDATE ShareLink SET expires_at = $1 WHERE id = $2`, database.SQLite.Time(time.Now().Add(-time.Minute)), link.ID)
+
+	if status, _ := f.open(t, link.Token, ""); status != http.StatusGone {
+		t.Errorf("got %d, want %d", status, http.StatusGone)
+	}
+}
+
+func TestShareViewLimit(t *testing.T) {
+	f := newShareFixture(t)
+	maxViews := 2
+	link := f.create(t, "s3cret", &maxViews)
+
+	// Showing the password form and wrong passwords use no view.
+	f.open(t, link.Token, "")
+	f.open(t, link.Token, "wrong")
+	for i := range maxViews {
+		if status, _ := f.open(t, link.Token, "s3cret"); status != http.StatusOK {
+			t.Fatalf("view %d: got %d, want %d", i+1, status, http.StatusOK)
+		}
+	}
+	if status, _ := f.open(t, link.Token, "s3cret"); status != http.StatusGone {
+		t.Errorf("view past the limit: got %d, want %d", status, http.StatusGone)
+	}
+	if status, _ := f.open(t, link.Token, ""); status != http.StatusGone {
+		t.Errorf("form past the limit: got %d, want %d", status, http.StatusGone)
+	}
+}
+
+func TestShareViewRevoked(t *testing.T) {
+	f := newShareFixture(t)
+	link := f.create(t, "", nil)
+	if err := f.service.Revoke(context.Background(), link.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	if status, _ := f.open(t, link.Token, ""); status != http.StatusNotFound {
+		t.Errorf("got %d, want %d", status, http.StatusNotFound)
+	}
+	if err := f.service.Revoke(context.Background(), link.ID); err == nil {
+		t.Error("revoked a link twice")
+	}
+}
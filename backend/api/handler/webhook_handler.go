@@ -0,0 +1,157 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"reportia/model"
+	request_report "reportia/model/request"
+	"reportia/problem"
+	"reportia/service"
+	"strconv"
+)
+
+type WebhookHandler struct {
+	service *service.WebhookService
+}
+
+func NewWebhookHandler(s *service.WebhookService) *WebhookHandler {
+	return &WebhookHandler{service: s}
+}
+
+// List godoc
+// @Summary List webhook subscriptions
+// @Description Get all webhook subscriptions. Secrets are never returned here.
+// @Tags webhooks
+// @Produce json
+// @Success 200 {array} model.WebhookSubscription
+// @Failure 500 {object} problem.Problem
+// @Router /api/v1/webhooks [get]
+func (h *WebhookHandler) List(w http.ResponseWriter, r *http.Request) {
+	subscriptions, err := h.service.List(r.Context())
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(subscriptions)
+}
+
+// Create godoc
+// @Summary Create webhook subscription
+// @Description Subscribe a URL to generation and template events. Payloads are signed with HMAC-SHA256 using the returned secret.
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Param webhook body request_report.CreateWebhookReq true "Create Webhook Request"
+// @Success 200 {object} model.WebhookSubscription
+// @Failure 400 {object} problem.Problem
+// @Router /api/v1/webhooks [post]
+func (h *WebhookHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var req request_report.CreateWebhookReq
+
+	if err := decodeJSON(r, &req); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	sub, err := h.service.Create(r.Context(), model.WebhookSubscription{
+		URL:        req.URL,
+		Secret:     req.Secret,
+		EventTypes: req.EventTypes,
+		UserMail:   req.UserMail,
+	})
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(sub)
+}
+
+// Update godoc
+// @Summary Update webhook subscription
+// @Description Update the URL or event types of a webhook subscription
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Param webhook body request_report.UpdateWebhookReq true "Update Webhook Request"
+// @Success 200 {object} model.WebhookSubscription
+// @Failure 400 {object} problem.Problem
+// @Router /api/v1/webhooks [put]
+func (h *WebhookHandler) Update(w http.ResponseWriter, r *http.Request) {
+	var req request_report.UpdateWebhookReq
+
+	if err := decodeJSON(r, &req); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	sub, err := h.service.Update(r.Context(), model.WebhookSubscription{
+		ID:         req.ID,
+		URL:        req.URL,
+		EventTypes: req.EventTypes,
+	})
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(sub)
+}
+
+// TurnOnOff godoc
+// @Summary Turn webhook subscription on or off
+// @Description Activate or deactivate a webhook subscription
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Param request body request_report.TurnOnOffReq true "Turn On/Off Request"
+// @Success 204
+// @Failure 400 {object} problem.Problem
+// @Router /api/v1/webhooks/turnonoff [post]
+func (h *WebhookHandler) TurnOnOff(w http.ResponseWriter, r *http.Request) {
+	var req request_report.TurnOnOffReq
+
+	if err := decodeJSON(r, &req); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	if err := h.service.TurnOnOff(r.Context(), req.ID, req.Active); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListDeliveries godoc
+// @Summary List webhook deliveries
+// @Description Get the most recent events sent to a subscription, with their payload and every delivery attempt
+// @Tags webhooks
+// @Produce json
+// @Param id query int true "Webhook subscription ID"
+// @Param limit query int false "Maximum number of events (default: 20, max: 100)"
+// @Success 200 {array} model.WebhookEvent
+// @Failure 400 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /api/v1/webhooks/deliveries [get]
+func (h *WebhookHandler) ListDeliveries(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	id, _ := strconv.Atoi(q.Get("id"))
+	limit, _ := strconv.Atoi(q.Get("limit"))
+
+	if id == 0 {
+		problem.Write(w, r, invalidParam("id is required"))
+		return
+	}
+
+	events, err := h.service.ListEvents(r.Context(), id, limit)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(events)
+}
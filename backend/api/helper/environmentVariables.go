@@ -1,13 +0,0 @@
-package helper
-
-import (
-	"os"
-)
-
-func GetEnv(key string, defaultValue string) string {
-	value, exists := os.LookupEnv(key)
-	if !exists {
-		return defaultValue
-	}
-	return value
-}
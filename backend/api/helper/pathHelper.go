@@ -0,0 +1,73 @@
+package helper
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// ResolveWithin resolves path inside the directory root, following symlinks,
+// and fails unless the result stays inside root. A relative path is taken
+// from root. Paths with ".." elements are rejected outright. The last
+// elements of path may not exist yet, so that a path can be checked before
+// the file it names is written; callers check it again before opening it.
+func ResolveWithin(root, path string) (string, error) {
+	if root == "" {
+		return "", errors.New("no base directory is configured")
+	}
+	if path == "" {
+		return "", errors.New("path is required")
+	}
+	if slices.Contains(strings.Split(filepath.ToSlash(path), "/"), "..") {
+		return "", fmt.Errorf("path %s must not contain ..", path)
+	}
+
+	realRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return "", fmt.Errorf("base directory %s is not available: %w", root, err)
+	}
+	if realRoot, err = filepath.Abs(realRoot); err != nil {
+		return "", err
+	}
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return "", err
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(absRoot, path)
+	}
+
+	resolved, err := evalExisting(filepath.Clean(path))
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(realRoot, resolved)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("path %s is outside %s", path, root)
+	}
+	return resolved, nil
+}
+
+// evalExisting follows the symlinks of the longest existing prefix of path
+// and appends the elements that do not exist yet.
+func evalExisting(path string) (string, error) {
+	var missing []string
+	for {
+		resolved, err := filepath.EvalSymlinks(path)
+		if err == nil {
+			return filepath.Join(append([]string{resolved}, missing...)...), nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return "", err
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return "", err
+		}
+		missing = append([]string{filepath.Base(path)}, missing...)
+		path = parent
+	}
+}
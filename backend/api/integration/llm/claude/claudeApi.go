@@ -4,8 +4,12 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"log/slog"
 	"path/filepath"
+	"reportia/metrics"
+	"reportia/tracing"
 	"strings"
+	"time"
 
 	"github.com/anthropics/anthropic-sdk-go"
 	"github.com/anthropics/anthropic-sdk-go/option"
@@ -38,17 +42,24 @@ func (ant *AnthropicAPI) GenerateAnalisysFromReportFile(ctx context.Context, pro
 		modelUsed = model
 	}
 
-	message, err := client.Messages.New(ctx, anthropic.MessageNewParams{
+	start := time.Now()
+	spanCtx, span := tracing.Start(ctx, "anthropic.messages")
+	message, err := client.Messages.New(spanCtx, anthropic.MessageNewParams{
 		Model:     anthropic.Model(modelUsed),
 		MaxTokens: int64(4096),
 		Messages: []anthropic.MessageParam{
 			anthropic.NewUserMessage(anthropic.NewTextBlock(fmt.Sprintf("%s\n\n---\nFile name: %s\nFile content:\n%s", prompt, fileBaseName, fileContent))),
 		},
 	})
+	tracing.End(span, err)
 
 	if err != nil {
+		slog.WarnContext(ctx, "llm call failed", "provider", "anthropic", "model", modelUsed, "duration_ms", time.Since(start).Milliseconds(), "error", err)
 		return "", err
 	}
+	slog.InfoContext(ctx, "llm call", "provider", "anthropic", "model", modelUsed, "duration_ms", time.Since(start).Milliseconds(),
+		"input_bytes", len(fileBytes), "input_tokens", message.Usage.InputTokens, "output_tokens", message.Usage.OutputTokens)
+	metrics.ObserveLLMTokens("anthropic", modelUsed, message.Usage.InputTokens, message.Usage.OutputTokens)
 
 	if len(message.Content) > 0 {
 		return message.Content[0].Text, nil
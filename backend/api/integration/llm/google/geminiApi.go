@@ -1,99 +1,252 @@
 package google
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"io"
+	"log/slog"
+	"mime"
+	"path/filepath"
+	"reportia/metrics"
+	"reportia/tracing"
 	"time"
 
-	"golang.org/x/net/context"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/genai"
 )
 
+const (
+	// defaultFileTimeout bounds the wait for an uploaded file when the
+	// configuration does not.
+	defaultFileTimeout = 2 * time.Minute
+	// deleteTimeout bounds the removal of the uploaded file, which goes on
+	// after the request is canceled.
+	deleteTimeout = 10 * time.Second
+)
+
+// The wait between two polls of a file doubles from firstPoll to lastPoll.
+// Tests shorten them.
+var (
+	firstPoll = 250 * time.Millisecond
+	lastPoll  = 5 * time.Second
+)
+
+// SafetyThresholds are the values accepted for Config.SafetyThreshold.
+var SafetyThresholds = []string{
+	string(genai.HarmBlockThresholdBlockLowAndAbove),
+	string(genai.HarmBlockThresholdBlockMediumAndAbove),
+	string(genai.HarmBlockThresholdBlockOnlyHigh),
+	string(genai.HarmBlockThresholdBlockNone),
+	string(genai.HarmBlockThresholdOff),
+}
+
+// safetyCategories are the harm categories SafetyThreshold applies to.
+var safetyCategories = []genai.HarmCategory{
+	genai.HarmCategoryHarassment,
+	genai.HarmCategoryHateSpeech,
+	genai.HarmCategorySexuallyExplicit,
+	genai.HarmCategoryDangerousContent,
+}
+
+// Config holds the Gemini settings. The zero values of the generation
+// settings leave them to the model.
+type Config struct {
+	APIKey string
+	// Model is used when a report does not ask for one.
+	Model string
+	// BaseURL replaces the endpoint of the Gemini API when set.
+	BaseURL         string
+	Temperature     *float32
+	MaxOutputTokens int32
+	SafetyThreshold string
+	// FileTimeout bounds the wait for an uploaded file to be processed.
+	FileTimeout time.Duration
+}
+
 type GeminiAPI struct {
-	apiKey string
-	model  string
+	cfg Config
 }
 
-func NewGeminiAPI(apiKey string, model string) *GeminiAPI {
-	return &GeminiAPI{apiKey: apiKey, model: model}
+func NewGeminiAPI(cfg Config) *GeminiAPI {
+	if cfg.FileTimeout <= 0 {
+		cfg.FileTimeout = defaultFileTimeout
+	}
+	return &GeminiAPI{cfg: cfg}
 }
 
 func (gemini *GeminiAPI) GenerateAnalisysFromReportFile(ctx context.Context, prompt string, model string, file io.Reader, fileName string, fileType string) (string, error) {
-	clientGemini, err := gemini.initializeGeminiClient(ctx)
+	modelUsed := model
+	if modelUsed == "" {
+		modelUsed = gemini.cfg.Model
+	}
+
+	client, err := gemini.initializeGeminiClient(ctx)
 	if err != nil {
 		return "", err
 	}
-	fileNameWithoutExtension := fileName[:len(fileName)-len(".txt")]
-	genaiFile, err := clientGemini.Files.Upload(ctx, file, &genai.UploadFileConfig{
-		Name:     fileNameWithoutExtension,
-		MIMEType: fileType,
-	})
+
+	uploaded, err := gemini.uploadFile(ctx, client, file, fileName, fileType)
 	if err != nil {
 		return "", err
 	}
-	maxTriesToUploadFile := 0
-	for genaiFile.Source != genai.FileSourceUploaded && maxTriesToUploadFile < 10 {
-		time.Sleep(1 * time.Second)
-		maxTriesToUploadFile++
-		genaiFile, err = clientGemini.Files.Get(ctx, genaiFile.Name, &genai.GetFileConfig{
-			HTTPOptions: &genai.HTTPOptions{
-				Timeout: func() *time.Duration { d := 10 * time.Second; return &d }(),
-			},
-		})
-		if err != nil {
-			return "", err
-		}
+	defer gemini.deleteFile(ctx, client, uploaded.Name)
+
+	spanCtx, span := tracing.Start(ctx, "gemini.poll_file")
+	uploaded, polls, err := gemini.waitForFile(spanCtx, client, uploaded)
+	span.SetAttributes(attribute.Int("gemini.polls", polls))
+	tracing.End(span, err)
+	if err != nil {
+		return "", err
 	}
 
-	defer clientGemini.Files.Delete(ctx, genaiFile.Name, &genai.DeleteFileConfig{
-		HTTPOptions: &genai.HTTPOptions{
-			Timeout: func() *time.Duration { d := 10 * time.Second; return &d }(),
-		},
-	})
+	contents := []*genai.Content{
+		genai.NewContentFromParts([]*genai.Part{
+			genai.NewPartFromFile(*uploaded),
+			genai.NewPartFromText(prompt),
+		}, genai.RoleUser),
+	}
 
-	var modelUsedInGemini string
-	if model == "" {
-		modelUsedInGemini = gemini.model
-	} else {
-		modelUsedInGemini = model
+	start := time.Now()
+	spanCtx, span = tracing.Start(ctx, "gemini.generate", trace.WithAttributes(attribute.String("gemini.model", metrics.ModelLabel("gemini", modelUsed))))
+	response, err := client.Models.GenerateContent(spanCtx, modelUsed, contents, gemini.generateContentConfig())
+	if err == nil {
+		err = blocked(response)
 	}
-	chat, err := clientGemini.Chats.Create(ctx, modelUsedInGemini, &genai.GenerateContentConfig{}, make([]*genai.Content, 0))
+	tracing.End(span, err)
 
 	if err != nil {
+		slog.WarnContext(ctx, "llm call failed", "provider", "gemini", "model", modelUsed, "duration_ms", time.Since(start).Milliseconds(), "error", err)
 		return "", err
 	}
+	slog.InfoContext(ctx, "llm call", "provider", "gemini", "model", modelUsed, "duration_ms", time.Since(start).Milliseconds())
+	if usage := response.UsageMetadata; usage != nil {
+		metrics.ObserveLLMTokens("gemini", modelUsed, int64(usage.PromptTokenCount), int64(usage.CandidatesTokenCount))
+	}
 
-	parts := make([]*genai.Part, 0)
+	return response.Text(), nil
+}
 
-	parts = append(parts, genai.NewPartFromFile(*genaiFile))
-	parts = append(parts, &genai.Part{
-		Text: prompt,
-	})
+// uploadFile uploads file under its name. The resource name is left to the
+// API, which only accepts a narrow alphabet, and the file name is kept as
+// the display name.
+func (gemini *GeminiAPI) uploadFile(ctx context.Context, client *genai.Client, file io.Reader, fileName string, fileType string) (*genai.File, error) {
+	if fileType == "" {
+		fileType = mime.TypeByExtension(filepath.Ext(fileName))
+	}
+	if fileType == "" {
+		fileType = "text/plain"
+	}
 
-	contents_user := make([]*genai.Content, 0)
-	contents_user = append(contents_user, &genai.Content{
-		Parts: parts,
-		Role:  genai.RoleUser,
+	spanCtx, span := tracing.Start(ctx, "gemini.upload_file", trace.WithAttributes(attribute.String("file.type", fileType)))
+	uploaded, err := client.Files.Upload(spanCtx, file, &genai.UploadFileConfig{
+		DisplayName: filepath.Base(fileName),
+		MIMEType:    fileType,
 	})
+	tracing.End(span, err)
+	return uploaded, err
+}
 
-	geminiContentResponse, err := chat.GenerateContent(ctx, gemini.model, contents_user, &genai.GenerateContentConfig{})
-
-	if err != nil {
-		return "", err
+// waitForFile polls the file until it is active, backing off between polls,
+// and fails when its processing fails, ctx is done or the file timeout
+// passes. It returns the number of polls.
+func (gemini *GeminiAPI) waitForFile(parent context.Context, client *genai.Client, file *genai.File) (*genai.File, int, error) {
+	ctx, cancel := context.WithTimeout(parent, gemini.cfg.FileTimeout)
+	defer cancel()
+	// expired tells the file timeout from the cancellation of parent.
+	expired := func(state genai.FileState) error {
+		if parent.Err() == nil {
+			return fmt.Errorf("gemini file %s still %s after %s: %w", file.Name, state, gemini.cfg.FileTimeout, ctx.Err())
+		}
+		return parent.Err()
 	}
 
-	return geminiContentResponse.Text(), nil
+	wait := firstPoll
+	for polls := 0; ; polls++ {
+		switch file.State {
+		case genai.FileStateActive:
+			return file, polls, nil
+		case genai.FileStateFailed:
+			message := "unknown error"
+			if file.Error != nil && file.Error.Message != "" {
+				message = file.Error.Message
+			}
+			return nil, polls, fmt.Errorf("gemini could not process file %s: %s", file.Name, message)
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, polls, expired(file.State)
+		case <-timer.C:
+		}
+		wait = min(wait*2, lastPoll)
+
+		polled, err := client.Files.Get(ctx, file.Name, nil)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, polls + 1, expired(file.State)
+			}
+			return nil, polls + 1, err
+		}
+		file = polled
+	}
 }
 
-func (gemini *GeminiAPI) initializeGeminiClient(ctx context.Context) (*genai.Client, error) {
-	context, cancel := context.WithDeadline(ctx, time.Now().Add(60*time.Second))
+// deleteFile removes the uploaded file, even when ctx was canceled.
+func (gemini *GeminiAPI) deleteFile(ctx context.Context, client *genai.Client, name string) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), deleteTimeout)
 	defer cancel()
 
-	client, err := genai.NewClient(context, &genai.ClientConfig{
-		APIKey:  gemini.apiKey,
-		Backend: genai.BackendGeminiAPI,
-	})
+	spanCtx, span := tracing.Start(ctx, "gemini.delete_file")
+	_, err := client.Files.Delete(spanCtx, name, nil)
+	tracing.End(span, err)
 	if err != nil {
-		return nil, err
+		slog.WarnContext(ctx, "could not delete gemini file", "file", name, "error", err)
 	}
-	return client, nil
+}
+
+func (gemini *GeminiAPI) generateContentConfig() *genai.GenerateContentConfig {
+	config := &genai.GenerateContentConfig{
+		Temperature:     gemini.cfg.Temperature,
+		MaxOutputTokens: gemini.cfg.MaxOutputTokens,
+	}
+	if gemini.cfg.SafetyThreshold != "" {
+		for _, category := range safetyCategories {
+			config.SafetySettings = append(config.SafetySettings, &genai.SafetySetting{
+				Category:  category,
+				Threshold: genai.HarmBlockThreshold(gemini.cfg.SafetyThreshold),
+			})
+		}
+	}
+	return config
+}
+
+// blocked reports a response without text because the prompt or the answer
+// was blocked, which the API does not return as an error.
+func blocked(response *genai.GenerateContentResponse) error {
+	if feedback := response.PromptFeedback; feedback != nil && feedback.BlockReason != "" {
+		return fmt.Errorf("gemini blocked the prompt: %s", feedback.BlockReason)
+	}
+	if len(response.Candidates) == 0 {
+		return errors.New("gemini returned no answer")
+	}
+	candidate := response.Candidates[0]
+	if response.Text() == "" && candidate.FinishReason != genai.FinishReasonStop && candidate.FinishReason != "" {
+		return fmt.Errorf("gemini returned no text, finish reason %s", candidate.FinishReason)
+	}
+	return nil
+}
+
+func (gemini *GeminiAPI) initializeGeminiClient(ctx context.Context) (*genai.Client, error) {
+	spanCtx, span := tracing.Start(ctx, "gemini.client_init")
+	client, err := genai.NewClient(spanCtx, &genai.ClientConfig{
+		APIKey:      gemini.cfg.APIKey,
+		Backend:     genai.BackendGeminiAPI,
+		HTTPOptions: genai.HTTPOptions{BaseURL: gemini.cfg.BaseURL},
+	})
+	tracing.End(span, err)
+	return client, err
 }
@@ -0,0 +1,309 @@
+package google
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/genai"
+)
+
+const fileName = "files/report-data"
+
+// fakeGemini stands in for the Gemini API: it takes one upload, answers the
+// polls of the file with states in turn and records the generate request.
+type fakeGemini struct {
+	*httptest.Server
+
+	mu sync.Mutex
+	// states are the states of the file, the first one on upload. The last
+	// one is repeated once they run out.
+	states   []genai.FileState
+	polls    []time.Time
+	uploaded time.Time
+	// answer is the body of the generateContent response.
+	answer       string
+	generatePath string
+	generateBody map[string]any
+	deleted      []string
+}
+
+func newFakeGemini(t *testing.T, states ...genai.FileState) *fakeGemini {
+	f := &fakeGemini{
+		states: states,
+		answer: `{"candidates":[{"content":{"role":"model","parts":[{"text":"the analysis"}]},"finishReason":"STOP"}]}`,
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /upload/v1beta/files", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Goog-Upload-URL", f.URL+"/upload/session")
+		w.Write([]byte("{}"))
+	})
+	mux.HandleFunc("POST /upload/session", func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		f.mu.Lock()
+		f.uploaded = time.Now()
+		f.mu.Unlock()
+		w.Header().Set("X-Goog-Upload-Status", "final")
+		f.writeFile(w, "file", f.state(0))
+	})
+	mux.HandleFunc("GET /v1beta/"+fileName, func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		f.polls = append(f.polls, time.Now())
+		polls := len(f.polls)
+		f.mu.Unlock()
+		f.writeFile(w, "", f.state(polls))
+	})
+	mux.HandleFunc("POST /v1beta/models/{model}", func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		f.generatePath = r.URL.Path
+		json.NewDecoder(r.Body).Decode(&f.generateBody)
+		answer := f.answer
+		f.mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(answer))
+	})
+	mux.HandleFunc("DELETE /v1beta/"+fileName, func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		f.deleted = append(f.deleted, strings.TrimPrefix(r.URL.Path, "/v1beta/"))
+		f.mu.Unlock()
+		w.Write([]byte("{}"))
+	})
+	f.Server = httptest.NewServer(mux)
+	t.Cleanup(f.Close)
+	return f
+}
+
+func (f *fakeGemini) state(i int) genai.FileState {
+	return f.states[min(i, len(f.states)-1)]
+}
+
+// writeFile answers with the file, wrapped in key unless key is empty.
+func (f *fakeGemini) writeFile(w http.ResponseWriter, key string, state genai.FileState) {
+	file := map[string]any{
+		"name":     fileName,
+		"mimeType": "text/csv",
+		"uri":      f.URL + "/v1beta/" + fileName,
+		"state":    state,
+	}
+	if state == genai.FileStateFailed {
+		file["error"] = map[string]any{"code": 3, "message": "unsupported spreadsheet"}
+	}
+	var body any = file
+	if key != "" {
+		body = map[string]any{key: file}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(body)
+}
+
+func (f *fakeGemini) api(cfg Config) *GeminiAPI {
+	cfg.APIKey = "test-key"
+	cfg.BaseURL = f.URL + "/"
+	if cfg.Model == "" {
+		cfg.Model = "gemini-default"
+	}
+	return NewGeminiAPI(cfg)
+}
+
+// shortPolls makes the tests poll every few milliseconds.
+func shortPolls(t *testing.T, first, last time.Duration) {
+	savedFirst, savedLast := firstPoll, lastPoll
+	firstPoll, lastPoll = first, last
+	t.Cleanup(func() { firstPoll, lastPoll = savedFirst, savedLast })
+}
+
+func generate(api *GeminiAPI, ctx context.Context, model string) (string, error) {
+	return api.GenerateAnalisysFromReportFile(ctx, "Summarize the data", model, strings.NewReader("month,total\njan,3\n"), "data.csv", "text/csv")
+}
+
+func TestGeneratePollsUntilActive(t *testing.T) {
+	shortPolls(t, 10*time.Millisecond, 40*time.Millisecond)
+	fake := newFakeGemini(t, genai.FileStateProcessing, genai.FileStateProcessing, genai.FileStateProcessing, genai.FileStateProcessing, genai.FileStateActive)
+
+	text, err := generate(fake.api(Config{}), context.Background(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text != "the analysis" {
+		t.Errorf("got %q, want the text of the answer", text)
+	}
+
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	// The wait doubles from firstPoll and stops growing at lastPoll.
+	waits := []time.Duration{10 * time.Millisecond, 20 * time.Millisecond, 40 * time.Millisecond, 40 * time.Millisecond}
+	if len(fake.polls) != len(waits) {
+		t.Fatalf("%d polls, want %d", len(fake.polls), len(waits))
+	}
+	previous := fake.uploaded
+	for i, poll := range fake.polls {
+		if gap := poll.Sub(previous); gap < waits[i] {
+			t.Errorf("poll %d came %s after the previous one, want at least %s", i+1, gap, waits[i])
+		}
+		previous = poll
+	}
+	if fake.generatePath != "/v1beta/models/gemini-default:generateContent" {
+		t.Errorf("generate path %s, want the configured model", fake.generatePath)
+	}
+	if len(fake.deleted) != 1 || fake.deleted[0] != fileName {
+		t.Errorf("deleted %v, want the uploaded file", fake.deleted)
+	}
+}
+
+func TestGenerateUsesRequestedModelAndSettings(t *testing.T) {
+	fake := newFakeGemini(t, genai.FileStateActive)
+	temperature := float32(0.5)
+	api := fake.api(Config{
+		Model:           "gemini-default",
+		Temperature:     &temperature,
+		MaxOutputTokens: 512,
+		SafetyThreshold: string(genai.HarmBlockThresholdBlockOnlyHigh),
+	})
+
+	if _, err := generate(api, context.Background(), "gemini-requested"); err != nil {
+		t.Fatal(err)
+	}
+
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	if fake.generatePath != "/v1beta/models/gemini-requested:generateContent" {
+		t.Errorf("generate path %s, want the requested model", fake.generatePath)
+	}
+	generationConfig, _ := fake.generateBody["generationConfig"].(map[string]any)
+	if generationConfig["temperature"] != 0.5 {
+		t.Errorf("temperature %v, want 0.5", generationConfig["temperature"])
+	}
+	if generationConfig["maxOutputTokens"] != float64(512) {
+		t.Errorf("maxOutputTokens %v, want 512", generationConfig["maxOutputTokens"])
+	}
+	settings, _ := fake.generateBody["safetySettings"].([]any)
+	if len(settings) != len(safetyCategories) {
+		t.Fatalf("%d safety settings, want one per category: %v", len(settings), settings)
+	}
+	for i, setting := range settings {
+		setting, _ := setting.(map[string]any)
+		if setting["category"] != string(safetyCategories[i]) || setting["threshold"] != "BLOCK_ONLY_HIGH" {
+			t.Errorf("safety setting %d is %v", i, setting)
+		}
+	}
+}
+
+func TestGenerateLeavesUnsetSettingsToTheModel(t *testing.T) {
+	fake := newFakeGemini(t, genai.FileStateActive)
+
+	if _, err := generate(fake.api(Config{}), context.Background(), ""); err != nil {
+		t.Fatal(err)
+	}
+
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	generationConfig, _ := fake.generateBody["generationConfig"].(map[string]any)
+	for _, key := range []string{"temperature", "maxOutputTokens"} {
+		if value, ok := generationConfig[key]; ok {
+			t.Errorf("%s sent as %v", key, value)
+		}
+	}
+	if settings, ok := fake.generateBody["safetySettings"]; ok {
+		t.Errorf("safety settings sent as %v", settings)
+	}
+}
+
+func TestGenerateFailedFile(t *testing.T) {
+	shortPolls(t, time.Millisecond, time.Millisecond)
+	fake := newFakeGemini(t, genai.FileStateProcessing, genai.FileStateFailed)
+
+	_, err := generate(fake.api(Config{}), context.Background(), "")
+	if err == nil || !strings.Contains(err.Error(), "unsupported spreadsheet") {
+		t.Fatalf("got %v, want the processing error", err)
+	}
+
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	if fake.generatePath != "" {
+		t.Error("generated content from a failed file")
+	}
+	if len(fake.deleted) != 1 {
+		t.Errorf("deleted %v, want the uploaded file", fake.deleted)
+	}
+}
+
+func TestGenerateFileTimeout(t *testing.T) {
+	shortPolls(t, time.Millisecond, 5*time.Millisecond)
+	fake := newFakeGemini(t, genai.FileStateProcessing)
+
+	_, err := generate(fake.api(Config{FileTimeout: 50 * time.Millisecond}), context.Background(), "")
+	if !errors.Is(err, context.DeadlineExceeded) || !strings.Contains(err.Error(), "still PROCESSING after 50ms") {
+		t.Fatalf("got %v, want the file timeout", err)
+	}
+
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	if len(fake.deleted) != 1 {
+		t.Errorf("deleted %v, want the uploaded file", fake.deleted)
+	}
+}
+
+func TestGenerateCanceledWhileWaiting(t *testing.T) {
+	shortPolls(t, time.Millisecond, 5*time.Millisecond)
+	fake := newFakeGemini(t, genai.FileStateProcessing)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := generate(fake.api(Config{FileTimeout: time.Minute}), ctx, "")
+	if !errors.Is(err, context.DeadlineExceeded) || strings.Contains(err.Error(), "still") {
+		t.Fatalf("got %v, want the deadline of the request, not the file timeout", err)
+	}
+
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	if len(fake.deleted) != 1 {
+		t.Errorf("deleted %v, want the uploaded file even after the cancellation", fake.deleted)
+	}
+}
+
+func TestGenerateBlockedPrompt(t *testing.T) {
+	fake := newFakeGemini(t, genai.FileStateActive)
+	fake.answer = `{"promptFeedback":{"blockReason":"SAFETY"}}`
+
+	_, err := generate(fake.api(Config{}), context.Background(), "")
+	if err == nil || !strings.Contains(err.Error(), "blocked the prompt: SAFETY") {
+		t.Fatalf("got %v, want the block reason", err)
+	}
+}
+
+func TestBlocked(t *testing.T) {
+	answer := func(text string, reason genai.FinishReason) *genai.GenerateContentResponse {
+		candidate := &genai.Candidate{FinishReason: reason, Content: genai.NewContentFromText(text, genai.RoleModel)}
+		return &genai.GenerateContentResponse{Candidates: []*genai.Candidate{candidate}}
+	}
+	for _, tc := range []struct {
+		name     string
+		response *genai.GenerateContentResponse
+		wantErr  string
+	}{
+		{"blocked prompt", &genai.GenerateContentResponse{PromptFeedback: &genai.GenerateContentResponsePromptFeedback{BlockReason: genai.BlockedReasonSafety}}, "blocked the prompt"},
+		{"no candidates", &genai.GenerateContentResponse{}, "no answer"},
+		{"empty answer blocked", answer("", genai.FinishReasonSafety), "finish reason SAFETY"},
+		{"empty answer cut short", answer("", genai.FinishReasonMaxTokens), "finish reason MAX_TOKENS"},
+		{"empty answer", answer("", genai.FinishReasonStop), ""},
+		{"answer", answer("the analysis", genai.FinishReasonStop), ""},
+		{"truncated answer", answer("the anal", genai.FinishReasonMaxTokens), ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := blocked(tc.response)
+			switch {
+			case tc.wantErr == "" && err != nil:
+				t.Errorf("got %v, want no error", err)
+			case tc.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tc.wantErr)):
+				t.Errorf("got %v, want an error with %q", err, tc.wantErr)
+			}
+		})
+	}
+}
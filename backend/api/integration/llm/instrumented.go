@@ -0,0 +1,51 @@
+package llm
+
+import (
+	"context"
+	"io"
+	"reportia/metrics"
+	"reportia/tracing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumented records the latency and outcome of the calls to an LLM and
+// traces them. Token usage and the phases of a call are only known to the
+// adapters, which record them themselves.
+type instrumented struct {
+	next         LLM
+	provider     string
+	defaultModel string
+}
+
+// Instrument wraps llm so that its calls are exported as metrics under
+// provider. defaultModel labels the calls that do not name a model, and
+// models not declared with metrics.SetModels are labelled as
+// metrics.OtherModel.
+func Instrument(llm LLM, provider string, defaultModel string) LLM {
+	return &instrumented{next: llm, provider: provider, defaultModel: defaultModel}
+}
+
+func (i *instrumented) GenerateAnalisysFromReportFile(ctx context.Context, prompt string, model string, file io.Reader, fileName string, fileType string) (string, error) {
+	label := model
+	if label == "" {
+		label = i.defaultModel
+	}
+	label = metrics.ModelLabel(i.provider, label)
+	ctx, span := tracing.Start(ctx, "llm.generate", trace.WithAttributes(
+		attribute.String("llm.provider", i.provider),
+		attribute.String("llm.model", label),
+	))
+	start := time.Now()
+	output, err := i.next.GenerateAnalisysFromReportFile(ctx, prompt, model, file, fileName, fileType)
+	tracing.End(span, err)
+	metrics.LLMDuration.WithLabelValues(i.provider, label).Observe(time.Since(start).Seconds())
+	outcome := "success"
+	if err != nil {
+		outcome = "error"
+	}
+	metrics.LLMRequests.WithLabelValues(i.provider, label, outcome).Inc()
+	return output, err
+}
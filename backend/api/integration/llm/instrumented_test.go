@@ -0,0 +1,50 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"io"
+	"reportia/metrics"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+type stubLLM struct{ err error }
+
+func (s stubLLM) GenerateAnalisysFromReportFile(context.Context, string, string, io.Reader, string, string) (string, error) {
+	return "analysis", s.err
+}
+
+func TestInstrumentLabelsUndeclaredModelsAsOther(t *testing.T) {
+	metrics.SetModels("test", "model-default", "model-known")
+	llm := Instrument(stubLLM{}, "test", "model-default")
+	failing := Instrument(stubLLM{err: errors.New("failed")}, "test", "model-default")
+	ctx := context.Background()
+
+	for _, model := range []string{"", "model-known", "model-a", "model-b", strings.Repeat("x", 300)} {
+		if _, err := llm.GenerateAnalisysFromReportFile(ctx, "prompt", model, strings.NewReader(""), "data.csv", "text/csv"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	failing.GenerateAnalisysFromReportFile(ctx, "prompt", "model-c", strings.NewReader(""), "data.csv", "text/csv")
+
+	for _, tc := range []struct {
+		model, outcome string
+		want           float64
+	}{
+		{"model-default", "success", 1},
+		{"model-known", "success", 1},
+		{metrics.OtherModel, "success", 3},
+		{metrics.OtherModel, "error", 1},
+	} {
+		if got := testutil.ToFloat64(metrics.LLMRequests.WithLabelValues("test", tc.model, tc.outcome)); got != tc.want {
+			t.Errorf("%s %s: %v calls, want %v", tc.model, tc.outcome, got, tc.want)
+		}
+	}
+	// One series per declared model and outcome, and one for all the others.
+	if got := testutil.CollectAndCount(metrics.LLMDuration); got != 3 {
+		t.Errorf("%d duration series, want 3", got)
+	}
+}
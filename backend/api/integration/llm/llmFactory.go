@@ -4,7 +4,6 @@ import (
 	"context"
 	"errors"
 	"io"
-	"reportia/helper"
 	anthropic "reportia/integration/llm/claude"
 	"reportia/integration/llm/google"
 	openaiapi "reportia/integration/llm/openai"
@@ -23,22 +22,59 @@ func GenerateAnalisysFromReportFile(llm LLM, ctx context.Context, prompt string,
 	return llm.GenerateAnalisysFromReportFile(ctx, prompt, model, file, fileName, fileType)
 }
 
-func NewLLM(llm string) LLM {
-	if llm == "" {
+type ProviderConfig struct {
+	APIKey       string
+	DefaultModel string
+}
+
+type Config struct {
+	OpenAI    ProviderConfig
+	Anthropic ProviderConfig
+	Gemini    google.Config
+}
+
+// Providers builds the LLM a report asks for from the configured providers.
+type Providers struct {
+	cfg Config
+}
+
+func NewProviders(cfg Config) *Providers {
+	return &Providers{cfg: cfg}
+}
+
+// Configured lists the providers that have an API key.
+func (p *Providers) Configured() []string {
+	var providers []string
+	for _, provider := range []struct {
+		name   string
+		apiKey string
+	}{
+		{"openai", p.cfg.OpenAI.APIKey},
+		{"anthropic", p.cfg.Anthropic.APIKey},
+		{"gemini", p.cfg.Gemini.APIKey},
+	} {
+		if provider.apiKey != "" {
+			providers = append(providers, provider.name)
+		}
+	}
+	return providers
+}
+
+// New returns the LLM named llm, or nil when no LLM is asked for. Unknown
+// names fall back to Gemini.
+func (p *Providers) New(llm string) LLM {
+	if p == nil || llm == "" {
 		return nil
 	}
 	switch llm {
 	case "openai":
-		apiKey := helper.GetEnv("OPENAI_API_KEY", "")
-		model := helper.GetEnv("OPENAI_MODEL_DEFAULT", "")
-		return openaiapi.NewOpenAIAPI(apiKey, model)
+		cfg := p.cfg.OpenAI
+		return Instrument(openaiapi.NewOpenAIAPI(cfg.APIKey, cfg.DefaultModel), "openai", cfg.DefaultModel)
 	case "anthropic", "claude":
-		apiKey := helper.GetEnv("ANTHROPIC_API_KEY", "")
-		model := helper.GetEnv("ANTHROPIC_MODEL_DEFAULT", "")
-		return anthropic.NewAnthropicAPI(apiKey, model)
+		cfg := p.cfg.Anthropic
+		return Instrument(anthropic.NewAnthropicAPI(cfg.APIKey, cfg.DefaultModel), "anthropic", cfg.DefaultModel)
 	default:
-		apiKey := helper.GetEnv("GEMINI_API_KEY", "")
-		model := helper.GetEnv("GEMINI_MODEL_DEFAULT", "")
-		return google.NewGeminiAPI(apiKey, model)
+		cfg := p.cfg.Gemini
+		return Instrument(google.NewGeminiAPI(cfg), "gemini", cfg.Model)
 	}
 }
@@ -4,8 +4,12 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"log/slog"
 	"path/filepath"
+	"reportia/metrics"
+	"reportia/tracing"
 	"strings"
+	"time"
 
 	openai "github.com/sashabaranov/go-openai"
 )
@@ -46,10 +50,17 @@ func (oai *OpenAIAPI) GenerateAnalisysFromReportFile(ctx context.Context, prompt
 		},
 	}
 
-	resp, err := client.CreateChatCompletion(ctx, req)
+	start := time.Now()
+	spanCtx, span := tracing.Start(ctx, "openai.chat_completion")
+	resp, err := client.CreateChatCompletion(spanCtx, req)
+	tracing.End(span, err)
 	if err != nil {
+		slog.WarnContext(ctx, "llm call failed", "provider", "openai", "model", modelUsed, "duration_ms", time.Since(start).Milliseconds(), "error", err)
 		return "", err
 	}
+	slog.InfoContext(ctx, "llm call", "provider", "openai", "model", modelUsed, "duration_ms", time.Since(start).Milliseconds(),
+		"input_bytes", len(fileBytes), "prompt_tokens", resp.Usage.PromptTokens, "completion_tokens", resp.Usage.CompletionTokens)
+	metrics.ObserveLLMTokens("openai", modelUsed, int64(resp.Usage.PromptTokens), int64(resp.Usage.CompletionTokens))
 
 	if len(resp.Choices) > 0 {
 		return resp.Choices[0].Message.Content, nil
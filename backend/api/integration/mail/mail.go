@@ -0,0 +1,31 @@
+package mail
+
+import (
+	"context"
+	"errors"
+	"net/textproto"
+)
+
+type Attachment struct {
+	FileName    string
+	ContentType string
+	Content     []byte
+}
+
+type Message struct {
+	To          []string
+	Subject     string
+	HTML        string
+	Attachments []Attachment
+}
+
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// IsPermanent reports whether the server rejected the message with a 5xx
+// reply, meaning a retry would bounce again.
+func IsPermanent(err error) bool {
+	var protoErr *textproto.Error
+	return errors.As(err, &protoErr) && protoErr.Code >= 500
+}
@@ -0,0 +1,60 @@
+package renderer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+)
+
+const chromeRenderTimeout = 60 * time.Second
+
+// ChromeRenderer shells out to a headless Chrome/Chromium binary.
+type ChromeRenderer struct {
+	binaryPath string
+}
+
+func NewChromeRenderer(binaryPath string) *ChromeRenderer {
+	return &ChromeRenderer{binaryPath: binaryPath}
+}
+
+func (c *ChromeRenderer) RenderPDF(ctx context.Context, html string) ([]byte, error) {
+	return c.render(ctx, html, "report.pdf", func(outputPath string) []string {
+		return []string{"--no-pdf-header-footer", "--print-to-pdf=" + outputPath}
+	})
+}
+
+func (c *ChromeRenderer) RenderPNG(ctx context.Context, html string, width, height int) ([]byte, error) {
+	return c.render(ctx, html, "report.png", func(outputPath string) []string {
+		return []string{"--hide-scrollbars", fmt.Sprintf("--window-size=%d,%d", width, height), "--screenshot=" + outputPath}
+	})
+}
+
+// render writes the page to a temporary directory and runs Chrome on it with
+// the flags producing outputName there.
+func (c *ChromeRenderer) render(ctx context.Context, html, outputName string, flags func(outputPath string) []string) ([]byte, error) {
+	dir, err := os.MkdirTemp("", "reportia-render-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	inputPath := filepath.Join(dir, "report.html")
+	outputPath := filepath.Join(dir, outputName)
+	if err := os.WriteFile(inputPath, []byte(html), 0o600); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, chromeRenderTimeout)
+	defer cancel()
+
+	args := []string{"--headless", "--disable-gpu", "--no-sandbox", "--virtual-time-budget=5000"}
+	args = append(args, flags(outputPath)...)
+	cmd := exec.CommandContext(ctx, c.binaryPath, append(args, "file://"+inputPath)...)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("headless chrome failed: %w: %s", err, output)
+	}
+	return os.ReadFile(outputPath)
+}
@@ -0,0 +1,25 @@
+package renderer
+
+import (
+	"context"
+	"errors"
+)
+
+var ErrRendererNotConfigured = errors.New("headless renderer not configured")
+
+// Renderer turns a generated HTML report into other document formats.
+type Renderer interface {
+	RenderPDF(ctx context.Context, html string) ([]byte, error)
+	// RenderPNG takes a screenshot of the top of the page in a viewport of
+	// the given size.
+	RenderPNG(ctx context.Context, html string, width, height int) ([]byte, error)
+}
+
+// NewRenderer returns the headless Chrome renderer when a browser binary is
+// configured, and nil otherwise.
+func NewRenderer(chromePath string) Renderer {
+	if chromePath == "" {
+		return nil
+	}
+	return NewChromeRenderer(chromePath)
+}
@@ -0,0 +1,94 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLocalStorageKeys(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "assets")
+	s, err := NewLocalStorage(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		key   string
+		valid bool
+	}{
+		{"reports/12/logo-1a2b3c", true},
+		{"logo", true},
+		{"", false},
+		{".", false},
+		{"../outside", false},
+		{"reports/../../outside", false},
+		{"reports/./logo", false},
+		{"/etc/passwd", false},
+		{"reports//logo", false},
+		{"reports/12/", false},
+	} {
+		t.Run(tc.key, func(t *testing.T) {
+			putErr := s.Put(context.Background(), tc.key, []byte("content"), "image/png")
+			_, openErr := s.Open(context.Background(), tc.key)
+			deleteErr := s.Delete(context.Background(), tc.key)
+			if tc.valid {
+				if putErr != nil || openErr != nil || deleteErr != nil {
+					t.Errorf("got %v, %v, %v, want no error", putErr, openErr, deleteErr)
+				}
+				return
+			}
+			if putErr == nil || openErr == nil || deleteErr == nil {
+				t.Errorf("got %v, %v, %v, want the key refused", putErr, openErr, deleteErr)
+			}
+		})
+	}
+
+	// Nothing was written out of the directory.
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "assets" {
+		t.Errorf("got %v next to the asset directory", entries)
+	}
+}
+
+func TestLocalStorage(t *testing.T) {
+	ctx := context.Background()
+	s, err := NewLocalStorage(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Open(ctx, "reports/1/logo"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("missing object: got %v, want ErrNotFound", err)
+	}
+	if err := s.Delete(ctx, "reports/1/logo"); err != nil {
+		t.Errorf("deleting a missing object: %v", err)
+	}
+
+	for _, content := range []string{"first", "second"} {
+		if err := s.Put(ctx, "reports/1/logo", []byte(content), "image/png"); err != nil {
+			t.Fatal(err)
+		}
+		f, err := s.Open(ctx, "reports/1/logo")
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, _ := io.ReadAll(f)
+		f.Close()
+		if string(got) != content {
+			t.Errorf("got %q, want %q", got, content)
+		}
+	}
+
+	if err := s.Delete(ctx, "reports/1/logo"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Open(ctx, "reports/1/logo"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("deleted object: got %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,49 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const (
+	BackendLocal = "local"
+	BackendS3    = "s3"
+)
+
+var ErrNotFound = errors.New("object not found")
+
+// Storage keeps the content of template assets. Keys are slash-separated
+// paths such as "reports/12/logo-1a2b3c".
+type Storage interface {
+	Put(ctx context.Context, key string, content []byte, contentType string) error
+	// Open returns ErrNotFound when no object has the key.
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete succeeds when the object is already gone.
+	Delete(ctx context.Context, key string) error
+}
+
+type Config struct {
+	Backend     string
+	Dir         string
+	S3Endpoint  string
+	S3Bucket    string
+	S3Prefix    string
+	S3Region    string
+	S3UseSSL    bool
+	S3AccessKey string
+	S3SecretKey string
+}
+
+// New returns the storage selected by cfg.Backend.
+func New(cfg Config) (Storage, error) {
+	switch cfg.Backend {
+	case BackendLocal, "":
+		return NewLocalStorage(cfg.Dir)
+	case BackendS3:
+		return NewS3Storage(cfg)
+	default:
+		return nil, fmt.Errorf("unknown asset storage %q, expected %s or %s", cfg.Backend, BackendLocal, BackendS3)
+	}
+}
@@ -0,0 +1,70 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"reportia/netguard"
+	"strconv"
+	"time"
+)
+
+const (
+	HeaderEvent     = "X-ReportIA-Event"
+	HeaderDelivery  = "X-ReportIA-Delivery"
+	HeaderTimestamp = "X-ReportIA-Timestamp"
+	HeaderSignature = "X-ReportIA-Signature"
+)
+
+// Sign computes the value of the signature header: an HMAC-SHA256 of
+// "<timestamp>.<body>" keyed with the subscription secret. Receivers should
+// recompute it and reject stale timestamps to prevent replays.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+type Sender struct {
+	client *http.Client
+}
+
+// NewSender posts events within timeout to the destinations network allows.
+func NewSender(timeout time.Duration, network netguard.Policy) *Sender {
+	return &Sender{client: network.Client(timeout)}
+}
+
+// Send posts the signed body and returns the response status code. Any non
+// 2xx status is reported as an error so the caller can retry.
+func (s *Sender) Send(ctx context.Context, url, secret string, deliveryID int, eventType string, body []byte) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "ReportIA-Webhook/1.0")
+	req.Header.Set(HeaderEvent, eventType)
+	req.Header.Set(HeaderDelivery, strconv.Itoa(deliveryID))
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(secret, timestamp, body))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("webhook endpoint returned status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,71 @@
+package webhook_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reportia/integration/webhook"
+	"reportia/netguard"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestSenderSignsEvents(t *testing.T) {
+	body := []byte(`{"id":7,"type":"generation.succeeded"}`)
+	var got *http.Request
+	var gotBody []byte
+	status := http.StatusNoContent
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		gotBody, _ = io.ReadAll(r.Body)
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	sender := webhook.NewSender(5*time.Second, netguard.Policy{AllowPrivate: true})
+	code, err := sender.Send(context.Background(), server.URL, "secret", 7, "generation.succeeded", body)
+	if err != nil || code != http.StatusNoContent {
+		t.Fatalf("got %d %v, want %d", code, err, http.StatusNoContent)
+	}
+	if string(gotBody) != string(body) {
+		t.Errorf("got body %s", gotBody)
+	}
+	if got.Header.Get(webhook.HeaderEvent) != "generation.succeeded" || got.Header.Get(webhook.HeaderDelivery) != "7" {
+		t.Errorf("got headers %v", got.Header)
+	}
+	timestamp, err := strconv.ParseInt(got.Header.Get(webhook.HeaderTimestamp), 10, 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := webhook.Sign("secret", timestamp, body); got.Header.Get(webhook.HeaderSignature) != want {
+		t.Errorf("got signature %s, want %s", got.Header.Get(webhook.HeaderSignature), want)
+	}
+
+	status = http.StatusServiceUnavailable
+	if code, err := sender.Send(context.Background(), server.URL, "secret", 7, "generation.succeeded", body); err == nil || code != status {
+		t.Errorf("got %d %v, want an error with %d", code, err, status)
+	}
+}
+
+func TestSenderRefusesPrivateNetworks(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("request reached a loopback server")
+	}))
+	defer server.Close()
+
+	sender := webhook.NewSender(5*time.Second, netguard.Policy{})
+	if _, err := sender.Send(context.Background(), server.URL, "secret", 1, "generation.failed", []byte("{}")); !errors.Is(err, netguard.ErrForbidden) {
+		t.Errorf("got %v, want ErrForbidden", err)
+	}
+}
+
+func TestSign(t *testing.T) {
+	// printf '1700000000.{}' | openssl dgst -sha256 -hmac secret
+	const want = "sha256=b8569b78799ff9e3cbff0fc2d63a33a2b57f3282abd07c37ae5e8e7d79a5f163"
+	if got := webhook.Sign("secret", 1700000000, []byte("{}")); got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,105 @@
+package logging
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const redacted = "[REDACTED]"
+
+// Credentials that are always redacted, on top of the configured ones.
+var (
+	DefaultRedactedHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Api-Key"}
+	DefaultRedactedFields  = []string{"password", "secret", "token", "api_key", "access_key", "secret_key", "authorization"}
+)
+
+// Redaction lists what must never reach the logs. Matching is case
+// insensitive; fields apply to JSON object keys at any depth and to query
+// parameters.
+type Redaction struct {
+	Headers []string
+	Fields  []string
+}
+
+func (rd Redaction) header(name string) bool {
+	return containsFold(rd.Headers, name)
+}
+
+func (rd Redaction) field(name string) bool {
+	return containsFold(rd.Fields, name)
+}
+
+// RedactHeaders flattens h, masking the listed headers.
+func (rd Redaction) RedactHeaders(h http.Header) map[string]string {
+	out := make(map[string]string, len(h))
+	for name, values := range h {
+		if rd.header(name) {
+			out[name] = redacted
+			continue
+		}
+		out[name] = strings.Join(values, ", ")
+	}
+	return out
+}
+
+// RedactQuery masks the listed fields of a raw query string.
+func (rd Redaction) RedactQuery(rawQuery string) string {
+	if rawQuery == "" {
+		return ""
+	}
+	values, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return redacted
+	}
+	for name := range values {
+		if rd.field(name) {
+			values[name] = []string{redacted}
+		}
+	}
+	return values.Encode()
+}
+
+// RedactJSON masks the listed fields of a JSON document. Bodies that are not
+// valid JSON are dropped entirely since they cannot be inspected.
+func (rd Redaction) RedactJSON(body []byte) string {
+	var doc any
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return redacted
+	}
+	var out strings.Builder
+	enc := json.NewEncoder(&out)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(rd.redactValue(doc)); err != nil {
+		return redacted
+	}
+	return strings.TrimSuffix(out.String(), "\n")
+}
+
+func (rd Redaction) redactValue(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for key, value := range v {
+			if rd.field(key) {
+				v[key] = redacted
+			} else {
+				v[key] = rd.redactValue(value)
+			}
+		}
+	case []any:
+		for i, value := range v {
+			v[i] = rd.redactValue(value)
+		}
+	}
+	return v
+}
+
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -1,11 +1,22 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
+	"os/signal"
 	"reportia/config"
+	"reportia/database"
+	"reportia/logging"
+	"reportia/migrations"
 	"reportia/server"
+	"reportia/tracing"
+	"strconv"
+	"syscall"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -23,26 +34,154 @@ import (
 // @license.url http://www.apache.org/licenses/LICENSE-2.0.html
 
 // @host localhost:8080
+
+// @securityDefinitions.apikey AdminToken
+// @in header
+// @name Authorization
+// @description "Bearer " followed by the ADMIN_TOKEN
 func main() {
-	env := os.Getenv("ENVIRONMENT")
+	// A .env file in the working directory is optional; the variables it sets
+	// are read like any other environment variable.
+	envErr := godotenv.Load(".env")
+
+	cfg, args, err := config.Load(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	level, _ := logging.ParseLevel(cfg.Log.Level)
+	logging.Setup(level)
+	if envErr == nil {
+		slog.Info("loaded environment variables from .env")
+	}
+
+	command := ""
+	if len(args) > 0 {
+		command = args[0]
+	}
+	switch command {
+	case "":
+		if cfg.Share.LinkSecret == "" {
+			slog.Warn("share.link_secret (SHARE_LINK_SECRET) not set, share links will not survive a restart")
+			cfg.Share.LinkSecret = config.RandomSecret()
+		}
+		if err := run(cfg); err != nil {
+			slog.Error("server failed", "error", err)
+			os.Exit(1)
+		}
+	case "migrate":
+		if err := runMigrate(cfg, args[1:]); err != nil {
+			slog.Error("migrate failed", "error", err)
+			os.Exit(1)
+		}
+	case "config":
+		if len(args) != 2 || args[1] != "print" {
+			fmt.Fprintln(os.Stderr, "usage: config print")
+			os.Exit(2)
+		}
+		if err := cfg.Print(os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n", command)
+		os.Exit(2)
+	}
+}
 
-	fmt.Println("Environment:", env)
+// run serves the API until SIGINT or SIGTERM, then releases what it set up.
+func run(cfg *config.Config) error {
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing.Exporter)
+	if err != nil {
+		return fmt.Errorf("could not set up tracing: %w", err)
+	}
+	defer func() {
+		flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(flushCtx); err != nil {
+			slog.Warn("could not flush traces", "error", err)
+		}
+	}()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		// A second signal kills the process without waiting for the drain.
+		<-ctx.Done()
+		stop()
+	}()
 
-	var envFile string
-	if env != "local" && env != "" {
-		envFile = ".env"
-	} else {
-		envFile = ".env.local"
+	var db *database.DB
+	if cfg.Server.StorageMode != config.StorageModeInMemory {
+		if db, err = database.Open(ctx, cfg.DatabaseOptions()); err != nil {
+			return err
+		}
+		defer db.Close()
 	}
 
-	fmt.Println("Loading environment variables from:", envFile)
+	srv, err := server.New(cfg, db)
+	if err != nil {
+		return err
+	}
+	return srv.Start(ctx)
+}
 
-	if err := godotenv.Load(envFile); err != nil {
-		log.Println("No .env file found, using environment variables")
+// runMigrate implements "migrate up", "migrate down [steps]" and
+// "migrate status".
+func runMigrate(cfg *config.Config, args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: migrate up | down [steps] | status")
 	}
-	cfg := config.Load()
-	srv := server.New(cfg)
-	if err := srv.Start(); err != nil {
-		log.Fatalf("server failed: %v", err)
+
+	db, err := database.Open(context.Background(), cfg.DatabaseOptions())
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	migrator, err := migrations.New(db.DB, db.Dialect)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Applied %d migration(s)\n", applied)
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps <= 0 {
+				return fmt.Errorf("invalid number of steps %q", args[1])
+			}
+		}
+		reverted, err := migrator.Down(ctx, steps)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Reverted %d migration(s)\n", reverted)
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, status := range statuses {
+			appliedAt := "pending"
+			if status.AppliedAt != nil {
+				appliedAt = status.AppliedAt.Format("2006-01-02 15:04:05 MST")
+			}
+			fmt.Printf("%03d  %-40s %s\n", status.Version, status.Name, appliedAt)
+		}
+	default:
+		return fmt.Errorf("unknown migrate command %q", args[0])
 	}
+	return nil
 }
@@ -0,0 +1,133 @@
+// Package metrics holds the Prometheus collectors of the API, served on
+// /metrics. They are registered on their own registry so that the exposition
+// only carries what the process declares.
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "reportia"
+
+var Registry = prometheus.NewRegistry()
+
+var factory = promauto.With(Registry)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+var (
+	HTTPRequests = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by method, route template and status.",
+	}, []string{"method", "route", "status"})
+
+	HTTPDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by method, route template and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	LLMRequests = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "llm_requests_total",
+		Help:      "LLM calls by provider, model and outcome (success or error).",
+	}, []string{"provider", "model", "outcome"})
+
+	// LLM calls take from seconds to minutes, well above the HTTP buckets.
+	LLMDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "llm_request_duration_seconds",
+		Help:      "LLM call latency by provider and model.",
+		Buckets:   []float64{0.5, 1, 2.5, 5, 10, 20, 30, 60, 120, 300},
+	}, []string{"provider", "model"})
+
+	LLMTokens = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "llm_tokens_total",
+		Help:      "Tokens reported by the LLM providers, by direction (input or output).",
+	}, []string{"provider", "model", "direction"})
+
+	Generations = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "generations_total",
+		Help:      "Finished generations by status and trigger (manual or schedule).",
+	}, []string{"status", "trigger"})
+
+	UploadSize = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "upload_size_bytes",
+		Help:      "Size of uploaded files by kind (report_file, asset or bundle).",
+		Buckets:   prometheus.ExponentialBuckets(1<<10, 4, 8),
+	}, []string{"kind"})
+
+	QueueDepth = factory.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "worker_queue_depth",
+		Help:      "Work waiting for or held by a background worker: pending webhook events, deliveries in flight and scheduled runs in flight.",
+	}, []string{"worker"})
+)
+
+// OtherModel labels the LLM calls to models that were not declared with
+// SetModels. The model of a call can come from the request, and labelling it
+// as is would let clients create series without bound.
+const OtherModel = "other"
+
+var (
+	modelsMu sync.RWMutex
+	models   = map[string]map[string]bool{}
+)
+
+// SetModels declares the models of provider that are labelled by name.
+func SetModels(provider string, names ...string) {
+	known := make(map[string]bool, len(names))
+	for _, name := range names {
+		if name != "" {
+			known[name] = true
+		}
+	}
+	modelsMu.Lock()
+	defer modelsMu.Unlock()
+	models[provider] = known
+}
+
+// ModelLabel returns the label of model in the LLM metrics and traces: its
+// name when it was declared for provider, OtherModel otherwise.
+func ModelLabel(provider, model string) string {
+	modelsMu.RLock()
+	defer modelsMu.RUnlock()
+	if models[provider][model] {
+		return model
+	}
+	return OtherModel
+}
+
+// ObserveLLMTokens records the usage reported by a provider.
+func ObserveLLMTokens(provider, model string, input, output int64) {
+	model = ModelLabel(provider, model)
+	LLMTokens.WithLabelValues(provider, model, "input").Add(float64(input))
+	LLMTokens.WithLabelValues(provider, model, "output").Add(float64(output))
+}
+
+// RegisterDB exports the connection pool statistics of db.
+func RegisterDB(db *sql.DB, name string) {
+	Registry.MustRegister(collectors.NewDBStatsCollector(db, name))
+}
+
+// Handler serves the registry in the Prometheus exposition format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
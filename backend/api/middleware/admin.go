@@ -0,0 +1,25 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"reportia/apperror"
+	"reportia/problem"
+	"strings"
+)
+
+var errAdminTokenRequired = apperror.New(apperror.Unauthorized, "admin_token_required", "admin token required")
+
+// AdminToken lets through requests carrying "Authorization: Bearer <token>".
+func AdminToken(token string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			given, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+				problem.Write(w, r, errAdminTokenRequired)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -1,20 +1,184 @@
 package middleware
 
-import "net/http"
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// CORSPolicy tells browsers which origins may call the API and how.
+type CORSPolicy struct {
+	// AllowedOrigins are exact origins, such as https://app.example.com,
+	// patterns with a wildcard subdomain, such as https://*.example.com, or
+	// "*" for any origin.
+	AllowedOrigins   []string
+	AllowedMethods   []string
+	AllowedHeaders   []string
+	ExposedHeaders   []string
+	AllowCredentials bool
+	// MaxAge is how long browsers may cache a preflight answer; 0 leaves it
+	// to the browser.
+	MaxAge time.Duration
+	// Routes overrides the methods and headers allowed on the routes with
+	// these path templates. Templates are full paths, with the prefixes of
+	// the subrouters a route is registered on, such as /api/v1.
+	Routes map[string]CORSRule
+}
+
+// CORSRule lists the methods and headers allowed on a route. Empty lists
+// keep those of the policy.
+type CORSRule struct {
+	Methods []string
+	Headers []string
+}
+
+// originPattern matches an origin on its scheme and host. A wildcard host
+// matches any subdomain of suffix, not suffix itself.
+type originPattern struct {
+	any      bool
+	exact    string
+	prefix   string
+	suffix   string
+	wildcard bool
+}
+
+// ValidOriginPattern reports whether pattern can be used in
+// CORSPolicy.AllowedOrigins.
+func ValidOriginPattern(pattern string) error {
+	_, err := parseOriginPattern(pattern)
+	return err
+}
+
+func parseOriginPattern(pattern string) (originPattern, error) {
+	if pattern == "*" {
+		return originPattern{any: true}, nil
+	}
+	pattern = strings.ToLower(strings.TrimSuffix(pattern, "/"))
+	u, err := url.Parse(strings.Replace(pattern, "*", "wildcard", 1))
+	if err != nil || u.Scheme == "" || u.Host == "" || u.Path != "" || u.RawQuery != "" || u.User != nil {
+		return originPattern{}, fmt.Errorf("%q is not an origin such as https://app.example.com", pattern)
+	}
+	scheme, host, _ := strings.Cut(pattern, "://")
+	switch {
+	case !strings.Contains(host, "*"):
+		return originPattern{exact: pattern}, nil
+	case strings.HasPrefix(host, "*.") && strings.Count(host, "*") == 1:
+		return originPattern{prefix: scheme + "://", suffix: host[1:], wildcard: true}, nil
+	default:
+		return originPattern{}, fmt.Errorf("%q may only have a wildcard as its first label, as in https://*.example.com", pattern)
+	}
+}
+
+func (p originPattern) match(origin string) bool {
+	switch {
+	case p.any:
+		return true
+	case !p.wildcard:
+		return origin == p.exact
+	}
+	subdomain, ok := strings.CutPrefix(origin, p.prefix)
+	if !ok {
+		return false
+	}
+	subdomain, ok = strings.CutSuffix(subdomain, p.suffix)
+	return ok && subdomain != "" && !strings.ContainsAny(subdomain, "/:@?#")
+}
+
+// CORS applies policy. Allowed origins are echoed with Vary: Origin so that
+// caches keep the answers of different origins apart. Preflight requests are
+// answered here with the methods and headers of the route router matches
+// for the requested method; other requests go on to next.
+func CORS(router *mux.Router, policy CORSPolicy) (func(http.Handler) http.Handler, error) {
+	patterns := make([]originPattern, 0, len(policy.AllowedOrigins))
+	for _, origin := range policy.AllowedOrigins {
+		pattern, err := parseOriginPattern(origin)
+		if err != nil {
+			return nil, err
+		}
+		patterns = append(patterns, pattern)
+	}
+	allowed := func(origin string) bool {
+		origin = strings.ToLower(origin)
+		for _, pattern := range patterns {
+			if pattern.match(origin) {
+				return true
+			}
+		}
+		return false
+	}
+	maxAge := ""
+	if policy.MaxAge > 0 {
+		maxAge = strconv.Itoa(int(policy.MaxAge.Seconds()))
+	}
 
-func CORS(allowedOrigin string) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			w.Header().Set("Access-Control-Allow-Origin", allowedOrigin)
-			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-			w.Header().Set("Access-Control-Allow-Credentials", "true")
+			h := w.Header()
+			h.Add("Vary", "Origin")
+			origin := r.Header.Get("Origin")
+			requestedMethod := r.Header.Get("Access-Control-Request-Method")
+			preflight := r.Method == http.MethodOptions && requestedMethod != ""
+			if preflight {
+				h.Add("Vary", "Access-Control-Request-Method")
+				h.Add("Vary", "Access-Control-Request-Headers")
+			}
 
-			if r.Method == http.MethodOptions {
-				w.WriteHeader(http.StatusNoContent)
+			if origin == "" || !allowed(origin) {
+				if preflight {
+					// Without the headers below the browser blocks the
+					// request that follows.
+					w.WriteHeader(http.StatusNoContent)
+					return
+				}
+				next.ServeHTTP(w, r)
 				return
 			}
-			next.ServeHTTP(w, r)
+
+			h.Set("Access-Control-Allow-Origin", origin)
+			if policy.AllowCredentials {
+				h.Set("Access-Control-Allow-Credentials", "true")
+			}
+			if !preflight {
+				if len(policy.ExposedHeaders) > 0 {
+					h.Set("Access-Control-Expose-Headers", strings.Join(policy.ExposedHeaders, ", "))
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			rule := corsRule(router, policy, r, requestedMethod)
+			h.Set("Access-Control-Allow-Methods", strings.Join(rule.Methods, ", "))
+			if len(rule.Headers) > 0 {
+				h.Set("Access-Control-Allow-Headers", strings.Join(rule.Headers, ", "))
+			}
+			if maxAge != "" {
+				h.Set("Access-Control-Max-Age", maxAge)
+			}
+			w.WriteHeader(http.StatusNoContent)
 		})
+	}, nil
+}
+
+// corsRule returns the methods and headers allowed on the route that serves
+// method on the path of the preflight request r.
+func corsRule(router *mux.Router, policy CORSPolicy, r *http.Request, method string) CORSRule {
+	rule := CORSRule{Methods: policy.AllowedMethods, Headers: policy.AllowedHeaders}
+	target := r.Clone(r.Context())
+	target.Method = method
+	override, ok := policy.Routes[routeTemplate(router, target)]
+	if !ok {
+		return rule
+	}
+	if len(override.Methods) > 0 {
+		rule.Methods = override.Methods
+	}
+	if len(override.Headers) > 0 {
+		rule.Headers = override.Headers
 	}
+	return rule
 }
@@ -0,0 +1,15 @@
+package middleware
+
+import "net/http"
+
+// Deprecated marks the responses of a route kept for older clients, pointing
+// them at the route that replaces it.
+func Deprecated(successor string) func(http.HandlerFunc) http.HandlerFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Deprecation", "true")
+			w.Header().Set("Link", "<"+successor+`>; rel="successor-version"`)
+			next(w, r)
+		}
+	}
+}
@@ -4,10 +4,22 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"log/slog"
+	"mime"
 	"net/http"
+	"reportia/logging"
+	"time"
 )
 
-const maxLogBodySize = 500
+// maxLogBodyCapture is how much of a body is buffered for logging. Larger
+// bodies are not logged, since they could not be redacted as a whole.
+const maxLogBodyCapture = 64 << 10
+
+type LoggingOptions struct {
+	Redaction logging.Redaction
+	// BodyLimit truncates the logged bodies, once redacted.
+	BodyLimit int
+}
 
 func truncateString(s string, max int) string {
 	if len(s) > max {
@@ -19,15 +31,20 @@ func truncateString(s string, max int) string {
 type responseRecorder struct {
 	http.ResponseWriter
 	statusCode int
-	body       *bytes.Buffer
+	size       int
+	// body captures the start of the response when bodies are logged.
+	body *bytes.Buffer
 }
 
-func newResponseRecorder(w http.ResponseWriter) *responseRecorder {
-	return &responseRecorder{
+func newResponseRecorder(w http.ResponseWriter, captureBody bool) *responseRecorder {
+	rec := &responseRecorder{
 		ResponseWriter: w,
 		statusCode:     http.StatusOK,
-		body:           &bytes.Buffer{},
 	}
+	if captureBody {
+		rec.body = &bytes.Buffer{}
+	}
+	return rec
 }
 
 func (rr *responseRecorder) WriteHeader(code int) {
@@ -36,33 +53,78 @@ func (rr *responseRecorder) WriteHeader(code int) {
 }
 
 func (rr *responseRecorder) Write(b []byte) (int, error) {
-	rr.body.Write(b)
-	return rr.ResponseWriter.Write(b)
+	if rr.body != nil && rr.body.Len() <= maxLogBodyCapture {
+		rr.body.Write(b)
+	}
+	n, err := rr.ResponseWriter.Write(b)
+	rr.size += n
+	return n, err
 }
 
-func Logging(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+// Logging writes one record per request with its redacted headers. At debug
+// level JSON bodies are logged too, redacted and truncated; other bodies,
+// such as uploaded files, never are.
+func Logging(opts LoggingOptions) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+			start := time.Now()
+			logBodies := slog.Default().Enabled(ctx, slog.LevelDebug)
 
-		var reqBody bytes.Buffer
-		if r.Body != nil {
-			bodyBytes, _ := io.ReadAll(r.Body)
-			reqBody.Write(bodyBytes)
+			var reqBody []byte
+			if logBodies && r.Body != nil && isJSON(r.Header.Get("Content-Type")) {
+				reqBody, _ = io.ReadAll(io.LimitReader(r.Body, maxLogBodyCapture+1))
+				r.Body = struct {
+					io.Reader
+					io.Closer
+				}{io.MultiReader(bytes.NewReader(reqBody), r.Body), r.Body}
+			}
 
-			r.Body = io.NopCloser(bytes.NewBuffer(bodyBytes))
-		}
+			rec := newResponseRecorder(w, logBodies && r.Method != http.MethodHead)
+			next.ServeHTTP(rec, r)
 
-		fmt.Println("=== Incoming Request ===")
-		fmt.Printf("Method: %s\n", r.Method)
-		fmt.Printf("URL: %s\n", r.URL.String())
-		fmt.Println("Headers:", r.Header)
-		fmt.Println("Body:", truncateString(reqBody.String(), maxLogBodySize))
+			attrs := []slog.Attr{
+				slog.String("method", r.Method),
+				slog.String("path", r.URL.Path),
+				slog.Int("status", rec.statusCode),
+				slog.Int("size", rec.size),
+				slog.Int64("duration_ms", time.Since(start).Milliseconds()),
+				slog.String("remote_addr", r.RemoteAddr),
+				slog.Any("headers", opts.Redaction.RedactHeaders(r.Header)),
+			}
+			if r.URL.RawQuery != "" {
+				attrs = append(attrs, slog.String("query", opts.Redaction.RedactQuery(r.URL.RawQuery)))
+			}
+			if reqBody != nil {
+				attrs = append(attrs, slog.String("request_body", opts.body(reqBody)))
+			}
+			if rec.body != nil && isJSON(rec.Header().Get("Content-Type")) {
+				attrs = append(attrs, slog.String("response_body", opts.body(rec.body.Bytes())))
+			}
 
-		rec := newResponseRecorder(w)
+			level := slog.LevelInfo
+			if rec.statusCode >= http.StatusInternalServerError {
+				level = slog.LevelError
+			}
+			slog.LogAttrs(ctx, level, "http request", attrs...)
+		})
+	}
+}
 
-		next.ServeHTTP(rec, r)
+func (opts LoggingOptions) body(body []byte) string {
+	if len(body) > maxLogBodyCapture {
+		return fmt.Sprintf("(%d+ bytes, not logged)", maxLogBodyCapture)
+	}
+	if len(body) == 0 {
+		return ""
+	}
+	return truncateString(opts.Redaction.RedactJSON(body), opts.BodyLimit)
+}
 
-		fmt.Println("=== Outgoing Response ===")
-		fmt.Printf("Status: %d\n", rec.statusCode)
-		fmt.Println("Body:", truncateString(rec.body.String(), maxLogBodySize))
-	})
+func isJSON(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" || mediaType == "application/problem+json"
 }
@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"net/http"
+	"reportia/metrics"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// unmatchedRoute labels requests no route matched, so that scanners cannot
+// blow up the cardinality of the metrics with arbitrary paths.
+const unmatchedRoute = "unmatched"
+
+// Metrics counts requests and their latency by the route template router
+// matched, such as /api/v1/reports/{id:[0-9]+}.
+func Metrics(router *mux.Router) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			rec := newResponseRecorder(w, false)
+			next.ServeHTTP(rec, r)
+
+			route := routeTemplate(router, r)
+			status := strconv.Itoa(rec.statusCode)
+			metrics.HTTPRequests.WithLabelValues(r.Method, route, status).Inc()
+			metrics.HTTPDuration.WithLabelValues(r.Method, route, status).Observe(time.Since(start).Seconds())
+		})
+	}
+}
+
+// routeTemplate returns the template of the route router matches for r, or
+// unmatchedRoute.
+func routeTemplate(router *mux.Router, r *http.Request) string {
+	var match mux.RouteMatch
+	if router.Match(r, &match) && match.MatchErr == nil && match.Route != nil {
+		if template, err := match.Route.GetPathTemplate(); err == nil {
+			return template
+		}
+	}
+	return unmatchedRoute
+}
@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"net/http"
+	"reportia/tracing"
+
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Tracing starts a server span per request, continuing the trace of the
+// caller when it sends a traceparent header. The span is named after the
+// route template router matched, like the metrics.
+func Tracing(router *mux.Router) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+			ctx, span := tracing.Start(ctx, r.Method,
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(
+					attribute.String("http.request.method", r.Method),
+					attribute.String("url.path", r.URL.Path),
+					attribute.String("user_agent.original", r.UserAgent()),
+				),
+			)
+			defer span.End()
+
+			rec := newResponseRecorder(w, false)
+			next.ServeHTTP(rec, r.WithContext(ctx))
+
+			route := routeTemplate(router, r)
+			span.SetName(r.Method + " " + route)
+			span.SetAttributes(
+				attribute.String("http.route", route),
+				attribute.Int("http.response.status_code", rec.statusCode),
+			)
+			if rec.statusCode >= http.StatusInternalServerError {
+				span.SetStatus(codes.Error, http.StatusText(rec.statusCode))
+			}
+		})
+	}
+}
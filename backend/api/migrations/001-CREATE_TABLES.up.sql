@@ -5,7 +5,7 @@ CREATE TABLE IF NOT EXISTS Report (
 	user_mail VARCHAR(60),
 	active BOOLEAN,
 	create_at DATE DEFAULT CURRENT_DATE,
-    update_at DATE DEFAULT CURRENT_DATE
+	update_at DATE DEFAULT CURRENT_DATE
 );
 
 -- Indexes for Report table
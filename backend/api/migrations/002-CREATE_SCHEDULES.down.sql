@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS Generation;
+DROP TABLE IF EXISTS Schedule;
@@ -0,0 +1,36 @@
+-- Create Schedule table
+CREATE TABLE IF NOT EXISTS Schedule (
+	id SERIAL PRIMARY KEY,
+	report_id INTEGER NOT NULL REFERENCES Report(id),
+	name VARCHAR(120) NOT NULL,
+	cron_expression VARCHAR(120) NOT NULL,
+	timezone VARCHAR(64) NOT NULL DEFAULT 'UTC',
+	llm VARCHAR(30) NOT NULL DEFAULT '',
+	model VARCHAR(60) NOT NULL DEFAULT '',
+	prompt TEXT NOT NULL DEFAULT '',
+	source_path TEXT NOT NULL,
+	user_mail VARCHAR(60) NOT NULL,
+	active BOOLEAN DEFAULT true,
+	next_run_at TIMESTAMPTZ,
+	last_run_at TIMESTAMPTZ,
+	create_at TIMESTAMPTZ DEFAULT NOW(),
+	update_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- Create Generation table
+CREATE TABLE IF NOT EXISTS Generation (
+	id SERIAL PRIMARY KEY,
+	report_id INTEGER NOT NULL REFERENCES Report(id),
+	schedule_id INTEGER REFERENCES Schedule(id),
+	llm VARCHAR(30) NOT NULL DEFAULT '',
+	model VARCHAR(60) NOT NULL DEFAULT '',
+	status VARCHAR(20) NOT NULL,
+	output TEXT,
+	error TEXT,
+	started_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	finished_at TIMESTAMPTZ
+);
+
+-- Indexes for Schedule and Generation tables
+CREATE INDEX IF NOT EXISTS idx_schedule_due ON Schedule(active, next_run_at);
+CREATE INDEX IF NOT EXISTS idx_generation_schedule_id ON Generation(schedule_id, started_at DESC);
@@ -0,0 +1,4 @@
+-- source_path stays nullable: schedules created against a data source have none
+ALTER TABLE Schedule DROP COLUMN IF EXISTS data_source_id;
+ALTER TABLE Report DROP COLUMN IF EXISTS data_source_id;
+DROP TABLE IF EXISTS DataSource;
@@ -0,0 +1,18 @@
+-- Create DataSource table
+CREATE TABLE IF NOT EXISTS DataSource (
+	id SERIAL PRIMARY KEY,
+	name VARCHAR(120) NOT NULL,
+	kind VARCHAR(20) NOT NULL,
+	config JSONB NOT NULL,
+	user_mail VARCHAR(60) NOT NULL,
+	active BOOLEAN DEFAULT true,
+	create_at TIMESTAMPTZ DEFAULT NOW(),
+	update_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- Reports and schedules may pull their input from a data source
+ALTER TABLE Report ADD COLUMN IF NOT EXISTS data_source_id INTEGER REFERENCES DataSource(id);
+ALTER TABLE Schedule ADD COLUMN IF NOT EXISTS data_source_id INTEGER REFERENCES DataSource(id);
+ALTER TABLE Schedule ALTER COLUMN source_path DROP NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_datasource_user_mail ON DataSource(user_mail);
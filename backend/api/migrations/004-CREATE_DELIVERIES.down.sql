@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS Delivery;
+DROP TABLE IF EXISTS DeliveryRule;
@@ -0,0 +1,30 @@
+-- Create DeliveryRule table: who receives a generated report by email
+CREATE TABLE IF NOT EXISTS DeliveryRule (
+	id SERIAL PRIMARY KEY,
+	report_id INTEGER REFERENCES Report(id),
+	schedule_id INTEGER REFERENCES Schedule(id),
+	recipients TEXT NOT NULL,
+	subject VARCHAR(200) NOT NULL DEFAULT '',
+	attach_pdf BOOLEAN NOT NULL DEFAULT false,
+	active BOOLEAN DEFAULT true,
+	create_at TIMESTAMPTZ DEFAULT NOW(),
+	update_at TIMESTAMPTZ DEFAULT NOW(),
+	CHECK ((report_id IS NULL) <> (schedule_id IS NULL))
+);
+
+-- Create Delivery table: one row per attempt to email a generation
+CREATE TABLE IF NOT EXISTS Delivery (
+	id SERIAL PRIMARY KEY,
+	generation_id INTEGER NOT NULL REFERENCES Generation(id),
+	rule_id INTEGER NOT NULL REFERENCES DeliveryRule(id),
+	recipients TEXT NOT NULL,
+	status VARCHAR(20) NOT NULL,
+	attempts INTEGER NOT NULL DEFAULT 0,
+	error TEXT,
+	create_at TIMESTAMPTZ DEFAULT NOW(),
+	sent_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_deliveryrule_report_id ON DeliveryRule(report_id);
+CREATE INDEX IF NOT EXISTS idx_deliveryrule_schedule_id ON DeliveryRule(schedule_id);
+CREATE INDEX IF NOT EXISTS idx_delivery_generation_id ON Delivery(generation_id);
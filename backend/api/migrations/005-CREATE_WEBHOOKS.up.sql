@@ -0,0 +1,39 @@
+-- Create WebhookSubscription table
+CREATE TABLE IF NOT EXISTS WebhookSubscription (
+	id SERIAL PRIMARY KEY,
+	url TEXT NOT NULL,
+	secret VARCHAR(128) NOT NULL,
+	event_types TEXT NOT NULL,
+	user_mail VARCHAR(60) NOT NULL,
+	active BOOLEAN DEFAULT true,
+	create_at TIMESTAMPTZ DEFAULT NOW(),
+	update_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- Create WebhookEvent table: the outbox, one row per event and subscription
+CREATE TABLE IF NOT EXISTS WebhookEvent (
+	id SERIAL PRIMARY KEY,
+	subscription_id INTEGER NOT NULL REFERENCES WebhookSubscription(id),
+	event_type VARCHAR(60) NOT NULL,
+	payload JSONB NOT NULL,
+	status VARCHAR(20) NOT NULL DEFAULT 'pending',
+	attempts INTEGER NOT NULL DEFAULT 0,
+	next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	last_error TEXT,
+	create_at TIMESTAMPTZ DEFAULT NOW(),
+	delivered_at TIMESTAMPTZ
+);
+
+-- Create WebhookAttempt table: the delivery log
+CREATE TABLE IF NOT EXISTS WebhookAttempt (
+	id SERIAL PRIMARY KEY,
+	event_id INTEGER NOT NULL REFERENCES WebhookEvent(id),
+	status_code INTEGER,
+	error TEXT,
+	duration_ms INTEGER NOT NULL,
+	create_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhookevent_due ON WebhookEvent(status, next_attempt_at);
+CREATE INDEX IF NOT EXISTS idx_webhookevent_subscription_id ON WebhookEvent(subscription_id, create_at DESC);
+CREATE INDEX IF NOT EXISTS idx_webhookattempt_event_id ON WebhookAttempt(event_id);
@@ -0,0 +1,15 @@
+-- Create ShareLink table: public, expiring links to a stored generation
+CREATE TABLE IF NOT EXISTS ShareLink (
+	id SERIAL PRIMARY KEY,
+	generation_id INTEGER NOT NULL REFERENCES Generation(id),
+	token_hash VARCHAR(64) NOT NULL,
+	password_hash TEXT,
+	expires_at TIMESTAMPTZ NOT NULL,
+	max_views INTEGER,
+	view_count INTEGER NOT NULL DEFAULT 0,
+	user_mail VARCHAR(60) NOT NULL,
+	revoked_at TIMESTAMPTZ,
+	create_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_sharelink_generation_id ON ShareLink(generation_id);
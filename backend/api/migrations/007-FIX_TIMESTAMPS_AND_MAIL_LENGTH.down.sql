@@ -0,0 +1,12 @@
+-- Fails instead of truncating when an address longer than 60 characters exists
+ALTER TABLE ShareLink ALTER COLUMN user_mail TYPE VARCHAR(60);
+ALTER TABLE WebhookSubscription ALTER COLUMN user_mail TYPE VARCHAR(60);
+ALTER TABLE DataSource ALTER COLUMN user_mail TYPE VARCHAR(60);
+ALTER TABLE Schedule ALTER COLUMN user_mail TYPE VARCHAR(60);
+ALTER TABLE Report ALTER COLUMN user_mail TYPE VARCHAR(60);
+
+ALTER TABLE Report
+	ALTER COLUMN create_at TYPE DATE USING create_at::date,
+	ALTER COLUMN create_at SET DEFAULT CURRENT_DATE,
+	ALTER COLUMN update_at TYPE DATE USING update_at::date,
+	ALTER COLUMN update_at SET DEFAULT CURRENT_DATE;
@@ -0,0 +1,13 @@
+-- Report kept only the day of creation and update
+ALTER TABLE Report
+	ALTER COLUMN create_at TYPE TIMESTAMPTZ USING create_at::timestamptz,
+	ALTER COLUMN create_at SET DEFAULT NOW(),
+	ALTER COLUMN update_at TYPE TIMESTAMPTZ USING update_at::timestamptz,
+	ALTER COLUMN update_at SET DEFAULT NOW();
+
+-- Email addresses can be up to 254 characters long (RFC 5321)
+ALTER TABLE Report ALTER COLUMN user_mail TYPE VARCHAR(254);
+ALTER TABLE Schedule ALTER COLUMN user_mail TYPE VARCHAR(254);
+ALTER TABLE DataSource ALTER COLUMN user_mail TYPE VARCHAR(254);
+ALTER TABLE WebhookSubscription ALTER COLUMN user_mail TYPE VARCHAR(254);
+ALTER TABLE ShareLink ALTER COLUMN user_mail TYPE VARCHAR(254);
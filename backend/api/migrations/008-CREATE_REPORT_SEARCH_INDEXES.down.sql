@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_report_update_at;
+DROP INDEX IF EXISTS idx_report_create_at;
+DROP INDEX IF EXISTS idx_report_search;
@@ -0,0 +1,6 @@
+-- Full-text search on templates; the expression must match the one used by the report listing
+CREATE INDEX IF NOT EXISTS idx_report_search ON Report USING GIN (to_tsvector('simple', COALESCE(template, '')));
+
+-- Keyset pagination on the default sort columns
+CREATE INDEX IF NOT EXISTS idx_report_create_at ON Report(create_at, id);
+CREATE INDEX IF NOT EXISTS idx_report_update_at ON Report(update_at, id);
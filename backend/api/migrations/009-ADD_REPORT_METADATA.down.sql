@@ -0,0 +1,13 @@
+DROP INDEX IF EXISTS idx_report_search;
+CREATE INDEX IF NOT EXISTS idx_report_search ON Report USING GIN (to_tsvector('simple', COALESCE(template, '')));
+
+DROP INDEX IF EXISTS idx_report_category;
+
+ALTER TABLE Report DROP COLUMN IF EXISTS thumbnail;
+ALTER TABLE Report DROP COLUMN IF EXISTS default_prompt;
+ALTER TABLE Report DROP COLUMN IF EXISTS default_model;
+ALTER TABLE Report DROP COLUMN IF EXISTS default_llm;
+ALTER TABLE Report DROP COLUMN IF EXISTS category;
+ALTER TABLE Report DROP COLUMN IF EXISTS tags;
+ALTER TABLE Report DROP COLUMN IF EXISTS description;
+ALTER TABLE Report DROP COLUMN IF EXISTS name;
@@ -0,0 +1,15 @@
+-- Descriptive metadata and generation defaults of a template
+ALTER TABLE Report ADD COLUMN IF NOT EXISTS name VARCHAR(120) NOT NULL DEFAULT '';
+ALTER TABLE Report ADD COLUMN IF NOT EXISTS description TEXT NOT NULL DEFAULT '';
+ALTER TABLE Report ADD COLUMN IF NOT EXISTS tags TEXT NOT NULL DEFAULT '';
+ALTER TABLE Report ADD COLUMN IF NOT EXISTS category VARCHAR(60) NOT NULL DEFAULT '';
+ALTER TABLE Report ADD COLUMN IF NOT EXISTS default_llm VARCHAR(30) NOT NULL DEFAULT '';
+ALTER TABLE Report ADD COLUMN IF NOT EXISTS default_model VARCHAR(60) NOT NULL DEFAULT '';
+ALTER TABLE Report ADD COLUMN IF NOT EXISTS default_prompt TEXT NOT NULL DEFAULT '';
+ALTER TABLE Report ADD COLUMN IF NOT EXISTS thumbnail TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_report_category ON Report(category);
+
+-- Search names and descriptions as well as template content
+DROP INDEX IF EXISTS idx_report_search;
+CREATE INDEX IF NOT EXISTS idx_report_search ON Report USING GIN (to_tsvector('simple', name || ' ' || description || ' ' || COALESCE(template, '')));
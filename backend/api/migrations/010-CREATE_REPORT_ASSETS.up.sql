@@ -0,0 +1,14 @@
+-- Create ReportAsset table: images and fonts a template references with {{ASSET:name}}
+CREATE TABLE IF NOT EXISTS ReportAsset (
+	id SERIAL PRIMARY KEY,
+	report_id INTEGER NOT NULL REFERENCES Report(id) ON DELETE CASCADE,
+	name VARCHAR(60) NOT NULL,
+	file_name VARCHAR(255) NOT NULL,
+	content_type VARCHAR(100) NOT NULL,
+	size INTEGER NOT NULL,
+	checksum VARCHAR(64) NOT NULL,
+	storage_key VARCHAR(300) NOT NULL,
+	create_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	update_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	UNIQUE (report_id, name)
+);
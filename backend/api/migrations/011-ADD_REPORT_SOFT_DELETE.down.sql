@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_report_deleted_at;
+
+ALTER TABLE Report DROP COLUMN IF EXISTS deleted_at;
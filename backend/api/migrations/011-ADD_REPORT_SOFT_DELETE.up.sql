@@ -0,0 +1,4 @@
+-- Deleted reports stay in the trash until restored or purged by the retention job
+ALTER TABLE Report ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_report_deleted_at ON Report(deleted_at);
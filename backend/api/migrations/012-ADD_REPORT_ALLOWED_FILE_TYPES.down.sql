@@ -0,0 +1 @@
+ALTER TABLE Report DROP COLUMN IF EXISTS allowed_file_types;
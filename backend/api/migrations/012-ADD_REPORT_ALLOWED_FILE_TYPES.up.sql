@@ -0,0 +1,3 @@
+-- Extensions and content types accepted by the generations of a template,
+-- comma separated; empty falls back to the server-wide list
+ALTER TABLE Report ADD COLUMN IF NOT EXISTS allowed_file_types TEXT NOT NULL DEFAULT '';
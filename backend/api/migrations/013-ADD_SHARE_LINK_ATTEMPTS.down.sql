@@ -0,0 +1,2 @@
+ALTER TABLE ShareLink DROP COLUMN IF EXISTS locked_until;
+ALTER TABLE ShareLink DROP COLUMN IF EXISTS failed_attempts;
@@ -0,0 +1,4 @@
+-- Wrong passwords entered on a share link, which lock it for a while once
+-- there are too many
+ALTER TABLE ShareLink ADD COLUMN IF NOT EXISTS failed_attempts INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE ShareLink ADD COLUMN IF NOT EXISTS locked_until TIMESTAMPTZ;
@@ -0,0 +1,29 @@
+package model
+
+import "time"
+
+// MaxAssetSize is the largest asset accepted, in bytes.
+const MaxAssetSize = 5 * 1024 * 1024
+
+// Asset represents the ReportAsset table in the database: an image or font
+// a template references with the {{ASSET:name}} token. The content lives in
+// the asset storage under StorageKey; URL is filled in when listing.
+type Asset struct {
+	ID          int       `json:"id"`
+	ReportID    int       `json:"report_id"`
+	Name        string    `json:"name"`
+	FileName    string    `json:"file_name"`
+	ContentType string    `json:"content_type"`
+	Size        int       `json:"size"`
+	Checksum    string    `json:"checksum"`
+	StorageKey  string    `json:"-"`
+	URL         string    `json:"url,omitempty"`
+	CreateAt    time.Time `json:"create_at"`
+	UpdateAt    time.Time `json:"update_at"`
+}
+
+// TemplateAsset lists an asset in the metadata of an exported template.
+type TemplateAsset struct {
+	Name     string `json:"name"`
+	FileName string `json:"file_name"`
+}
@@ -6,6 +6,7 @@ func GetPromptToGenerateAnalysisFromFile(template string, additionalInformation
 		Your html needs to follow the next structure of template:
 		` + template + `
 		Remember, you don't need to include any additional information outside of the provided template. Just the <html> tags directly inside the destination block of dynamic body.
+		Keep every {{ASSET:...}} token exactly as written, they are replaced with the URLs of the template's images and fonts afterwards.
 		You can add graphs using javascript libraries like Chart.js or D3.js, but ensure they are properly formatted within the HTML structure. Also, you can add script tag to do any necessary data manipulation or visualization.
 		**Important**:
 		- All text must be written in pt-br portuguese.
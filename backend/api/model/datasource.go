@@ -0,0 +1,18 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// DataSource represents the DataSource table in the database.
+type DataSource struct {
+	ID       int             `json:"id"`
+	Name     string          `json:"name"`
+	Kind     string          `json:"kind"`
+	Config   json.RawMessage `json:"config" swaggertype:"object"`
+	UserMail string          `json:"user_mail"`
+	Active   bool            `json:"active"`
+	CreateAt time.Time       `json:"create_at"`
+	UpdateAt time.Time       `json:"update_at"`
+}
@@ -0,0 +1,38 @@
+package model
+
+import "time"
+
+const (
+	DeliveryStatusPending = "pending"
+	DeliveryStatusSent    = "sent"
+	DeliveryStatusFailed  = "failed"
+	DeliveryStatusBounced = "bounced"
+	DeliveryStatusDryRun  = "dry_run"
+)
+
+// DeliveryRule represents the DeliveryRule table in the database. Exactly one
+// of ReportID and ScheduleID is set.
+type DeliveryRule struct {
+	ID         int       `json:"id"`
+	ReportID   *int      `json:"report_id"`
+	ScheduleID *int      `json:"schedule_id"`
+	Recipients []string  `json:"recipients"`
+	Subject    string    `json:"subject"`
+	AttachPDF  bool      `json:"attach_pdf"`
+	Active     bool      `json:"active"`
+	CreateAt   time.Time `json:"create_at"`
+	UpdateAt   time.Time `json:"update_at"`
+}
+
+// Delivery represents the Delivery table in the database.
+type Delivery struct {
+	ID           int        `json:"id"`
+	GenerationID int        `json:"generation_id"`
+	RuleID       int        `json:"rule_id"`
+	Recipients   []string   `json:"recipients"`
+	Status       string     `json:"status"`
+	Attempts     int        `json:"attempts"`
+	Error        string     `json:"error"`
+	CreateAt     time.Time  `json:"create_at"`
+	SentAt       *time.Time `json:"sent_at"`
+}
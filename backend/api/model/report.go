@@ -2,20 +2,117 @@ package model
 
 import "time"
 
-// Report represents the Report table in the database.
+// Report represents the Report table in the database. DefaultLLM,
+// DefaultModel and DefaultPrompt are used by generations that do not choose
+// their own; Thumbnail references a preview image of the template.
+// AllowedFileTypes lists the extensions and content types of the files its
+// generations accept, the server's list applying when it is empty. DeletedAt
+// is set while the report is in the trash.
 type Report struct {
-	ID       int       `json:"id"`
-	Template string    `json:"template"`
-	UserMail string    `json:"user_mail"`
-	Active   bool      `json:"active"`
-	CreateAt time.Time `json:"create_at"`
-	UpdateAt time.Time `json:"update_at"`
+	ID            int      `json:"id"`
+	Name          string   `json:"name"`
+	Description   string   `json:"description"`
+	Tags          []string `json:"tags"`
+	Category      string   `json:"category"`
+	Template      string   `json:"template"`
+	DefaultLLM    string   `json:"default_llm"`
+	DefaultModel  string   `json:"default_model"`
+	DefaultPrompt string   `json:"default_prompt"`
+	Thumbnail     string   `json:"thumbnail"`
+	// AllowedFileTypes holds entries such as .csv, text/csv or text/*.
+	AllowedFileTypes []string   `json:"allowed_file_types"`
+	UserMail         string     `json:"user_mail"`
+	Active           bool       `json:"active"`
+	DataSourceID     *int       `json:"data_source_id"`
+	CreateAt         time.Time  `json:"create_at"`
+	UpdateAt         time.Time  `json:"update_at"`
+	DeletedAt        *time.Time `json:"deleted_at,omitempty"`
 }
 
+// ReportUpdate carries the new values of a report. Nil metadata fields keep
+// their current value; Template and DataSourceID are always replaced.
+type ReportUpdate struct {
+	ID               int
+	Template         string
+	DataSourceID     *int
+	Name             *string
+	Description      *string
+	Tags             *[]string
+	Category         *string
+	DefaultLLM       *string
+	DefaultModel     *string
+	DefaultPrompt    *string
+	Thumbnail        *string
+	AllowedFileTypes *[]string
+}
+
+// ReportPatch carries the fields of a partial update. Nil fields are left
+// unchanged; Active turns the report on or off.
+type ReportPatch struct {
+	Template         *string
+	DataSourceID     *int
+	Name             *string
+	Description      *string
+	Tags             *[]string
+	Category         *string
+	DefaultLLM       *string
+	DefaultModel     *string
+	DefaultPrompt    *string
+	Thumbnail        *string
+	AllowedFileTypes *[]string
+	Active           *bool
+}
+
+// PaginatedReports is one page of a report listing. NextCursor is set when
+// more reports follow and can be passed back as ReportQuery.Cursor; Page is 0
+// when the page was requested by cursor.
 type PaginatedReports struct {
 	Reports    []Report `json:"reports"`
 	TotalCount int      `json:"total_count"`
 	Page       int      `json:"page"`
 	PageSize   int      `json:"page_size"`
 	TotalPages int      `json:"total_pages"`
+	NextCursor string   `json:"next_cursor,omitempty"`
+}
+
+// ReportQuery filters, sorts and paginates report listings. Nil and zero
+// fields do not filter; reports must carry every tag in Tags and every word
+// of Search, and date bounds are inclusive. Search matches whole words on
+// Postgres and parts of words on SQLite and in memory. Deleted lists the reports in the trash instead of the live
+// ones. When Cursor is set it takes precedence over Page.
+type ReportQuery struct {
+	ID          int
+	Name        *string
+	Search      string
+	Tags        []string
+	Category    *string
+	UserMail    *string
+	Active      *bool
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+	UpdatedFrom *time.Time
+	UpdatedTo   *time.Time
+	SortBy      string
+	SortDesc    bool
+	Page        int
+	PageSize    int
+	Cursor      string
+	Deleted     bool
+}
+
+// TemplateMetadata describes a template outside the database: in export
+// bundles and in the starter gallery. UpdateAt is the version of the template
+// that was exported and Assets the files stored next to it.
+type TemplateMetadata struct {
+	Name             string          `json:"name"`
+	Description      string          `json:"description"`
+	Tags             []string        `json:"tags"`
+	Category         string          `json:"category"`
+	DefaultLLM       string          `json:"default_llm,omitempty"`
+	DefaultModel     string          `json:"default_model,omitempty"`
+	DefaultPrompt    string          `json:"default_prompt,omitempty"`
+	Thumbnail        string          `json:"thumbnail,omitempty"`
+	AllowedFileTypes []string        `json:"allowed_file_types,omitempty"`
+	UpdateAt         *time.Time      `json:"update_at,omitempty"`
+	Assets           []TemplateAsset `json:"assets,omitempty"`
 }
@@ -0,0 +1,17 @@
+package request_report
+
+import "encoding/json"
+
+type CreateDataSourceReq struct {
+	Name     string          `json:"name" validate:"required,min=1,max=120"`
+	Kind     string          `json:"kind" validate:"required,oneof=sql http directory s3"`
+	Config   json.RawMessage `json:"config" validate:"required" swaggertype:"object"`
+	UserMail string          `json:"user_mail" validate:"required,email"`
+}
+
+type UpdateDataSourceReq struct {
+	ID     int             `json:"id" validate:"required,gt=0"`
+	Name   string          `json:"name" validate:"required,min=1,max=120"`
+	Kind   string          `json:"kind" validate:"required,oneof=sql http directory s3"`
+	Config json.RawMessage `json:"config" validate:"required" swaggertype:"object"`
+}
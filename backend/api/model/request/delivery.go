@@ -0,0 +1,16 @@
+package request_report
+
+type CreateDeliveryRuleReq struct {
+	ReportID   *int     `json:"report_id" validate:"omitempty,gt=0"`
+	ScheduleID *int     `json:"schedule_id" validate:"omitempty,gt=0"`
+	Recipients []string `json:"recipients" validate:"required,min=1,dive,email"`
+	Subject    string   `json:"subject" validate:"max=200"`
+	AttachPDF  bool     `json:"attach_pdf"`
+}
+
+type UpdateDeliveryRuleReq struct {
+	ID         int      `json:"id" validate:"required,gt=0"`
+	Recipients []string `json:"recipients" validate:"required,min=1,dive,email"`
+	Subject    string   `json:"subject" validate:"max=200"`
+	AttachPDF  bool     `json:"attach_pdf"`
+}
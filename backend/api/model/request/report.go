@@ -5,8 +5,43 @@ import (
 )
 
 type CreateReportReq struct {
-	Template string `json:"template" validate:"required,min=1"`
+	Name          string   `json:"name" validate:"max=120"`
+	Description   string   `json:"description" validate:"max=2000"`
+	Tags          []string `json:"tags" validate:"max=20,dive,min=1,max=40,excludesall=0x2C"`
+	Category      string   `json:"category" validate:"max=60"`
+	Template      string   `json:"template" validate:"required,min=1"`
+	DefaultLLM    string   `json:"default_llm" validate:"max=30"`
+	DefaultModel  string   `json:"default_model" validate:"max=60"`
+	DefaultPrompt string   `json:"default_prompt"`
+	Thumbnail     string   `json:"thumbnail" validate:"max=500"`
+	// AllowedFileTypes holds extensions such as .csv and content types such
+	// as text/csv or text/*.
+	AllowedFileTypes []string `json:"allowed_file_types" validate:"max=30,dive,min=2,max=100,excludesall=0x2C"`
+	UserMail         string   `json:"user_mail" validate:"required,email"`
+	DataSourceID     *int     `json:"data_source_id" validate:"omitempty,gt=0"`
+}
+
+type ValidateTemplateReq struct {
+	Template string `json:"template" validate:"required"`
+}
+
+type CloneStarterReq struct {
+	Slug     string `json:"slug" validate:"required"`
 	UserMail string `json:"user_mail" validate:"required,email"`
+	Name     string `json:"name" validate:"max=120"`
+}
+
+type DeleteAssetReq struct {
+	ID int `json:"id" validate:"required,gt=0"`
+}
+
+// PreviewReportReq renders a template preview. Without a generation the body
+// slot is filled with sample content; Width and Height size the PNG.
+type PreviewReportReq struct {
+	GenerationID *int   `json:"generation_id" validate:"omitempty,gt=0"`
+	Format       string `json:"format" validate:"omitempty,oneof=html png"`
+	Width        int    `json:"width" validate:"omitempty,min=200,max=3840"`
+	Height       int    `json:"height" validate:"omitempty,min=200,max=3840"`
 }
 
 type TurnOnOffReq struct {
@@ -14,9 +49,43 @@ type TurnOnOffReq struct {
 	Active bool `json:"active"`
 }
 
+// UpdateReportReq is the body of the deprecated PUT /reports, which takes the
+// report id in the body.
 type UpdateReportReq struct {
-	ID       int    `json:"id" validate:"required,gt=0"`
-	Template string `json:"template" validate:"required,min=1"`
+	ID int `json:"id" validate:"required,gt=0"`
+	ReplaceReportReq
+}
+
+// ReplaceReportReq replaces the template and data source of a report. Omitted
+// metadata fields are left unchanged.
+type ReplaceReportReq struct {
+	Template         string    `json:"template" validate:"required,min=1"`
+	DataSourceID     *int      `json:"data_source_id" validate:"omitempty,gt=0"`
+	Name             *string   `json:"name" validate:"omitempty,max=120"`
+	Description      *string   `json:"description" validate:"omitempty,max=2000"`
+	Tags             *[]string `json:"tags" validate:"omitempty,max=20,dive,min=1,max=40,excludesall=0x2C"`
+	Category         *string   `json:"category" validate:"omitempty,max=60"`
+	DefaultLLM       *string   `json:"default_llm" validate:"omitempty,max=30"`
+	DefaultModel     *string   `json:"default_model" validate:"omitempty,max=60"`
+	DefaultPrompt    *string   `json:"default_prompt"`
+	Thumbnail        *string   `json:"thumbnail" validate:"omitempty,max=500"`
+	AllowedFileTypes *[]string `json:"allowed_file_types" validate:"omitempty,max=30,dive,min=2,max=100,excludesall=0x2C"`
+}
+
+// PatchReportReq changes only the fields it carries.
+type PatchReportReq struct {
+	Template         *string   `json:"template" validate:"omitempty,min=1"`
+	DataSourceID     *int      `json:"data_source_id" validate:"omitempty,gt=0"`
+	Name             *string   `json:"name" validate:"omitempty,max=120"`
+	Description      *string   `json:"description" validate:"omitempty,max=2000"`
+	Tags             *[]string `json:"tags" validate:"omitempty,max=20,dive,min=1,max=40,excludesall=0x2C"`
+	Category         *string   `json:"category" validate:"omitempty,max=60"`
+	DefaultLLM       *string   `json:"default_llm" validate:"omitempty,max=30"`
+	DefaultModel     *string   `json:"default_model" validate:"omitempty,max=60"`
+	DefaultPrompt    *string   `json:"default_prompt"`
+	Thumbnail        *string   `json:"thumbnail" validate:"omitempty,max=500"`
+	AllowedFileTypes *[]string `json:"allowed_file_types" validate:"omitempty,max=30,dive,min=2,max=100,excludesall=0x2C"`
+	Active           *bool     `json:"active"`
 }
 
 type ListReportsReq struct {
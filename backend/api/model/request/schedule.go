@@ -0,0 +1,26 @@
+package request_report
+
+type CreateScheduleReq struct {
+	ReportID       int    `json:"report_id" validate:"required,gt=0"`
+	Name           string `json:"name" validate:"required,min=1,max=120"`
+	CronExpression string `json:"cron_expression" validate:"required"`
+	Timezone       string `json:"timezone"`
+	LLM            string `json:"llm"`
+	Model          string `json:"model"`
+	Prompt         string `json:"prompt"`
+	SourcePath     string `json:"source_path"`
+	DataSourceID   *int   `json:"data_source_id" validate:"omitempty,gt=0"`
+	UserMail       string `json:"user_mail" validate:"required,email"`
+}
+
+type UpdateScheduleReq struct {
+	ID             int    `json:"id" validate:"required,gt=0"`
+	Name           string `json:"name" validate:"required,min=1,max=120"`
+	CronExpression string `json:"cron_expression" validate:"required"`
+	Timezone       string `json:"timezone"`
+	LLM            string `json:"llm"`
+	Model          string `json:"model"`
+	Prompt         string `json:"prompt"`
+	SourcePath     string `json:"source_path"`
+	DataSourceID   *int   `json:"data_source_id" validate:"omitempty,gt=0"`
+}
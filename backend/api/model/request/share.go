@@ -0,0 +1,13 @@
+package request_report
+
+type CreateShareLinkReq struct {
+	GenerationID   int    `json:"generation_id" validate:"required,gt=0"`
+	ExpiresInHours int    `json:"expires_in_hours" validate:"omitempty,gt=0,lte=8760"`
+	Password       string `json:"password" validate:"omitempty,min=4,max=72"`
+	MaxViews       *int   `json:"max_views" validate:"omitempty,gt=0"`
+	UserMail       string `json:"user_mail" validate:"required,email"`
+}
+
+type RevokeShareLinkReq struct {
+	ID int `json:"id" validate:"required,gt=0"`
+}
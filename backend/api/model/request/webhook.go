@@ -0,0 +1,14 @@
+package request_report
+
+type CreateWebhookReq struct {
+	URL        string   `json:"url" validate:"required,url"`
+	Secret     string   `json:"secret" validate:"omitempty,min=16,max=128"`
+	EventTypes []string `json:"event_types" validate:"required,min=1,dive,oneof=generation.succeeded generation.failed template.updated"`
+	UserMail   string   `json:"user_mail" validate:"required,email"`
+}
+
+type UpdateWebhookReq struct {
+	ID         int      `json:"id" validate:"required,gt=0"`
+	URL        string   `json:"url" validate:"required,url"`
+	EventTypes []string `json:"event_types" validate:"required,min=1,dive,oneof=generation.succeeded generation.failed template.updated"`
+}
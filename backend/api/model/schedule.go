@@ -0,0 +1,43 @@
+package model
+
+import "time"
+
+const (
+	GenerationStatusRunning   = "running"
+	GenerationStatusSucceeded = "succeeded"
+	GenerationStatusFailed    = "failed"
+)
+
+// Schedule represents the Schedule table in the database.
+type Schedule struct {
+	ID             int        `json:"id"`
+	ReportID       int        `json:"report_id"`
+	Name           string     `json:"name"`
+	CronExpression string     `json:"cron_expression"`
+	Timezone       string     `json:"timezone"`
+	LLM            string     `json:"llm"`
+	Model          string     `json:"model"`
+	Prompt         string     `json:"prompt"`
+	SourcePath     string     `json:"source_path"`
+	DataSourceID   *int       `json:"data_source_id"`
+	UserMail       string     `json:"user_mail"`
+	Active         bool       `json:"active"`
+	NextRunAt      *time.Time `json:"next_run_at"`
+	LastRunAt      *time.Time `json:"last_run_at"`
+	CreateAt       time.Time  `json:"create_at"`
+	UpdateAt       time.Time  `json:"update_at"`
+}
+
+// Generation represents the Generation table in the database.
+type Generation struct {
+	ID         int        `json:"id"`
+	ReportID   int        `json:"report_id"`
+	ScheduleID *int       `json:"schedule_id"`
+	LLM        string     `json:"llm"`
+	Model      string     `json:"model"`
+	Status     string     `json:"status"`
+	Output     string     `json:"output"`
+	Error      string     `json:"error"`
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at"`
+}
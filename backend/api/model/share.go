@@ -0,0 +1,21 @@
+package model
+
+import "time"
+
+// ShareLink represents the ShareLink table in the database. Token and URL are
+// only filled in when the link is minted; afterwards only a hash is kept.
+type ShareLink struct {
+	ID                int        `json:"id"`
+	GenerationID      int        `json:"generation_id"`
+	Token             string     `json:"token,omitempty"`
+	URL               string     `json:"url,omitempty"`
+	TokenHash         string     `json:"-"`
+	PasswordHash      string     `json:"-"`
+	PasswordProtected bool       `json:"password_protected"`
+	ExpiresAt         time.Time  `json:"expires_at"`
+	MaxViews          *int       `json:"max_views"`
+	ViewCount         int        `json:"view_count"`
+	UserMail          string     `json:"user_mail"`
+	RevokedAt         *time.Time `json:"revoked_at"`
+	CreateAt          time.Time  `json:"create_at"`
+}
@@ -0,0 +1,66 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	EventGenerationSucceeded = "generation.succeeded"
+	EventGenerationFailed    = "generation.failed"
+	EventTemplateUpdated     = "template.updated"
+)
+
+const (
+	WebhookEventPending   = "pending"
+	WebhookEventDelivered = "delivered"
+	WebhookEventFailed    = "failed"
+	// WebhookEventSkipped events were pending when their subscription was
+	// turned off, and are never sent.
+	WebhookEventSkipped = "skipped"
+)
+
+// WebhookSubscription represents the WebhookSubscription table in the database.
+// Secret is only returned when the subscription is created.
+type WebhookSubscription struct {
+	ID         int       `json:"id"`
+	URL        string    `json:"url"`
+	Secret     string    `json:"secret,omitempty"`
+	EventTypes []string  `json:"event_types"`
+	UserMail   string    `json:"user_mail"`
+	Active     bool      `json:"active"`
+	CreateAt   time.Time `json:"create_at"`
+	UpdateAt   time.Time `json:"update_at"`
+}
+
+// WebhookEvent represents the WebhookEvent outbox table in the database.
+type WebhookEvent struct {
+	ID             int              `json:"id"`
+	SubscriptionID int              `json:"subscription_id"`
+	EventType      string           `json:"event_type"`
+	Payload        json.RawMessage  `json:"payload" swaggertype:"object"`
+	Status         string           `json:"status"`
+	Attempts       int              `json:"attempts"`
+	NextAttemptAt  time.Time        `json:"next_attempt_at"`
+	LastError      string           `json:"last_error"`
+	CreateAt       time.Time        `json:"create_at"`
+	DeliveredAt    *time.Time       `json:"delivered_at"`
+	AttemptLog     []WebhookAttempt `json:"attempt_log"`
+}
+
+// WebhookAttempt represents the WebhookAttempt table in the database.
+type WebhookAttempt struct {
+	ID         int       `json:"id"`
+	EventID    int       `json:"event_id"`
+	StatusCode *int      `json:"status_code"`
+	Error      string    `json:"error"`
+	DurationMs int       `json:"duration_ms"`
+	CreateAt   time.Time `json:"create_at"`
+}
+
+// PendingWebhook is an outbox event joined with the subscription it targets.
+type PendingWebhook struct {
+	Event  WebhookEvent
+	URL    string
+	Secret string
+}
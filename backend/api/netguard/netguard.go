@@ -0,0 +1,119 @@
+// Package netguard keeps the requests the server sends to URLs chosen by API
+// clients, such as data sources and webhooks, away from the server's own
+// network: loopback, private, link-local and cloud metadata addresses.
+package netguard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ErrForbidden is returned for a host or an address the policy refuses.
+var ErrForbidden = errors.New("destination not allowed")
+
+// sharedAddressSpace (RFC 6598) is used by carrier-grade NAT and some cloud
+// internal networks, and netip has no predicate for it.
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// Policy tells where outbound requests may go.
+type Policy struct {
+	// AllowedHosts, when set, are the only hosts requests may go to. An
+	// entry starting with "." matches the subdomains of the rest.
+	AllowedHosts []string
+	// AllowPrivate lets requests reach loopback, private and link-local
+	// addresses, for deployments whose data lives on the internal network.
+	AllowPrivate bool
+}
+
+// CheckURL refuses URLs that are not absolute http(s) URLs to an allowed
+// host. Addresses are checked again when connecting, after resolution.
+func (p Policy) CheckURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("url must be an absolute http(s) URL")
+	}
+	return p.CheckHost(u.Hostname())
+}
+
+// CheckHost refuses hosts outside AllowedHosts and literal addresses in the
+// private ranges.
+func (p Policy) CheckHost(host string) error {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if len(p.AllowedHosts) > 0 && !slices.ContainsFunc(p.AllowedHosts, func(allowed string) bool {
+		allowed = strings.ToLower(allowed)
+		if strings.HasPrefix(allowed, ".") {
+			return strings.HasSuffix(host, allowed)
+		}
+		return host == allowed
+	}) {
+		return fmt.Errorf("%w: host %s is not in the allowed hosts", ErrForbidden, host)
+	}
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return p.checkAddr(addr)
+	}
+	return nil
+}
+
+func (p Policy) checkAddr(addr netip.Addr) error {
+	if p.AllowPrivate {
+		return nil
+	}
+	addr = addr.Unmap()
+	if addr.IsLoopback() || addr.IsPrivate() || addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() ||
+		addr.IsInterfaceLocalMulticast() || addr.IsMulticast() || addr.IsUnspecified() || sharedAddressSpace.Contains(addr) {
+		return fmt.Errorf("%w: %s is a private address", ErrForbidden, addr)
+	}
+	return nil
+}
+
+// control checks the address a connection is about to be made to, after DNS
+// resolution, so that a public name resolving to a private address is
+// refused too.
+func (p Policy) control(_, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrForbidden, address)
+	}
+	return p.checkAddr(addrPort.Addr())
+}
+
+// Transport returns an HTTP transport that only connects to the addresses
+// the policy allows. It ignores the proxy environment variables, since a
+// proxy would connect on its behalf.
+func (p Policy) Transport() *http.Transport {
+	dialer := &net.Dialer{Timeout: 10 * time.Second, KeepAlive: 30 * time.Second, Control: p.control}
+	return &http.Transport{
+		DialContext: func(ctx context.Context, network, address string) (net.Conn, error) {
+			return dialer.DialContext(ctx, network, address)
+		},
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          10,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: time.Second,
+	}
+}
+
+// Client returns an HTTP client on Transport that also checks the host of
+// every redirect.
+func (p Policy) Client(timeout time.Duration) *http.Client {
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: p.Transport(),
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 10 {
+				return errors.New("stopped after 10 redirects")
+			}
+			return p.CheckHost(req.URL.Hostname())
+		},
+	}
+}
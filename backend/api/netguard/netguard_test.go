@@ -0,0 +1,135 @@
+package netguard
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCheckHost(t *testing.T) {
+	allowList := Policy{AllowedHosts: []string{"api.example.com", ".data.example.org"}}
+	for _, tc := range []struct {
+		name    string
+		policy  Policy
+		host    string
+		allowed bool
+	}{
+		{"public name", Policy{}, "api.example.com", true},
+		{"public address", Policy{}, "8.8.8.8", true},
+		{"public IPv6", Policy{}, "2001:4860:4860::8888", true},
+		{"loopback", Policy{}, "127.0.0.1", false},
+		{"loopback range", Policy{}, "127.1.2.3", false},
+		{"IPv6 loopback", Policy{}, "::1", false},
+		{"IPv4-mapped loopback", Policy{}, "::ffff:127.0.0.1", false},
+		{"IPv4-mapped private", Policy{}, "::ffff:10.0.0.1", false},
+		{"private 10/8", Policy{}, "10.1.2.3", false},
+		{"private 172.16/12", Policy{}, "172.20.0.1", false},
+		{"private 192.168/16", Policy{}, "192.168.1.1", false},
+		{"unique local IPv6", Policy{}, "fd00::1", false},
+		{"link-local metadata", Policy{}, "169.254.169.254", false},
+		{"link-local IPv6", Policy{}, "fe80::1", false},
+		{"multicast", Policy{}, "224.0.0.1", false},
+		{"IPv6 multicast", Policy{}, "ff02::1", false},
+		{"unspecified", Policy{}, "0.0.0.0", false},
+		{"shared address space", Policy{}, "100.64.0.1", false},
+		{"shared address space end", Policy{}, "100.127.255.254", false},
+		{"past the shared address space", Policy{}, "100.128.0.1", true},
+		{"private allowed", Policy{AllowPrivate: true}, "10.1.2.3", true},
+		{"metadata allowed with private", Policy{AllowPrivate: true}, "169.254.169.254", true},
+		{"allowed host", allowList, "api.example.com", true},
+		{"allowed host case and trailing dot", allowList, "API.Example.com.", true},
+		{"host not in the list", allowList, "example.com", false},
+		{"subdomain of an exact entry", allowList, "v2.api.example.com", false},
+		{"wildcard subdomain", allowList, "eu.data.example.org", true},
+		{"wildcard deep subdomain", allowList, "a.b.data.example.org", true},
+		{"wildcard parent itself", allowList, "data.example.org", false},
+		{"wildcard suffix lookalike", allowList, "evildata.example.org", false},
+		{"listed private address still refused", Policy{AllowedHosts: []string{"10.0.0.5"}}, "10.0.0.5", false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.policy.CheckHost(tc.host)
+			if tc.allowed && err != nil {
+				t.Errorf("got %v, want allowed", err)
+			}
+			if !tc.allowed && !errors.Is(err, ErrForbidden) {
+				t.Errorf("got %v, want ErrForbidden", err)
+			}
+		})
+	}
+}
+
+func TestCheckURL(t *testing.T) {
+	for _, tc := range []struct {
+		url     string
+		allowed bool
+	}{
+		{"https://api.example.com/data.csv", true},
+		{"http://api.example.com:8080/", true},
+		{"ftp://api.example.com/data.csv", false},
+		{"file:///etc/passwd", false},
+		{"/relative/path", false},
+		{"https://", false},
+		{"http://127.0.0.1:8080/metrics", false},
+		{"http://[::1]/", false},
+		{"http://[::ffff:169.254.169.254]/latest/meta-data", false},
+	} {
+		if err := (Policy{}).CheckURL(tc.url); (err == nil) != tc.allowed {
+			t.Errorf("%s: got %v, want allowed %v", tc.url, err, tc.allowed)
+		}
+	}
+}
+
+// TestControl checks the addresses connections are made to, after
+// resolution, which CheckHost cannot see for names.
+func TestControl(t *testing.T) {
+	for _, tc := range []struct {
+		address string
+		allowed bool
+	}{
+		{"93.184.216.34:443", true},
+		{"[2606:2800:220:1::]:443", true},
+		{"127.0.0.1:80", false},
+		{"[::1]:80", false},
+		{"[::ffff:127.0.0.1]:80", false},
+		{"[::ffff:192.168.0.1]:443", false},
+		{"169.254.169.254:80", false},
+		{"100.100.100.200:80", false},
+		{"[fe80::1%eth0]:80", false},
+		{"not-an-address", false},
+	} {
+		if err := (Policy{}).control("tcp", tc.address, nil); (err == nil) != tc.allowed {
+			t.Errorf("%s: got %v, want allowed %v", tc.address, err, tc.allowed)
+		}
+	}
+	if err := (Policy{AllowPrivate: true}).control("tcp", "127.0.0.1:80", nil); err != nil {
+		t.Errorf("loopback with AllowPrivate: got %v", err)
+	}
+}
+
+func TestClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/redirect" {
+			http.Redirect(w, r, "http://other.example.com/", http.StatusFound)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	// httptest listens on loopback, which is refused when connecting.
+	if _, err := (Policy{}).Client(0).Get(server.URL); !errors.Is(err, ErrForbidden) {
+		t.Errorf("loopback server: got %v, want ErrForbidden", err)
+	}
+
+	private := Policy{AllowPrivate: true, AllowedHosts: []string{"127.0.0.1"}}
+	resp, err := private.Client(0).Get(server.URL)
+	if err != nil {
+		t.Fatalf("loopback server with AllowPrivate: %v", err)
+	}
+	resp.Body.Close()
+
+	if _, err := private.Client(0).Get(server.URL + "/redirect"); !errors.Is(err, ErrForbidden) {
+		t.Errorf("redirect to a host not allowed: got %v, want ErrForbidden", err)
+	}
+}
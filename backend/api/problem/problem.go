@@ -0,0 +1,76 @@
+// Package problem writes errors as RFC 7807 problem details. The status
+// comes from the apperror kind of the error and the code extension member
+// carries its stable apperror code.
+package problem
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"reportia/apperror"
+	"reportia/templatelint"
+)
+
+const ContentType = "application/problem+json"
+
+// Problem is the body of every error response. Type is always about:blank,
+// so Title is the status text; Issues is set for rejected templates.
+type Problem struct {
+	Type     string               `json:"type"`
+	Title    string               `json:"title"`
+	Status   int                  `json:"status"`
+	Detail   string               `json:"detail"`
+	Instance string               `json:"instance,omitempty"`
+	Code     string               `json:"code"`
+	Issues   []templatelint.Issue `json:"issues,omitempty"`
+}
+
+var statuses = map[apperror.Kind]int{
+	apperror.NotFound:             http.StatusNotFound,
+	apperror.Inactive:             http.StatusUnprocessableEntity,
+	apperror.Validation:           http.StatusBadRequest,
+	apperror.Conflict:             http.StatusConflict,
+	apperror.Upstream:             http.StatusBadGateway,
+	apperror.Unauthorized:         http.StatusUnauthorized,
+	apperror.Gone:                 http.StatusGone,
+	apperror.NotImplemented:       http.StatusNotImplemented,
+	apperror.TooManyRequests:      http.StatusTooManyRequests,
+	apperror.TooLarge:             http.StatusRequestEntityTooLarge,
+	apperror.UnsupportedMediaType: http.StatusUnsupportedMediaType,
+}
+
+// From describes err. Internal errors are logged and their message is not
+// exposed.
+func From(r *http.Request, err error) Problem {
+	p := Problem{Type: "about:blank", Instance: r.URL.Path}
+
+	var lintErr *templatelint.Error
+	if errors.As(err, &lintErr) {
+		p.Status = http.StatusUnprocessableEntity
+		p.Code = "template_invalid"
+		p.Detail = "template is invalid"
+		p.Issues = lintErr.Issues
+	} else if status, ok := statuses[apperror.KindOf(err)]; ok {
+		p.Status = status
+		p.Code = apperror.CodeOf(err)
+		p.Detail = err.Error()
+	} else {
+		slog.ErrorContext(r.Context(), "internal error", "method", r.Method, "path", r.URL.Path, "error", err)
+		p.Status = http.StatusInternalServerError
+		p.Code = string(apperror.Internal)
+		p.Detail = "internal server error"
+	}
+	p.Title = http.StatusText(p.Status)
+	return p
+}
+
+// Write answers the request with the problem details of err.
+func Write(w http.ResponseWriter, r *http.Request, err error) {
+	p := From(r, err)
+	w.Header().Set("Content-Type", ContentType)
+	w.WriteHeader(p.Status)
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.Encode(p)
+}
@@ -0,0 +1,40 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+)
+
+// AdvisoryLocker takes Postgres session-level advisory locks so that only one
+// API replica works on a given resource at a time.
+type AdvisoryLocker struct {
+	db *sql.DB
+}
+
+func NewAdvisoryLocker(db *sql.DB) *AdvisoryLocker {
+	return &AdvisoryLocker{db: db}
+}
+
+// TryLock returns ok=false without blocking when another session holds the
+// lock. The returned release function must be called once the work is done.
+func (l *AdvisoryLocker) TryLock(ctx context.Context, namespace, key int32) (release func(), ok bool, err error) {
+	conn, err := l.db.Conn(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if err := conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock($1, $2)`, namespace, key).Scan(&ok); err != nil {
+		conn.Close()
+		return nil, false, err
+	}
+	if !ok {
+		conn.Close()
+		return nil, false, nil
+	}
+
+	release = func() {
+		conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1, $2)`, namespace, key)
+		conn.Close()
+	}
+	return release, true, nil
+}
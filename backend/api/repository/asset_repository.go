@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"reportia/database"
+	"reportia/model"
+)
+
+const assetColumns = `id, report_id, name, file_name, content_type, size, checksum, storage_key, create_at, update_at`
+
+type AssetRepository struct {
+	db      *sql.DB
+	dialect database.Dialect
+}
+
+func NewAssetRepository(db *sql.DB, dialect database.Dialect) *AssetRepository {
+	return &AssetRepository{db: db, dialect: dialect}
+}
+
+func scanAsset(row rowScanner) (*model.Asset, error) {
+	var asset model.Asset
+	err := row.Scan(&asset.ID, &asset.ReportID, &asset.Name, &asset.FileName, &asset.ContentType, &asset.Size, &asset.Checksum, &asset.StorageKey, &asset.CreateAt, &asset.UpdateAt)
+	if err != nil {
+		return nil, err
+	}
+	return &asset, nil
+}
+
+func (r *AssetRepository) ListByReport(ctx context.Context, reportID int) ([]model.Asset, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+assetColumns+` FROM ReportAsset WHERE report_id = $1 ORDER BY name`, reportID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	assets := make([]model.Asset, 0)
+	for rows.Next() {
+		asset, err := scanAsset(rows)
+		if err != nil {
+			return nil, err
+		}
+		assets = append(assets, *asset)
+	}
+	return assets, rows.Err()
+}
+
+// GetByID and GetByName return nil when the asset does not exist.
+func (r *AssetRepository) GetByID(ctx context.Context, id int) (*model.Asset, error) {
+	asset, err := scanAsset(r.db.QueryRowContext(ctx, `SELECT `+assetColumns+` FROM ReportAsset WHERE id = $1`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return asset, err
+}
+
+func (r *AssetRepository) GetByName(ctx context.Context, reportID int, name string) (*model.Asset, error) {
+	asset, err := scanAsset(r.db.QueryRowContext(ctx, `SELECT `+assetColumns+` FROM ReportAsset WHERE report_id = $1 AND name = $2`, reportID, name))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return asset, err
+}
+
+func (r *AssetRepository) Create(ctx context.Context, asset model.Asset) (*model.Asset, error) {
+	return scanAsset(r.db.QueryRowContext(ctx,
+		`INSERT INTO ReportAsset (report_id, name, file_name, content_type, size, checksum, storage_key)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7)
+		 RETURNING `+assetColumns,
+		asset.ReportID, asset.Name, asset.FileName, asset.ContentType, asset.Size, asset.Checksum, asset.StorageKey))
+}
+
+// Replace points an existing asset at new content.
+func (r *AssetRepository) Replace(ctx context.Context, asset model.Asset) (*model.Asset, error) {
+	return scanAsset(r.db.QueryRowContext(ctx,
+		`UPDATE ReportAsset SET file_name = $1, content_type = $2, size = $3, checksum = $4, storage_key = $5, update_at = `+r.dialect.Now()+`
+		 WHERE id = $6
+		 RETURNING `+assetColumns,
+		asset.FileName, asset.ContentType, asset.Size, asset.Checksum, asset.StorageKey, asset.ID))
+}
+
+func (r *AssetRepository) Delete(ctx context.Context, id int) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM ReportAsset WHERE id = $1`, id)
+	return err
+}
@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"reportia/model"
+)
+
+const dataSourceColumns = `id, name, kind, config, user_mail, active, create_at, update_at`
+
+type DataSourceRepository struct {
+	db *sql.DB
+}
+
+func NewDataSourceRepository(db *sql.DB) *DataSourceRepository {
+	return &DataSourceRepository{db: db}
+}
+
+func scanDataSource(row rowScanner) (*model.DataSource, error) {
+	var ds model.DataSource
+	var config []byte
+	if err := row.Scan(&ds.ID, &ds.Name, &ds.Kind, &config, &ds.UserMail, &ds.Active, &ds.CreateAt, &ds.UpdateAt); err != nil {
+		return nil, err
+	}
+	ds.Config = config
+	return &ds, nil
+}
+
+func (r *DataSourceRepository) List(ctx context.Context) ([]model.DataSource, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+dataSourceColumns+` FROM DataSource ORDER BY create_at DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	dataSources := make([]model.DataSource, 0)
+	for rows.Next() {
+		ds, err := scanDataSource(rows)
+		if err != nil {
+			return nil, err
+		}
+		dataSources = append(dataSources, *ds)
+	}
+	return dataSources, rows.Err()
+}
+
+func (r *DataSourceRepository) GetByID(ctx context.Context, id int) (*model.DataSource, error) {
+	ds, err := scanDataSource(r.db.QueryRowContext(ctx, `SELECT `+dataSourceColumns+` FROM DataSource WHERE id = $1`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return ds, err
+}
+
+func (r *DataSourceRepository) Create(ctx context.Context, ds model.DataSource) (*model.DataSource, error) {
+	return scanDataSource(r.db.QueryRowContext(ctx,
+		`INSERT INTO DataSource (name, kind, config, user_mail, active)
+		 VALUES ($1, $2, $3, $4, true)
+		 RETURNING `+dataSourceColumns,
+		ds.Name, ds.Kind, []byte(ds.Config), ds.UserMail))
+}
+
+func (r *DataSourceRepository) Update(ctx context.Context, ds model.DataSource) (*model.DataSource, error) {
+	updated, err := scanDataSource(r.db.QueryRowContext(ctx,
+		`UPDATE DataSource SET name = $1, kind = $2, config = $3, update_at = NOW()
+		 WHERE id = $4
+		 RETURNING `+dataSourceColumns,
+		ds.Name, ds.Kind, []byte(ds.Config), ds.ID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return updated, err
+}
+
+func (r *DataSourceRepository) TurnOnOff(ctx context.Context, id int, active bool) error {
+	res, err := r.db.ExecContext(ctx, `UPDATE DataSource SET active = $1, update_at = NOW() WHERE id = $2`, active, id)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
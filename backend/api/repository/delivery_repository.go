@@ -0,0 +1,154 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"reportia/model"
+	"strings"
+)
+
+const deliveryRuleColumns = `id, report_id, schedule_id, recipients, subject, attach_pdf, active, create_at, update_at`
+const deliveryColumns = `id, generation_id, rule_id, recipients, status, attempts, COALESCE(error, ''), create_at, sent_at`
+
+type DeliveryRepository struct {
+	db *sql.DB
+}
+
+func NewDeliveryRepository(db *sql.DB) *DeliveryRepository {
+	return &DeliveryRepository{db: db}
+}
+
+// joinList and splitList store short string lists as comma-separated text,
+// which keeps the schema portable across database engines.
+func joinList(values []string) string {
+	return strings.Join(values, ",")
+}
+
+func splitList(values string) []string {
+	if values == "" {
+		return []string{}
+	}
+	return strings.Split(values, ",")
+}
+
+func scanDeliveryRule(row rowScanner) (*model.DeliveryRule, error) {
+	var rule model.DeliveryRule
+	var recipients string
+	if err := row.Scan(&rule.ID, &rule.ReportID, &rule.ScheduleID, &recipients, &rule.Subject, &rule.AttachPDF, &rule.Active, &rule.CreateAt, &rule.UpdateAt); err != nil {
+		return nil, err
+	}
+	rule.Recipients = splitList(recipients)
+	return &rule, nil
+}
+
+func scanDelivery(row rowScanner) (*model.Delivery, error) {
+	var delivery model.Delivery
+	var recipients string
+	if err := row.Scan(&delivery.ID, &delivery.GenerationID, &delivery.RuleID, &recipients, &delivery.Status, &delivery.Attempts, &delivery.Error, &delivery.CreateAt, &delivery.SentAt); err != nil {
+		return nil, err
+	}
+	delivery.Recipients = splitList(recipients)
+	return &delivery, nil
+}
+
+func (r *DeliveryRepository) queryRules(ctx context.Context, query string, args ...any) ([]model.DeliveryRule, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rules := make([]model.DeliveryRule, 0)
+	for rows.Next() {
+		rule, err := scanDeliveryRule(rows)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, *rule)
+	}
+	return rules, rows.Err()
+}
+
+func (r *DeliveryRepository) ListRules(ctx context.Context) ([]model.DeliveryRule, error) {
+	return r.queryRules(ctx, `SELECT `+deliveryRuleColumns+` FROM DeliveryRule ORDER BY create_at DESC`)
+}
+
+// ListActiveRulesFor returns the rules attached to the report, plus those
+// attached to the schedule when the generation came from one.
+func (r *DeliveryRepository) ListActiveRulesFor(ctx context.Context, reportID int, scheduleID *int) ([]model.DeliveryRule, error) {
+	return r.queryRules(ctx,
+		`SELECT `+deliveryRuleColumns+` FROM DeliveryRule
+		 WHERE active = true AND (report_id = $1 OR ($2::INTEGER IS NOT NULL AND schedule_id = $2))
+		 ORDER BY id`,
+		reportID, scheduleID)
+}
+
+func (r *DeliveryRepository) CreateRule(ctx context.Context, rule model.DeliveryRule) (*model.DeliveryRule, error) {
+	return scanDeliveryRule(r.db.QueryRowContext(ctx,
+		`INSERT INTO DeliveryRule (report_id, schedule_id, recipients, subject, attach_pdf, active)
+		 VALUES ($1, $2, $3, $4, $5, true)
+		 RETURNING `+deliveryRuleColumns,
+		rule.ReportID, rule.ScheduleID, joinList(rule.Recipients), rule.Subject, rule.AttachPDF))
+}
+
+func (r *DeliveryRepository) UpdateRule(ctx context.Context, rule model.DeliveryRule) (*model.DeliveryRule, error) {
+	updated, err := scanDeliveryRule(r.db.QueryRowContext(ctx,
+		`UPDATE DeliveryRule SET recipients = $1, subject = $2, attach_pdf = $3, update_at = NOW()
+		 WHERE id = $4
+		 RETURNING `+deliveryRuleColumns,
+		joinList(rule.Recipients), rule.Subject, rule.AttachPDF, rule.ID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return updated, err
+}
+
+func (r *DeliveryRepository) TurnOnOffRule(ctx context.Context, id int, active bool) error {
+	res, err := r.db.ExecContext(ctx, `UPDATE DeliveryRule SET active = $1, update_at = NOW() WHERE id = $2`, active, id)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *DeliveryRepository) Create(ctx context.Context, generationID, ruleID int, recipients []string) (*model.Delivery, error) {
+	return scanDelivery(r.db.QueryRowContext(ctx,
+		`INSERT INTO Delivery (generation_id, rule_id, recipients, status)
+		 VALUES ($1, $2, $3, 'pending')
+		 RETURNING `+deliveryColumns,
+		generationID, ruleID, joinList(recipients)))
+}
+
+func (r *DeliveryRepository) Finish(ctx context.Context, id int, status string, attempts int, errorMessage string) error {
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE Delivery
+		 SET status = $1, attempts = $2, error = NULLIF($3, ''), sent_at = CASE WHEN $1 = 'sent' THEN NOW() ELSE sent_at END
+		 WHERE id = $4`,
+		status, attempts, errorMessage, id)
+	return err
+}
+
+func (r *DeliveryRepository) ListByGeneration(ctx context.Context, generationID int) ([]model.Delivery, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+deliveryColumns+` FROM Delivery WHERE generation_id = $1 ORDER BY id`, generationID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := make([]model.Delivery, 0)
+	for rows.Next() {
+		delivery, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, *delivery)
+	}
+	return deliveries, rows.Err()
+}
@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"reportia/database"
+	"reportia/model"
+)
+
+const generationColumns = `id, report_id, schedule_id, llm, model, status, COALESCE(output, ''), COALESCE(error, ''), started_at, finished_at`
+
+type GenerationRepository struct {
+	db      *sql.DB
+	dialect database.Dialect
+}
+
+func NewGenerationRepository(db *sql.DB, dialect database.Dialect) *GenerationRepository {
+	return &GenerationRepository{db: db, dialect: dialect}
+}
+
+func scanGeneration(row rowScanner) (*model.Generation, error) {
+	var gen model.Generation
+	err := row.Scan(&gen.ID, &gen.ReportID, &gen.ScheduleID, &gen.LLM, &gen.Model, &gen.Status, &gen.Output, &gen.Error, &gen.StartedAt, &gen.FinishedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &gen, nil
+}
+
+func (r *GenerationRepository) Start(ctx context.Context, reportID int, scheduleID *int, llm, model string) (*model.Generation, error) {
+	return scanGeneration(r.db.QueryRowContext(ctx,
+		`INSERT INTO Generation (report_id, schedule_id, llm, model, status)
+		 VALUES ($1, $2, $3, $4, 'running')
+		 RETURNING `+generationColumns,
+		reportID, scheduleID, llm, model))
+}
+
+func (r *GenerationRepository) Finish(ctx context.Context, id int, status, output, errorMessage string) error {
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE Generation SET status = $1, output = NULLIF($2, ''), error = NULLIF($3, ''), finished_at = `+r.dialect.Now()+` WHERE id = $4`,
+		status, output, errorMessage, id)
+	return err
+}
+
+func (r *GenerationRepository) ListBySchedule(ctx context.Context, scheduleID, limit int) ([]model.Generation, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT `+generationColumns+` FROM Generation WHERE schedule_id = $1 ORDER BY started_at DESC LIMIT $2`,
+		scheduleID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	generations := make([]model.Generation, 0)
+	for rows.Next() {
+		gen, err := scanGeneration(rows)
+		if err != nil {
+			return nil, err
+		}
+		generations = append(generations, *gen)
+	}
+	return generations, rows.Err()
+}
+
+func (r *GenerationRepository) GetByID(ctx context.Context, id int) (*model.Generation, error) {
+	gen, err := scanGeneration(r.db.QueryRowContext(ctx, `SELECT `+generationColumns+` FROM Generation WHERE id = $1`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return gen, err
+}
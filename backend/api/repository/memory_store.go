@@ -0,0 +1,411 @@
+package repository
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"reportia/model"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemoryReportStore is a thread-safe ReportStore that keeps reports in
+// process memory. It mirrors the Postgres repository's ordering and inactive
+// report rules, and is meant for demos and tests; nothing survives a restart.
+type MemoryReportStore struct {
+	mu      sync.RWMutex
+	reports []model.Report
+	nextID  int
+}
+
+func NewMemoryReportStore() *MemoryReportStore {
+	return &MemoryReportStore{nextID: 1}
+}
+
+func (s *MemoryReportStore) Search(ctx context.Context, q model.ReportQuery) ([]model.Report, int, string, error) {
+	key, column, err := reportSort(q)
+	if err != nil {
+		return nil, 0, "", err
+	}
+	var cursor *reportCursor
+	if q.Cursor != "" {
+		if cursor, err = decodeReportCursor(q.Cursor, key, q.SortDesc); err != nil {
+			return nil, 0, "", err
+		}
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	matches := make([]model.Report, 0)
+	for _, rep := range s.reports {
+		if matchesReportQuery(rep, q) {
+			matches = append(matches, *copyReport(rep))
+		}
+	}
+	totalCount := len(matches)
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return compareReports(column, matches[i], matches[j], q.SortDesc) < 0
+	})
+
+	offset := (q.Page - 1) * q.PageSize
+	if cursor != nil {
+		offset = sort.Search(len(matches), func(i int) bool {
+			return compareToCursor(column, matches[i], cursor, q.SortDesc) > 0
+		})
+	}
+	reports := paginate(matches, offset, q.PageSize+1)
+
+	var nextCursor string
+	if len(reports) > q.PageSize {
+		reports = reports[:q.PageSize]
+		nextCursor = encodeReportCursor(key, q.SortDesc, column, reports[len(reports)-1])
+	}
+	return reports, totalCount, nextCursor, nil
+}
+
+func (s *MemoryReportStore) Filter(ctx context.Context, id int, userMail *string) (*model.Report, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	i := s.liveIndexOf(id)
+	if i < 0 || (userMail != nil && s.reports[i].UserMail != *userMail) {
+		return nil, nil
+	}
+	if !s.reports[i].Active {
+		return nil, ErrReportInactive
+	}
+	return copyReport(s.reports[i]), nil
+}
+
+func (s *MemoryReportStore) Create(ctx context.Context, rep model.Report) (*model.Report, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	rep = *copyReport(rep)
+	rep.ID = s.nextID
+	rep.Active = true
+	rep.CreateAt = now
+	rep.UpdateAt = now
+	if rep.Tags == nil {
+		rep.Tags = []string{}
+	}
+	if rep.AllowedFileTypes == nil {
+		rep.AllowedFileTypes = []string{}
+	}
+	s.nextID++
+	s.reports = append(s.reports, rep)
+	return copyReport(rep), nil
+}
+
+func (s *MemoryReportStore) Update(ctx context.Context, upd model.ReportUpdate) (*model.Report, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.liveIndexOf(upd.ID)
+	if i < 0 {
+		return nil, sql.ErrNoRows
+	}
+	rep := &s.reports[i]
+	rep.Template = upd.Template
+	rep.DataSourceID = copyInt(upd.DataSourceID)
+	for _, field := range []struct {
+		value  *string
+		target *string
+	}{
+		{upd.Name, &rep.Name},
+		{upd.Description, &rep.Description},
+		{upd.Category, &rep.Category},
+		{upd.DefaultLLM, &rep.DefaultLLM},
+		{upd.DefaultModel, &rep.DefaultModel},
+		{upd.DefaultPrompt, &rep.DefaultPrompt},
+		{upd.Thumbnail, &rep.Thumbnail},
+	} {
+		if field.value != nil {
+			*field.target = *field.value
+		}
+	}
+	if upd.Tags != nil {
+		rep.Tags = append([]string{}, *upd.Tags...)
+	}
+	if upd.AllowedFileTypes != nil {
+		rep.AllowedFileTypes = append([]string{}, *upd.AllowedFileTypes...)
+	}
+	rep.UpdateAt = time.Now()
+	return copyReport(*rep), nil
+}
+
+func (s *MemoryReportStore) TurnOnOff(ctx context.Context, id int, active bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.liveIndexOf(id)
+	if i < 0 {
+		return sql.ErrNoRows
+	}
+	s.reports[i].Active = active
+	return nil
+}
+
+func (s *MemoryReportStore) SoftDelete(ctx context.Context, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.liveIndexOf(id)
+	if i < 0 {
+		return sql.ErrNoRows
+	}
+	now := time.Now()
+	s.reports[i].DeletedAt = &now
+	return nil
+}
+
+func (s *MemoryReportStore) Restore(ctx context.Context, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.indexOf(id)
+	if i < 0 || s.reports[i].DeletedAt == nil {
+		return sql.ErrNoRows
+	}
+	s.reports[i].DeletedAt = nil
+	s.reports[i].UpdateAt = time.Now()
+	return nil
+}
+
+// HardDelete only removes the report: generations are kept by a separate
+// store and the in-memory mode has no schedules or deliveries.
+func (s *MemoryReportStore) HardDelete(ctx context.Context, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.indexOf(id)
+	if i < 0 {
+		return sql.ErrNoRows
+	}
+	s.reports = slices.Delete(s.reports, i, i+1)
+	return nil
+}
+
+func (s *MemoryReportStore) ListDeletedBefore(ctx context.Context, before time.Time, limit int) ([]int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	deleted := make([]model.Report, 0)
+	for _, rep := range s.reports {
+		if rep.DeletedAt != nil && rep.DeletedAt.Before(before) {
+			deleted = append(deleted, rep)
+		}
+	}
+	slices.SortFunc(deleted, func(a, b model.Report) int {
+		return compareValues(*a.DeletedAt, *b.DeletedAt, a.ID, b.ID)
+	})
+	ids := make([]int, 0, min(limit, len(deleted)))
+	for _, rep := range deleted[:min(limit, len(deleted))] {
+		ids = append(ids, rep.ID)
+	}
+	return ids, nil
+}
+
+func (s *MemoryReportStore) indexOf(id int) int {
+	for i, rep := range s.reports {
+		if rep.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// liveIndexOf is indexOf ignoring the reports in the trash.
+func (s *MemoryReportStore) liveIndexOf(id int) int {
+	i := s.indexOf(id)
+	if i >= 0 && s.reports[i].DeletedAt != nil {
+		return -1
+	}
+	return i
+}
+
+func matchesReportQuery(rep model.Report, q model.ReportQuery) bool {
+	document := strings.ToLower(rep.Name + " " + rep.Description + " " + rep.Template)
+	for _, word := range searchWords(q.Search) {
+		if !strings.Contains(document, word) {
+			return false
+		}
+	}
+	for _, tag := range q.Tags {
+		if !slices.Contains(rep.Tags, tag) {
+			return false
+		}
+	}
+	switch {
+	case q.Deleted != (rep.DeletedAt != nil),
+		q.ID != 0 && rep.ID != q.ID,
+		q.Name != nil && rep.Name != *q.Name,
+		q.Category != nil && rep.Category != *q.Category,
+		q.UserMail != nil && rep.UserMail != *q.UserMail,
+		q.Active != nil && rep.Active != *q.Active,
+		q.CreatedFrom != nil && rep.CreateAt.Before(*q.CreatedFrom),
+		q.CreatedTo != nil && rep.CreateAt.After(*q.CreatedTo),
+		q.UpdatedFrom != nil && rep.UpdateAt.Before(*q.UpdatedFrom),
+		q.UpdatedTo != nil && rep.UpdateAt.After(*q.UpdatedTo):
+		return false
+	}
+	return true
+}
+
+// compareReports orders reports like the SQL ORDER BY: by the sort column,
+// then by id, both in the requested direction.
+func compareReports(column reportSortColumn, a, b model.Report, desc bool) int {
+	return directed(compareValues(column.value(a), column.value(b), a.ID, b.ID), desc)
+}
+
+func compareToCursor(column reportSortColumn, rep model.Report, cursor *reportCursor, desc bool) int {
+	return directed(compareValues(column.value(rep), cursor.Value, rep.ID, cursor.ID), desc)
+}
+
+func directed(c int, desc bool) int {
+	if desc {
+		return -c
+	}
+	return c
+}
+
+func compareValues(a, b any, aID, bID int) int {
+	c := 0
+	switch a := a.(type) {
+	case int:
+		c = cmp.Compare(a, b.(int))
+	case string:
+		c = strings.Compare(a, b.(string))
+	case bool:
+		c = cmp.Compare(boolRank(a), boolRank(b.(bool)))
+	case time.Time:
+		c = a.Compare(b.(time.Time))
+	}
+	if c == 0 {
+		c = cmp.Compare(aID, bID)
+	}
+	return c
+}
+
+func boolRank(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func paginate(reports []model.Report, offset, limit int) []model.Report {
+	if offset < 0 || offset >= len(reports) {
+		return make([]model.Report, 0)
+	}
+	end := offset + limit
+	if end > len(reports) {
+		end = len(reports)
+	}
+	return reports[offset:end]
+}
+
+func copyReport(rep model.Report) *model.Report {
+	rep.DataSourceID = copyInt(rep.DataSourceID)
+	if rep.DeletedAt != nil {
+		deletedAt := *rep.DeletedAt
+		rep.DeletedAt = &deletedAt
+	}
+	if rep.Tags != nil {
+		rep.Tags = append([]string{}, rep.Tags...)
+	}
+	if rep.AllowedFileTypes != nil {
+		rep.AllowedFileTypes = append([]string{}, rep.AllowedFileTypes...)
+	}
+	return &rep
+}
+
+func copyInt(v *int) *int {
+	if v == nil {
+		return nil
+	}
+	c := *v
+	return &c
+}
+
+// MemoryGenerationStore is the in-memory counterpart of GenerationRepository.
+type MemoryGenerationStore struct {
+	mu          sync.RWMutex
+	generations []model.Generation
+}
+
+func NewMemoryGenerationStore() *MemoryGenerationStore {
+	return &MemoryGenerationStore{}
+}
+
+func (s *MemoryGenerationStore) Start(ctx context.Context, reportID int, scheduleID *int, llm, llmModel string) (*model.Generation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	gen := model.Generation{
+		ID:         len(s.generations) + 1,
+		ReportID:   reportID,
+		ScheduleID: copyInt(scheduleID),
+		LLM:        llm,
+		Model:      llmModel,
+		Status:     model.GenerationStatusRunning,
+		StartedAt:  time.Now(),
+	}
+	s.generations = append(s.generations, gen)
+	return copyGeneration(gen), nil
+}
+
+func (s *MemoryGenerationStore) Finish(ctx context.Context, id int, status, output, errorMessage string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if id <= 0 || id > len(s.generations) {
+		return sql.ErrNoRows
+	}
+	finishedAt := time.Now()
+	gen := &s.generations[id-1]
+	gen.Status = status
+	gen.Output = output
+	gen.Error = errorMessage
+	gen.FinishedAt = &finishedAt
+	return nil
+}
+
+func (s *MemoryGenerationStore) ListBySchedule(ctx context.Context, scheduleID, limit int) ([]model.Generation, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	generations := make([]model.Generation, 0)
+	for i := len(s.generations) - 1; i >= 0 && len(generations) < limit; i-- {
+		gen := s.generations[i]
+		if gen.ScheduleID != nil && *gen.ScheduleID == scheduleID {
+			generations = append(generations, *copyGeneration(gen))
+		}
+	}
+	return generations, nil
+}
+
+func (s *MemoryGenerationStore) GetByID(ctx context.Context, id int) (*model.Generation, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if id <= 0 || id > len(s.generations) {
+		return nil, nil
+	}
+	return copyGeneration(s.generations[id-1]), nil
+}
+
+func copyGeneration(gen model.Generation) *model.Generation {
+	gen.ScheduleID = copyInt(gen.ScheduleID)
+	if gen.FinishedAt != nil {
+		finishedAt := *gen.FinishedAt
+		gen.FinishedAt = &finishedAt
+	}
+	return &gen
+}
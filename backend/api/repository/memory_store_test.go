@@ -0,0 +1,355 @@
+package repository_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"reportia/database"
+	"reportia/migrations"
+	"reportia/model"
+	"reportia/repository"
+	"slices"
+	"testing"
+	"time"
+)
+
+// postgresURLEnv names a Postgres database the store tests may empty and
+// fill. Without it the SQL repository is only tested on SQLite.
+const postgresURLEnv = "REPORTIA_TEST_POSTGRES_URL"
+
+const testTemplate = "<html><body><!-- DYNAMIC BODY CONTENT HERE --></body></html>"
+
+// forEachStore runs test against the in-memory store and the SQL repository,
+// so that both are held to the same behaviour.
+func forEachStore(t *testing.T, test func(t *testing.T, store repository.ReportStore)) {
+	t.Run("memory", func(t *testing.T) {
+		test(t, repository.NewMemoryReportStore())
+	})
+	t.Run("sqlite", func(t *testing.T) {
+		url := "file:" + filepath.Join(t.TempDir(), "reportia.db") + "?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)"
+		test(t, openSQLStore(t, "sqlite", url))
+	})
+	t.Run("postgres", func(t *testing.T) {
+		url := os.Getenv(postgresURLEnv)
+		if url == "" {
+			t.Skip(postgresURLEnv + " is not set")
+		}
+		test(t, openSQLStore(t, "postgres", url))
+	})
+}
+
+func openSQLStore(t *testing.T, driver, url string) repository.ReportStore {
+	t.Helper()
+	ctx := context.Background()
+	db, err := database.Open(ctx, database.Options{Driver: driver, URL: url, MaxOpenConns: 1, ConnectTimeout: 5 * time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	migrator, err := migrations.New(db.DB, db.Dialect)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrator.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if db.Dialect == database.Postgres {
+		if _, err := db.ExecContext(ctx, `TRUNCATE Report RESTART IDENTITY CASCADE`); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return repository.NewReportRepository(db.DB, db.Dialect)
+}
+
+func createReport(t *testing.T, store repository.ReportStore, rep model.Report) *model.Report {
+	t.Helper()
+	if rep.Template == "" {
+		rep.Template = testTemplate
+	}
+	if rep.UserMail == "" {
+		rep.UserMail = "owner@example.com"
+	}
+	created, err := store.Create(context.Background(), rep)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return created
+}
+
+func search(t *testing.T, store repository.ReportStore, q model.ReportQuery) ([]string, int, string) {
+	t.Helper()
+	if q.Page == 0 {
+		q.Page = 1
+	}
+	if q.PageSize == 0 {
+		q.PageSize = 50
+	}
+	reports, total, next, err := store.Search(context.Background(), q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make([]string, 0, len(reports))
+	for _, rep := range reports {
+		names = append(names, rep.Name)
+	}
+	return names, total, next
+}
+
+func ptr[T any](v T) *T { return &v }
+
+func TestReportStoreSearchFilters(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store repository.ReportStore) {
+		ctx := context.Background()
+		alpha := createReport(t, store, model.Report{Name: "Alpha", Category: "sales", Tags: []string{"q1", "eu"}, UserMail: "a@example.com"})
+		createReport(t, store, model.Report{Name: "Beta", Description: "Weekly numbers", Category: "ops", Tags: []string{"q1"}, UserMail: "b@example.com"})
+		gamma := createReport(t, store, model.Report{Name: "Gamma", Category: "sales", Tags: []string{"eu"}, UserMail: "a@example.com"})
+		delta := createReport(t, store, model.Report{Name: "Delta", Category: "sales"})
+		if err := store.TurnOnOff(ctx, gamma.ID, false); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.SoftDelete(ctx, delta.ID); err != nil {
+			t.Fatal(err)
+		}
+
+		for _, tc := range []struct {
+			name  string
+			query model.ReportQuery
+			want  []string
+		}{
+			{"all live reports", model.ReportQuery{}, []string{"Alpha", "Beta", "Gamma"}},
+			{"id", model.ReportQuery{ID: alpha.ID}, []string{"Alpha"}},
+			{"exact name", model.ReportQuery{Name: ptr("Beta")}, []string{"Beta"}},
+			{"name prefix does not match", model.ReportQuery{Name: ptr("Bet")}, []string{}},
+			{"search is case insensitive", model.ReportQuery{Search: "weekly"}, []string{"Beta"}},
+			{"search in names", model.ReportQuery{Search: "GAMMA"}, []string{"Gamma"}},
+			// Postgres matches whole words only, the other stores parts of
+			// words too, so searches use whole words.
+			{"search matches every word", model.ReportQuery{Search: "numbers, weekly"}, []string{"Beta"}},
+			{"search misses a word", model.ReportQuery{Search: "weekly totals"}, []string{}},
+			{"one tag", model.ReportQuery{Tags: []string{"eu"}}, []string{"Alpha", "Gamma"}},
+			{"all tags", model.ReportQuery{Tags: []string{"q1", "eu"}}, []string{"Alpha"}},
+			{"category", model.ReportQuery{Category: ptr("sales")}, []string{"Alpha", "Gamma"}},
+			{"user mail", model.ReportQuery{UserMail: ptr("a@example.com")}, []string{"Alpha", "Gamma"}},
+			{"active", model.ReportQuery{Active: ptr(true)}, []string{"Alpha", "Beta"}},
+			{"inactive", model.ReportQuery{Active: ptr(false)}, []string{"Gamma"}},
+			{"trash", model.ReportQuery{Deleted: true}, []string{"Delta"}},
+			{"combined", model.ReportQuery{Category: ptr("sales"), Active: ptr(true)}, []string{"Alpha"}},
+		} {
+			t.Run(tc.name, func(t *testing.T) {
+				tc.query.SortBy = "id"
+				names, total, next := search(t, store, tc.query)
+				if !slices.Equal(names, tc.want) {
+					t.Errorf("got %v, want %v", names, tc.want)
+				}
+				if total != len(tc.want) {
+					t.Errorf("total count %d, want %d", total, len(tc.want))
+				}
+				if next != "" {
+					t.Errorf("next cursor %q on the only page", next)
+				}
+			})
+		}
+	})
+}
+
+func TestReportStoreTagsMatchLiterally(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store repository.ReportStore) {
+		createReport(t, store, model.Report{Name: "Promo", Tags: []string{"50%_off", `a\b`}})
+		createReport(t, store, model.Report{Name: "Plain", Tags: []string{"500off", "ab"}})
+
+		for _, tc := range []struct {
+			tags []string
+			want []string
+		}{
+			{[]string{"%"}, []string{}},
+			{[]string{"_"}, []string{}},
+			{[]string{"50%"}, []string{}},
+			{[]string{"50%_off"}, []string{"Promo"}},
+			{[]string{"50__off"}, []string{}},
+			{[]string{`a\b`}, []string{"Promo"}},
+			{[]string{"ab"}, []string{"Plain"}},
+		} {
+			names, _, _ := search(t, store, model.ReportQuery{SortBy: "id", Tags: tc.tags})
+			if !slices.Equal(names, tc.want) {
+				t.Errorf("tags %q: got %v, want %v", tc.tags, names, tc.want)
+			}
+		}
+	})
+}
+
+func TestReportStorePagination(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store repository.ReportStore) {
+		// Created out of order so that sorting by name differs from by id.
+		for _, name := range []string{"r4", "r1", "r7", "r2", "r6", "r3", "r5"} {
+			createReport(t, store, model.Report{Name: name})
+		}
+
+		for _, tc := range []struct {
+			page int
+			want []string
+		}{
+			{1, []string{"r1", "r2", "r3"}},
+			{2, []string{"r4", "r5", "r6"}},
+			{3, []string{"r7"}},
+			{4, []string{}},
+		} {
+			names, total, _ := search(t, store, model.ReportQuery{SortBy: "name", Page: tc.page, PageSize: 3})
+			if !slices.Equal(names, tc.want) {
+				t.Errorf("page %d: got %v, want %v", tc.page, names, tc.want)
+			}
+			if total != 7 {
+				t.Errorf("page %d: total count %d, want 7", tc.page, total)
+			}
+		}
+
+		names, _, _ := search(t, store, model.ReportQuery{SortBy: "name", SortDesc: true, PageSize: 2})
+		if want := []string{"r7", "r6"}; !slices.Equal(names, want) {
+			t.Errorf("descending: got %v, want %v", names, want)
+		}
+	})
+}
+
+func TestReportStoreCursor(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store repository.ReportStore) {
+		for _, name := range []string{"r4", "r1", "r7", "r2", "r6", "r3", "r5"} {
+			createReport(t, store, model.Report{Name: name})
+		}
+
+		for _, desc := range []bool{false, true} {
+			var all []string
+			cursor := ""
+			for pages := 0; ; pages++ {
+				if pages > 5 {
+					t.Fatalf("desc=%v: cursor does not end", desc)
+				}
+				names, total, next := search(t, store, model.ReportQuery{SortBy: "name", SortDesc: desc, PageSize: 3, Cursor: cursor})
+				if total != 7 {
+					t.Errorf("desc=%v: total count %d, want 7", desc, total)
+				}
+				all = append(all, names...)
+				if next == "" {
+					break
+				}
+				cursor = next
+			}
+			want := []string{"r1", "r2", "r3", "r4", "r5", "r6", "r7"}
+			if desc {
+				slices.Reverse(want)
+			}
+			if !slices.Equal(all, want) {
+				t.Errorf("desc=%v: got %v, want %v", desc, all, want)
+			}
+		}
+
+		// A cursor is bound to the sort it was made for.
+		_, _, next := search(t, store, model.ReportQuery{SortBy: "name", PageSize: 3})
+		ctx := context.Background()
+		for _, q := range []model.ReportQuery{
+			{SortBy: "id", Page: 1, PageSize: 3, Cursor: next},
+			{SortBy: "name", SortDesc: true, Page: 1, PageSize: 3, Cursor: next},
+			{SortBy: "name", Page: 1, PageSize: 3, Cursor: "not-a-cursor"},
+			{SortBy: "unknown", Page: 1, PageSize: 3},
+		} {
+			if _, _, _, err := store.Search(ctx, q); !errors.Is(err, repository.ErrInvalidReportQuery) {
+				t.Errorf("sort %q desc=%v cursor %q: got %v, want ErrInvalidReportQuery", q.SortBy, q.SortDesc, q.Cursor, err)
+			}
+		}
+	})
+}
+
+func TestReportStoreInactiveReports(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store repository.ReportStore) {
+		ctx := context.Background()
+		rep := createReport(t, store, model.Report{Name: "Report", UserMail: "a@example.com"})
+		if !rep.Active {
+			t.Fatal("reports are created active")
+		}
+
+		found, err := store.Filter(ctx, rep.ID, ptr("a@example.com"))
+		if err != nil || found == nil || found.ID != rep.ID {
+			t.Fatalf("Filter of an active report: got %v, %v", found, err)
+		}
+		if found, err := store.Filter(ctx, rep.ID, ptr("b@example.com")); err != nil || found != nil {
+			t.Errorf("Filter of another user's report: got %v, %v, want nil", found, err)
+		}
+		if found, err := store.Filter(ctx, rep.ID+100, nil); err != nil || found != nil {
+			t.Errorf("Filter of a missing report: got %v, %v, want nil", found, err)
+		}
+
+		if err := store.TurnOnOff(ctx, rep.ID, false); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := store.Filter(ctx, rep.ID, nil); !errors.Is(err, repository.ErrReportInactive) {
+			t.Errorf("Filter of an inactive report: got %v, want ErrReportInactive", err)
+		}
+		// Inactive reports can still be edited and turned back on.
+		name := "Renamed"
+		if updated, err := store.Update(ctx, model.ReportUpdate{ID: rep.ID, Template: testTemplate, Name: &name}); err != nil || updated.Name != name || updated.Active {
+			t.Errorf("Update of an inactive report: got %v, %v", updated, err)
+		}
+		if err := store.TurnOnOff(ctx, rep.ID, true); err != nil {
+			t.Fatal(err)
+		}
+		if found, err := store.Filter(ctx, rep.ID, nil); err != nil || found == nil {
+			t.Errorf("Filter of a report turned back on: got %v, %v", found, err)
+		}
+
+		missing := rep.ID + 100
+		if err := store.TurnOnOff(ctx, missing, false); !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("TurnOnOff of a missing report: got %v, want sql.ErrNoRows", err)
+		}
+		if _, err := store.Update(ctx, model.ReportUpdate{ID: missing, Template: testTemplate}); !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("Update of a missing report: got %v, want sql.ErrNoRows", err)
+		}
+	})
+}
+
+func TestReportStoreTrash(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store repository.ReportStore) {
+		ctx := context.Background()
+		rep := createReport(t, store, model.Report{Name: "Report"})
+
+		if err := store.Restore(ctx, rep.ID); !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("Restore of a live report: got %v, want sql.ErrNoRows", err)
+		}
+		if err := store.SoftDelete(ctx, rep.ID); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.SoftDelete(ctx, rep.ID); !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("SoftDelete of a report in the trash: got %v, want sql.ErrNoRows", err)
+		}
+		if found, err := store.Filter(ctx, rep.ID, nil); err != nil || found != nil {
+			t.Errorf("Filter of a report in the trash: got %v, %v, want nil", found, err)
+		}
+		if err := store.TurnOnOff(ctx, rep.ID, false); !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("TurnOnOff of a report in the trash: got %v, want sql.ErrNoRows", err)
+		}
+		if _, err := store.Update(ctx, model.ReportUpdate{ID: rep.ID, Template: testTemplate}); !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("Update of a report in the trash: got %v, want sql.ErrNoRows", err)
+		}
+
+		ids, err := store.ListDeletedBefore(ctx, time.Now().Add(time.Minute), 10)
+		if err != nil || !slices.Equal(ids, []int{rep.ID}) {
+			t.Errorf("ListDeletedBefore: got %v, %v, want [%d]", ids, err, rep.ID)
+		}
+		if ids, err := store.ListDeletedBefore(ctx, time.Now().Add(-time.Hour), 10); err != nil || len(ids) != 0 {
+			t.Errorf("ListDeletedBefore an hour ago: got %v, %v, want none", ids, err)
+		}
+
+		if err := store.Restore(ctx, rep.ID); err != nil {
+			t.Fatal(err)
+		}
+		if found, err := store.Filter(ctx, rep.ID, nil); err != nil || found == nil {
+			t.Errorf("Filter of a restored report: got %v, %v", found, err)
+		}
+
+		if err := store.HardDelete(ctx, rep.ID); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.HardDelete(ctx, rep.ID); !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("HardDelete of a missing report: got %v, want sql.ErrNoRows", err)
+		}
+	})
+}
@@ -0,0 +1,246 @@
+package repository
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reportia/database"
+	"reportia/model"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// ErrInvalidReportQuery is wrapped by errors caused by an unknown sort column
+// or a malformed cursor.
+var ErrInvalidReportQuery = errors.New("invalid report query")
+
+const defaultReportSort = "create_at"
+
+// reportSearchDocument is the text searched by the listing. On Postgres it
+// must match the expression of the idx_report_search index.
+const reportSearchDocument = "name || ' ' || description || ' ' || COALESCE(template, '')"
+
+// reportSortColumn maps a sort key accepted by the API to its SQL expression
+// and to the value it reads from a report, used to build cursors and to sort
+// in memory.
+type reportSortColumn struct {
+	expr  string
+	value func(rep model.Report) any
+}
+
+var reportSortColumns = map[string]reportSortColumn{
+	"id":        {expr: "id", value: func(rep model.Report) any { return rep.ID }},
+	"name":      {expr: "name", value: func(rep model.Report) any { return rep.Name }},
+	"category":  {expr: "category", value: func(rep model.Report) any { return rep.Category }},
+	"template":  {expr: "COALESCE(template, '')", value: func(rep model.Report) any { return rep.Template }},
+	"user_mail": {expr: "user_mail", value: func(rep model.Report) any { return rep.UserMail }},
+	"active":    {expr: "active", value: func(rep model.Report) any { return rep.Active }},
+	"data_source_id": {expr: "COALESCE(data_source_id, 0)", value: func(rep model.Report) any {
+		if rep.DataSourceID == nil {
+			return 0
+		}
+		return *rep.DataSourceID
+	}},
+	"create_at": {expr: "create_at", value: func(rep model.Report) any { return rep.CreateAt }},
+	"update_at": {expr: "update_at", value: func(rep model.Report) any { return rep.UpdateAt }},
+}
+
+// ReportSortKeys lists the columns reports can be sorted by.
+func ReportSortKeys() []string {
+	return []string{"id", "name", "category", "template", "user_mail", "active", "data_source_id", "create_at", "update_at"}
+}
+
+func reportSort(q model.ReportQuery) (string, reportSortColumn, error) {
+	key := q.SortBy
+	if key == "" {
+		key = defaultReportSort
+	}
+	column, ok := reportSortColumns[key]
+	if !ok {
+		return "", reportSortColumn{}, fmt.Errorf("%w: unknown sort column %q", ErrInvalidReportQuery, key)
+	}
+	return key, column, nil
+}
+
+// reportCursor points just after the last report of a page. It carries the
+// sort it was made for, so it cannot be replayed against another ordering.
+type reportCursor struct {
+	Sort  string `json:"s"`
+	Desc  bool   `json:"d"`
+	Value any    `json:"v"`
+	ID    int    `json:"id"`
+}
+
+func encodeReportCursor(key string, desc bool, column reportSortColumn, last model.Report) string {
+	value := column.value(last)
+	if t, ok := value.(time.Time); ok {
+		value = t.Format(time.RFC3339Nano)
+	}
+	raw, _ := json.Marshal(reportCursor{Sort: key, Desc: desc, Value: value, ID: last.ID})
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// decodeReportCursor returns the cursor with Value converted back to the type
+// the sort column's value function produces.
+func decodeReportCursor(encoded, key string, desc bool) (*reportCursor, error) {
+	invalid := fmt.Errorf("%w: malformed cursor", ErrInvalidReportQuery)
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, invalid
+	}
+	var cursor reportCursor
+	decoder := json.NewDecoder(strings.NewReader(string(raw)))
+	decoder.UseNumber()
+	if err := decoder.Decode(&cursor); err != nil {
+		return nil, invalid
+	}
+	if cursor.Sort != key || cursor.Desc != desc {
+		return nil, fmt.Errorf("%w: cursor was created for another sort order", ErrInvalidReportQuery)
+	}
+
+	switch key {
+	case "id", "data_source_id":
+		number, ok := cursor.Value.(json.Number)
+		if !ok {
+			return nil, invalid
+		}
+		value, err := strconv.Atoi(number.String())
+		if err != nil {
+			return nil, invalid
+		}
+		cursor.Value = value
+	case "active":
+		if _, ok := cursor.Value.(bool); !ok {
+			return nil, invalid
+		}
+	case "create_at", "update_at":
+		text, ok := cursor.Value.(string)
+		if !ok {
+			return nil, invalid
+		}
+		value, err := time.Parse(time.RFC3339Nano, text)
+		if err != nil {
+			return nil, invalid
+		}
+		cursor.Value = value
+	default:
+		if _, ok := cursor.Value.(string); !ok {
+			return nil, invalid
+		}
+	}
+	return &cursor, nil
+}
+
+// reportQueryBuilder accumulates the WHERE clause of a report listing and its
+// positional arguments.
+type reportQueryBuilder struct {
+	dialect    database.Dialect
+	conditions []string
+	args       []any
+}
+
+func (b *reportQueryBuilder) arg(value any) string {
+	if t, ok := value.(time.Time); ok {
+		value = b.dialect.Time(t)
+	}
+	b.args = append(b.args, value)
+	return "$" + strconv.Itoa(len(b.args))
+}
+
+func (b *reportQueryBuilder) where(condition string) {
+	b.conditions = append(b.conditions, condition)
+}
+
+func (b *reportQueryBuilder) whereClause() string {
+	if len(b.conditions) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(b.conditions, " AND ")
+}
+
+// filter adds the conditions of q, except for the cursor.
+func (b *reportQueryBuilder) filter(q model.ReportQuery) {
+	if q.Deleted {
+		b.where("deleted_at IS NOT NULL")
+	} else {
+		b.where("deleted_at IS NULL")
+	}
+	if q.ID != 0 {
+		b.where("id = " + b.arg(q.ID))
+	}
+	if q.Name != nil {
+		b.where("name = " + b.arg(*q.Name))
+	}
+	if search := strings.TrimSpace(q.Search); search != "" {
+		if b.dialect == database.SQLite {
+			for _, word := range searchWords(search) {
+				b.where("LOWER(" + reportSearchDocument + ") LIKE " + b.arg("%"+word+"%"))
+			}
+		} else {
+			b.where("to_tsvector('simple', " + reportSearchDocument + ") @@ plainto_tsquery('simple', " + b.arg(search) + ")")
+		}
+	}
+	for _, tag := range q.Tags {
+		b.where("',' || tags || ',' LIKE " + b.arg("%,"+escapeLike(tag)+",%") + ` ESCAPE '\'`)
+	}
+	if q.Category != nil {
+		b.where("category = " + b.arg(*q.Category))
+	}
+	if q.UserMail != nil {
+		b.where("user_mail = " + b.arg(*q.UserMail))
+	}
+	if q.Active != nil {
+		b.where("active = " + b.arg(*q.Active))
+	}
+	if q.CreatedFrom != nil {
+		b.where("create_at >= " + b.arg(*q.CreatedFrom))
+	}
+	if q.CreatedTo != nil {
+		b.where("create_at <= " + b.arg(*q.CreatedTo))
+	}
+	if q.UpdatedFrom != nil {
+		b.where("update_at >= " + b.arg(*q.UpdatedFrom))
+	}
+	if q.UpdatedTo != nil {
+		b.where("update_at <= " + b.arg(*q.UpdatedTo))
+	}
+}
+
+// searchWords splits a search into lowercase words, as plainto_tsquery does
+// on Postgres. Without full-text search, SQLite and the memory store require
+// every word to appear in the report, where Postgres also requires it to be
+// a whole word: "rep" finds "report" there but not on Postgres.
+func searchWords(search string) []string {
+	return strings.FieldsFunc(strings.ToLower(search), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// likeEscaper escapes the wildcards of LIKE patterns, and the escape
+// character itself, so that a value only matches literally.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+func escapeLike(value string) string {
+	return likeEscaper.Replace(value)
+}
+
+// after restricts the listing to the reports following the cursor, using the
+// id as tie-breaker so that paging is stable when sort values repeat.
+func (b *reportQueryBuilder) after(column reportSortColumn, desc bool, cursor *reportCursor) {
+	operator := ">"
+	if desc {
+		operator = "<"
+	}
+	b.where(fmt.Sprintf("(%s, id) %s (%s, %s)", column.expr, operator, b.arg(cursor.Value), b.arg(cursor.ID)))
+}
+
+func reportOrderBy(column reportSortColumn, desc bool) string {
+	direction := "ASC"
+	if desc {
+		direction = "DESC"
+	}
+	return fmt.Sprintf(" ORDER BY %s %s, id %s", column.expr, direction, direction)
+}
@@ -4,58 +4,94 @@ import (
 	"context"
 	"database/sql"
 	"errors"
-	"fmt"
+	"reportia/database"
 	"reportia/model"
+	"time"
 )
 
 var ErrReportInactive = errors.New("the report that you select was inactive")
 
+const reportColumns = `id, name, description, tags, category, COALESCE(template, ''), default_llm, default_model, default_prompt, thumbnail, allowed_file_types, user_mail, active, data_source_id, create_at, update_at, deleted_at`
+
 type ReportRepository struct {
-	db *sql.DB
+	db      *sql.DB
+	dialect database.Dialect
 }
 
-func NewReportRepository(db *sql.DB) *ReportRepository {
-	return &ReportRepository{db: db}
+func NewReportRepository(db *sql.DB, dialect database.Dialect) *ReportRepository {
+	return &ReportRepository{db: db, dialect: dialect}
 }
 
-func (r *ReportRepository) List(ctx context.Context) ([]model.Report, error) {
-	return r.ListWithPagination(ctx, 1, 1000) // Default behavior for backward compatibility
+func scanReport(row rowScanner) (*model.Report, error) {
+	var rep model.Report
+	var tags, allowedFileTypes string
+	if err := row.Scan(&rep.ID, &rep.Name, &rep.Description, &tags, &rep.Category, &rep.Template, &rep.DefaultLLM, &rep.DefaultModel, &rep.DefaultPrompt, &rep.Thumbnail, &allowedFileTypes, &rep.UserMail, &rep.Active, &rep.DataSourceID, &rep.CreateAt, &rep.UpdateAt, &rep.DeletedAt); err != nil {
+		return nil, err
+	}
+	rep.Tags = splitList(tags)
+	rep.AllowedFileTypes = splitList(allowedFileTypes)
+	return &rep, nil
 }
 
-func (r *ReportRepository) ListWithPagination(ctx context.Context, page, pageSize int) ([]model.Report, error) {
-	offset := (page - 1) * pageSize
+// Search returns one page of the reports matching q together with the total
+// number of matches and, when more reports follow, the cursor of the next page.
+func (r *ReportRepository) Search(ctx context.Context, q model.ReportQuery) ([]model.Report, int, string, error) {
+	key, column, err := reportSort(q)
+	if err != nil {
+		return nil, 0, "", err
+	}
 
-	rows, err := r.db.QueryContext(ctx,
-		`SELECT id, template, user_mail, active, create_at, update_at 
-		 FROM Report 
-		 ORDER BY create_at DESC 
-		 LIMIT $1 OFFSET $2`,
-		pageSize, offset)
+	builder := &reportQueryBuilder{dialect: r.dialect}
+	builder.filter(q)
+
+	var totalCount int
+	if err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM Report"+builder.whereClause(), builder.args...).Scan(&totalCount); err != nil {
+		return nil, 0, "", err
+	}
+
+	offset := (q.Page - 1) * q.PageSize
+	if q.Cursor != "" {
+		cursor, err := decodeReportCursor(q.Cursor, key, q.SortDesc)
+		if err != nil {
+			return nil, 0, "", err
+		}
+		builder.after(column, q.SortDesc, cursor)
+		offset = 0
+	}
+
+	// One extra row tells whether a next page exists.
+	selectQuery := "SELECT " + reportColumns + " FROM Report" + builder.whereClause() +
+		reportOrderBy(column, q.SortDesc) +
+		" LIMIT " + builder.arg(q.PageSize+1) + " OFFSET " + builder.arg(offset)
+
+	rows, err := r.db.QueryContext(ctx, selectQuery, builder.args...)
 	if err != nil {
-		return nil, err
+		return nil, 0, "", err
 	}
 	defer rows.Close()
 
 	var reports []model.Report = make([]model.Report, 0)
 	for rows.Next() {
-		var rep model.Report
-		if err := rows.Scan(&rep.ID, &rep.Template, &rep.UserMail, &rep.Active, &rep.CreateAt, &rep.UpdateAt); err != nil {
-			return nil, err
+		rep, err := scanReport(rows)
+		if err != nil {
+			return nil, 0, "", err
 		}
-		reports = append(reports, rep)
+		reports = append(reports, *rep)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, "", err
 	}
-	return reports, nil
-}
 
-func (r *ReportRepository) GetTotalCount(ctx context.Context) (int, error) {
-	var count int
-	err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM Report`).Scan(&count)
-	return count, err
+	var nextCursor string
+	if len(reports) > q.PageSize {
+		reports = reports[:q.PageSize]
+		nextCursor = encodeReportCursor(key, q.SortDesc, column, reports[len(reports)-1])
+	}
+	return reports, totalCount, nextCursor, nil
 }
 
 func (r *ReportRepository) Filter(ctx context.Context, id int, userMail *string) (*model.Report, error) {
-	var rep model.Report
-	query := `SELECT id, template, user_mail, active, create_at, update_at FROM Report WHERE id = $1`
+	query := `SELECT ` + reportColumns + ` FROM Report WHERE id = $1 AND deleted_at IS NULL`
 	args := []interface{}{id}
 
 	if userMail != nil {
@@ -63,8 +99,8 @@ func (r *ReportRepository) Filter(ctx context.Context, id int, userMail *string)
 		args = append(args, *userMail)
 	}
 
-	row := r.db.QueryRowContext(ctx, query, args...)
-	if err := row.Scan(&rep.ID, &rep.Template, &rep.UserMail, &rep.Active, &rep.CreateAt, &rep.UpdateAt); err != nil {
+	rep, err := scanReport(r.db.QueryRowContext(ctx, query, args...))
+	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
 		}
@@ -73,78 +109,119 @@ func (r *ReportRepository) Filter(ctx context.Context, id int, userMail *string)
 	if !rep.Active {
 		return nil, ErrReportInactive
 	}
-	return &rep, nil
+	return rep, nil
 }
 
-func (r *ReportRepository) FilterWithPagination(ctx context.Context, id int, userMail *string, page, pageSize int) ([]model.Report, int, error) {
-	offset := (page - 1) * pageSize
-
-	baseQuery := `FROM Report WHERE id = $1`
-	args := []interface{}{id}
+func (r *ReportRepository) Create(ctx context.Context, rep model.Report) (*model.Report, error) {
+	return scanReport(r.db.QueryRowContext(ctx,
+		`INSERT INTO Report (name, description, tags, category, template, default_llm, default_model, default_prompt, thumbnail, allowed_file_types, user_mail, active, data_source_id)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, true, $12)
+		 RETURNING `+reportColumns,
+		rep.Name, rep.Description, joinList(rep.Tags), rep.Category, rep.Template, rep.DefaultLLM, rep.DefaultModel, rep.DefaultPrompt, rep.Thumbnail, joinList(rep.AllowedFileTypes), rep.UserMail, rep.DataSourceID))
+}
 
-	if userMail != nil {
-		baseQuery += " AND user_mail = $2"
-		args = append(args, *userMail)
+func (r *ReportRepository) Update(ctx context.Context, upd model.ReportUpdate) (*model.Report, error) {
+	var tags *string
+	if upd.Tags != nil {
+		joined := joinList(*upd.Tags)
+		tags = &joined
 	}
-
-	// Get total count
-	var totalCount int
-	countQuery := "SELECT COUNT(*) " + baseQuery
-	err := r.db.QueryRowContext(ctx, countQuery, args...).Scan(&totalCount)
-	if err != nil {
-		return nil, 0, err
+	var allowedFileTypes *string
+	if upd.AllowedFileTypes != nil {
+		joined := joinList(*upd.AllowedFileTypes)
+		allowedFileTypes = &joined
 	}
+	return scanReport(r.db.QueryRowContext(ctx,
+		`UPDATE Report SET template = $1, data_source_id = $2,
+		     name = COALESCE($3, name), description = COALESCE($4, description), tags = COALESCE($5, tags),
+		     category = COALESCE($6, category), default_llm = COALESCE($7, default_llm), default_model = COALESCE($8, default_model),
+		     default_prompt = COALESCE($9, default_prompt), thumbnail = COALESCE($10, thumbnail),
+		     allowed_file_types = COALESCE($11, allowed_file_types), update_at = `+r.dialect.Now()+`
+		 WHERE id = $12 AND deleted_at IS NULL
+		 RETURNING `+reportColumns,
+		upd.Template, upd.DataSourceID, upd.Name, upd.Description, tags, upd.Category, upd.DefaultLLM, upd.DefaultModel, upd.DefaultPrompt, upd.Thumbnail, allowedFileTypes, upd.ID))
+}
 
-	// Get paginated results
-	selectQuery := "SELECT id, template, user_mail, active, create_at, update_at " +
-		baseQuery + " ORDER BY create_at DESC LIMIT $" +
-		fmt.Sprintf("%d", len(args)+1) + " OFFSET $" + fmt.Sprintf("%d", len(args)+2)
-	args = append(args, pageSize, offset)
+func (r *ReportRepository) TurnOnOff(ctx context.Context, id int, active bool) error {
+	return r.exec(ctx, `UPDATE Report SET active = $1 WHERE id = $2 AND deleted_at IS NULL`, active, id)
+}
+
+func (r *ReportRepository) SoftDelete(ctx context.Context, id int) error {
+	return r.exec(ctx, `UPDATE Report SET deleted_at = `+r.dialect.Now()+` WHERE id = $1 AND deleted_at IS NULL`, id)
+}
+
+func (r *ReportRepository) Restore(ctx context.Context, id int) error {
+	return r.exec(ctx, `UPDATE Report SET deleted_at = NULL, update_at = `+r.dialect.Now()+` WHERE id = $1 AND deleted_at IS NOT NULL`, id)
+}
 
-	rows, err := r.db.QueryContext(ctx, selectQuery, args...)
+// reportDependents deletes, in foreign key order, the rows that reference a
+// report ($1) directly or through its generations and schedules. Assets are
+// listed although they cascade, since SQLite does not enforce foreign keys by
+// default.
+var reportDependents = []string{
+	`DELETE FROM ShareLink WHERE generation_id IN (SELECT id FROM Generation WHERE report_id = $1)`,
+	`DELETE FROM Delivery WHERE generation_id IN (SELECT id FROM Generation WHERE report_id = $1)
+	     OR rule_id IN (SELECT id FROM DeliveryRule WHERE report_id = $1 OR schedule_id IN (SELECT id FROM Schedule WHERE report_id = $1))`,
+	`DELETE FROM Generation WHERE report_id = $1`,
+	`DELETE FROM DeliveryRule WHERE report_id = $1 OR schedule_id IN (SELECT id FROM Schedule WHERE report_id = $1)`,
+	`DELETE FROM Schedule WHERE report_id = $1`,
+	`DELETE FROM ReportAsset WHERE report_id = $1`,
+}
+
+// HardDelete removes a report for good together with its schedules,
+// generations, delivery rules, deliveries and share links.
+func (r *ReportRepository) HardDelete(ctx context.Context, id int) error {
+	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
-		return nil, 0, err
+		return err
 	}
-	defer rows.Close()
+	defer tx.Rollback()
 
-	var reports []model.Report = make([]model.Report, 0)
-	for rows.Next() {
-		var rep model.Report
-		if err := rows.Scan(&rep.ID, &rep.Template, &rep.UserMail, &rep.Active, &rep.CreateAt, &rep.UpdateAt); err != nil {
-			return nil, 0, err
+	for _, query := range reportDependents {
+		if _, err := tx.ExecContext(ctx, query, id); err != nil {
+			return err
 		}
-		if !rep.Active {
-			return nil, 0, ErrReportInactive
-		}
-		reports = append(reports, rep)
 	}
-
-	return reports, totalCount, nil
-}
-
-func (r *ReportRepository) Create(ctx context.Context, template, userMail string) (*model.Report, error) {
-	var rep model.Report
-	err := r.db.QueryRowContext(ctx, `INSERT INTO Report (template, user_mail, active) VALUES ($1, $2, true) RETURNING id, template, user_mail, active, create_at, update_at`, template, userMail).Scan(&rep.ID, &rep.Template, &rep.UserMail, &rep.Active, &rep.CreateAt, &rep.UpdateAt)
+	res, err := tx.ExecContext(ctx, `DELETE FROM Report WHERE id = $1`, id)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	return &rep, nil
+	if err := affectedOne(res); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
-func (r *ReportRepository) Update(ctx context.Context, id int, template string) (*model.Report, error) {
-	var rep model.Report
-	err := r.db.QueryRowContext(ctx, `UPDATE Report SET template = $1, update_at = CURRENT_DATE WHERE id = $2 RETURNING id, template, user_mail, active, create_at, update_at`, template, id).Scan(&rep.ID, &rep.Template, &rep.UserMail, &rep.Active, &rep.CreateAt, &rep.UpdateAt)
+func (r *ReportRepository) ListDeletedBefore(ctx context.Context, before time.Time, limit int) ([]int, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT id FROM Report WHERE deleted_at IS NOT NULL AND deleted_at < $1 ORDER BY deleted_at, id LIMIT $2`,
+		r.dialect.Time(before), limit)
 	if err != nil {
 		return nil, err
 	}
-	return &rep, nil
+	defer rows.Close()
+
+	ids := make([]int, 0)
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
 }
 
-func (r *ReportRepository) TurnOnOff(ctx context.Context, id int, active bool) error {
-	res, err := r.db.ExecContext(ctx, `UPDATE Report SET active = $1 WHERE id = $2`, active, id)
+func (r *ReportRepository) exec(ctx context.Context, query string, args ...any) error {
+	res, err := r.db.ExecContext(ctx, query, args...)
 	if err != nil {
 		return err
 	}
+	return affectedOne(res)
+}
+
+// affectedOne returns sql.ErrNoRows when the statement changed no row.
+func affectedOne(res sql.Result) error {
 	n, err := res.RowsAffected()
 	if err != nil {
 		return err
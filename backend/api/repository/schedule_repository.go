@@ -0,0 +1,113 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"reportia/model"
+	"time"
+)
+
+const scheduleColumns = `id, report_id, name, cron_expression, timezone, llm, model, prompt, COALESCE(source_path, ''), data_source_id, user_mail, active, next_run_at, last_run_at, create_at, update_at`
+
+type ScheduleRepository struct {
+	db *sql.DB
+}
+
+func NewScheduleRepository(db *sql.DB) *ScheduleRepository {
+	return &ScheduleRepository{db: db}
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanSchedule(row rowScanner) (*model.Schedule, error) {
+	var sch model.Schedule
+	err := row.Scan(&sch.ID, &sch.ReportID, &sch.Name, &sch.CronExpression, &sch.Timezone, &sch.LLM, &sch.Model, &sch.Prompt, &sch.SourcePath, &sch.DataSourceID, &sch.UserMail, &sch.Active, &sch.NextRunAt, &sch.LastRunAt, &sch.CreateAt, &sch.UpdateAt)
+	if err != nil {
+		return nil, err
+	}
+	return &sch, nil
+}
+
+func (r *ScheduleRepository) querySchedules(ctx context.Context, query string, args ...any) ([]model.Schedule, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	schedules := make([]model.Schedule, 0)
+	for rows.Next() {
+		sch, err := scanSchedule(rows)
+		if err != nil {
+			return nil, err
+		}
+		schedules = append(schedules, *sch)
+	}
+	return schedules, rows.Err()
+}
+
+func (r *ScheduleRepository) List(ctx context.Context) ([]model.Schedule, error) {
+	return r.querySchedules(ctx, `SELECT `+scheduleColumns+` FROM Schedule ORDER BY create_at DESC`)
+}
+
+// ListDue skips the schedules of reports in the trash; they run again once
+// the report is restored.
+func (r *ScheduleRepository) ListDue(ctx context.Context, now time.Time) ([]model.Schedule, error) {
+	return r.querySchedules(ctx,
+		`SELECT `+scheduleColumns+` FROM Schedule
+		 WHERE active = true AND next_run_at IS NOT NULL AND next_run_at <= $1
+		   AND report_id NOT IN (SELECT id FROM Report WHERE deleted_at IS NOT NULL)
+		 ORDER BY next_run_at`,
+		now)
+}
+
+func (r *ScheduleRepository) GetByID(ctx context.Context, id int) (*model.Schedule, error) {
+	sch, err := scanSchedule(r.db.QueryRowContext(ctx, `SELECT `+scheduleColumns+` FROM Schedule WHERE id = $1`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return sch, err
+}
+
+func (r *ScheduleRepository) Create(ctx context.Context, sch model.Schedule) (*model.Schedule, error) {
+	return scanSchedule(r.db.QueryRowContext(ctx,
+		`INSERT INTO Schedule (report_id, name, cron_expression, timezone, llm, model, prompt, source_path, data_source_id, user_mail, active, next_run_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, ''), $9, $10, true, $11)
+		 RETURNING `+scheduleColumns,
+		sch.ReportID, sch.Name, sch.CronExpression, sch.Timezone, sch.LLM, sch.Model, sch.Prompt, sch.SourcePath, sch.DataSourceID, sch.UserMail, sch.NextRunAt))
+}
+
+func (r *ScheduleRepository) Update(ctx context.Context, sch model.Schedule) (*model.Schedule, error) {
+	updated, err := scanSchedule(r.db.QueryRowContext(ctx,
+		`UPDATE Schedule
+		 SET name = $1, cron_expression = $2, timezone = $3, llm = $4, model = $5, prompt = $6, source_path = NULLIF($7, ''), data_source_id = $8, next_run_at = $9, update_at = NOW()
+		 WHERE id = $10
+		 RETURNING `+scheduleColumns,
+		sch.Name, sch.CronExpression, sch.Timezone, sch.LLM, sch.Model, sch.Prompt, sch.SourcePath, sch.DataSourceID, sch.NextRunAt, sch.ID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return updated, err
+}
+
+func (r *ScheduleRepository) TurnOnOff(ctx context.Context, id int, active bool, nextRunAt *time.Time) error {
+	res, err := r.db.ExecContext(ctx, `UPDATE Schedule SET active = $1, next_run_at = $2, update_at = NOW() WHERE id = $3`, active, nextRunAt, id)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *ScheduleRepository) MarkRun(ctx context.Context, id int, ranAt time.Time, nextRunAt *time.Time) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE Schedule SET last_run_at = $1, next_run_at = $2 WHERE id = $3`, ranAt, nextRunAt, id)
+	return err
+}
@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"reportia/database"
+	"reportia/model"
+	"time"
+)
+
+const shareLinkColumns = `id, generation_id, token_hash, COALESCE(password_hash, ''), expires_at, max_views, view_count, user_mail, revoked_at, create_at`
+
+type ShareRepository struct {
+	db      *sql.DB
+	dialect database.Dialect
+}
+
+func NewShareRepository(db *sql.DB, dialect database.Dialect) *ShareRepository {
+	return &ShareRepository{db: db, dialect: dialect}
+}
+
+func scanShareLink(row rowScanner) (*model.ShareLink, error) {
+	var link model.ShareLink
+	if err := row.Scan(&link.ID, &link.GenerationID, &link.TokenHash, &link.PasswordHash, &link.ExpiresAt, &link.MaxViews, &link.ViewCount, &link.UserMail, &link.RevokedAt, &link.CreateAt); err != nil {
+		return nil, err
+	}
+	link.PasswordProtected = link.PasswordHash != ""
+	return &link, nil
+}
+
+func (r *ShareRepository) Create(ctx context.Context, link model.ShareLink) (*model.ShareLink, error) {
+	return scanShareLink(r.db.QueryRowContext(ctx,
+		`INSERT INTO ShareLink (generation_id, token_hash, password_hash, expires_at, max_views, user_mail)
+		 VALUES ($1, $2, NULLIF($3, ''), $4, $5, $6)
+		 RETURNING `+shareLinkColumns,
+		link.GenerationID, link.TokenHash, link.PasswordHash, r.dialect.Time(link.ExpiresAt), link.MaxViews, link.UserMail))
+}
+
+func (r *ShareRepository) GetByID(ctx context.Context, id int) (*model.ShareLink, error) {
+	link, err := scanShareLink(r.db.QueryRowContext(ctx, `SELECT `+shareLinkColumns+` FROM ShareLink WHERE id = $1`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return link, err
+}
+
+func (r *ShareRepository) ListByGeneration(ctx context.Context, generationID int) ([]model.ShareLink, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+shareLinkColumns+` FROM ShareLink WHERE generation_id = $1 ORDER BY create_at DESC`, generationID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	links := make([]model.ShareLink, 0)
+	for rows.Next() {
+		link, err := scanShareLink(rows)
+		if err != nil {
+			return nil, err
+		}
+		links = append(links, *link)
+	}
+	return links, rows.Err()
+}
+
+// RegisterView atomically counts a view and clears the failed password
+// attempts, and reports false when the link is revoked, expired or has used
+// up its views.
+func (r *ShareRepository) RegisterView(ctx context.Context, id int, now time.Time) (bool, error) {
+	return r.updated(r.db.ExecContext(ctx,
+		`UPDATE ShareLink SET view_count = view_count + 1, failed_attempts = 0, locked_until = NULL
+		 WHERE id = $1 AND revoked_at IS NULL AND expires_at > $2 AND (max_views IS NULL OR view_count < max_views)`,
+		id, r.dialect.Time(now)))
+}
+
+// ClaimPasswordAttempt counts a password attempt as failed until
+// RegisterView clears it, and reports false while the link is locked. The
+// attempt that reaches maxAttempts locks the link until lockedUntil, as does
+// every failed attempt after it, so that concurrent guesses are counted too.
+func (r *ShareRepository) ClaimPasswordAttempt(ctx context.Context, id int, now time.Time, maxAttempts int, lockedUntil time.Time) (bool, error) {
+	return r.updated(r.db.ExecContext(ctx,
+		`UPDATE ShareLink SET failed_attempts = failed_attempts + 1,
+		        locked_until = CASE WHEN failed_attempts + 1 >= $3 THEN $4 ELSE NULL END
+		 WHERE id = $1 AND (locked_until IS NULL OR locked_until <= $2)`,
+		id, r.dialect.Time(now), maxAttempts, r.dialect.Time(lockedUntil)))
+}
+
+func (r *ShareRepository) updated(res sql.Result, err error) (bool, error) {
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
+
+func (r *ShareRepository) Revoke(ctx context.Context, id int) error {
+	res, err := r.db.ExecContext(ctx, `UPDATE ShareLink SET revoked_at = `+r.dialect.Now()+` WHERE id = $1 AND revoked_at IS NULL`, id)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"context"
+	"reportia/model"
+	"time"
+)
+
+// ReportStore is the storage used by the report service. ReportRepository
+// implements it on top of Postgres and MemoryReportStore keeps everything in
+// process memory.
+type ReportStore interface {
+	// Search returns one page of matching reports, the total number of
+	// matches and the cursor of the next page, empty on the last page. Errors
+	// caused by the query itself wrap ErrInvalidReportQuery.
+	Search(ctx context.Context, q model.ReportQuery) ([]model.Report, int, string, error)
+	// Filter returns nil when no report matches and ErrReportInactive when the
+	// matching report is turned off.
+	Filter(ctx context.Context, id int, userMail *string) (*model.Report, error)
+	Create(ctx context.Context, rep model.Report) (*model.Report, error)
+	// Update and TurnOnOff return sql.ErrNoRows when the report does not
+	// exist or is in the trash.
+	Update(ctx context.Context, upd model.ReportUpdate) (*model.Report, error)
+	TurnOnOff(ctx context.Context, id int, active bool) error
+	// SoftDelete moves a report to the trash and Restore takes it back out.
+	// They return sql.ErrNoRows when no report is in the expected state.
+	SoftDelete(ctx context.Context, id int) error
+	Restore(ctx context.Context, id int) error
+	// HardDelete removes a report, in the trash or not, with everything
+	// referencing it. It returns sql.ErrNoRows when the report does not exist.
+	HardDelete(ctx context.Context, id int) error
+	// ListDeletedBefore returns the ids of at most limit reports moved to the
+	// trash before the given time, oldest first.
+	ListDeletedBefore(ctx context.Context, before time.Time, limit int) ([]int, error)
+}
+
+// GenerationStore keeps the history of generations.
+type GenerationStore interface {
+	Start(ctx context.Context, reportID int, scheduleID *int, llm, model string) (*model.Generation, error)
+	Finish(ctx context.Context, id int, status, output, errorMessage string) error
+	ListBySchedule(ctx context.Context, scheduleID, limit int) ([]model.Generation, error)
+	// GetByID returns nil when the generation does not exist.
+	GetByID(ctx context.Context, id int) (*model.Generation, error)
+}
+
+var (
+	_ ReportStore     = (*ReportRepository)(nil)
+	_ ReportStore     = (*MemoryReportStore)(nil)
+	_ GenerationStore = (*GenerationRepository)(nil)
+	_ GenerationStore = (*MemoryGenerationStore)(nil)
+)
@@ -0,0 +1,263 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"reportia/model"
+	"time"
+)
+
+const webhookSubscriptionColumns = `id, url, secret, event_types, user_mail, active, create_at, update_at`
+const webhookEventColumns = `id, subscription_id, event_type, payload, status, attempts, next_attempt_at, COALESCE(last_error, ''), create_at, delivered_at`
+
+type WebhookRepository struct {
+	db *sql.DB
+}
+
+func NewWebhookRepository(db *sql.DB) *WebhookRepository {
+	return &WebhookRepository{db: db}
+}
+
+func scanWebhookSubscription(row rowScanner) (*model.WebhookSubscription, error) {
+	var sub model.WebhookSubscription
+	var eventTypes string
+	if err := row.Scan(&sub.ID, &sub.URL, &sub.Secret, &eventTypes, &sub.UserMail, &sub.Active, &sub.CreateAt, &sub.UpdateAt); err != nil {
+		return nil, err
+	}
+	sub.EventTypes = splitList(eventTypes)
+	return &sub, nil
+}
+
+func scanWebhookEvent(row rowScanner) (*model.WebhookEvent, error) {
+	var event model.WebhookEvent
+	var payload []byte
+	if err := row.Scan(&event.ID, &event.SubscriptionID, &event.EventType, &payload, &event.Status, &event.Attempts, &event.NextAttemptAt, &event.LastError, &event.CreateAt, &event.DeliveredAt); err != nil {
+		return nil, err
+	}
+	event.Payload = payload
+	event.AttemptLog = make([]model.WebhookAttempt, 0)
+	return &event, nil
+}
+
+func (r *WebhookRepository) querySubscriptions(ctx context.Context, query string, args ...any) ([]model.WebhookSubscription, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	subscriptions := make([]model.WebhookSubscription, 0)
+	for rows.Next() {
+		sub, err := scanWebhookSubscription(rows)
+		if err != nil {
+			return nil, err
+		}
+		subscriptions = append(subscriptions, *sub)
+	}
+	return subscriptions, rows.Err()
+}
+
+func (r *WebhookRepository) ListSubscriptions(ctx context.Context) ([]model.WebhookSubscription, error) {
+	return r.querySubscriptions(ctx, `SELECT `+webhookSubscriptionColumns+` FROM WebhookSubscription ORDER BY create_at DESC`)
+}
+
+func (r *WebhookRepository) ListActiveSubscriptionsFor(ctx context.Context, eventType string) ([]model.WebhookSubscription, error) {
+	return r.querySubscriptions(ctx,
+		`SELECT `+webhookSubscriptionColumns+` FROM WebhookSubscription
+		 WHERE active = true AND ',' || event_types || ',' LIKE '%,' || $1 || ',%'`,
+		eventType)
+}
+
+func (r *WebhookRepository) CreateSubscription(ctx context.Context, sub model.WebhookSubscription) (*model.WebhookSubscription, error) {
+	return scanWebhookSubscription(r.db.QueryRowContext(ctx,
+		`INSERT INTO WebhookSubscription (url, secret, event_types, user_mail, active)
+		 VALUES ($1, $2, $3, $4, true)
+		 RETURNING `+webhookSubscriptionColumns,
+		sub.URL, sub.Secret, joinList(sub.EventTypes), sub.UserMail))
+}
+
+func (r *WebhookRepository) UpdateSubscription(ctx context.Context, sub model.WebhookSubscription) (*model.WebhookSubscription, error) {
+	updated, err := scanWebhookSubscription(r.db.QueryRowContext(ctx,
+		`UPDATE WebhookSubscription SET url = $1, event_types = $2, update_at = NOW()
+		 WHERE id = $3
+		 RETURNING `+webhookSubscriptionColumns,
+		sub.URL, joinList(sub.EventTypes), sub.ID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return updated, err
+}
+
+func (r *WebhookRepository) TurnOnOffSubscription(ctx context.Context, id int, active bool) error {
+	res, err := r.db.ExecContext(ctx, `UPDATE WebhookSubscription SET active = $1, update_at = NOW() WHERE id = $2`, active, id)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// Enqueue writes one outbox row per subscription in a single transaction.
+func (r *WebhookRepository) Enqueue(ctx context.Context, eventType string, payload json.RawMessage, subscriptionIDs []int) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, subscriptionID := range subscriptionIDs {
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO WebhookEvent (subscription_id, event_type, payload) VALUES ($1, $2, $3)`,
+			subscriptionID, eventType, []byte(payload)); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// ClaimDue leases up to limit pending events by pushing their next attempt
+// past the lease, so concurrent dispatchers on other replicas skip them and a
+// crashed dispatcher's events become due again once the lease expires.
+// Pending events of inactive subscriptions are marked skipped instead, so
+// that turning a subscription off also stops its retries.
+func (r *WebhookRepository) ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]model.PendingWebhook, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE WebhookEvent SET status = $1, last_error = 'subscription inactive'
+		 WHERE status = 'pending'
+		   AND subscription_id IN (SELECT id FROM WebhookSubscription WHERE active = false)`,
+		model.WebhookEventSkipped); err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.QueryContext(ctx,
+		`SELECT e.id, e.subscription_id, e.event_type, e.payload, e.status, e.attempts, e.next_attempt_at, COALESCE(e.last_error, ''), e.create_at, e.delivered_at, s.url, s.secret
+		 FROM WebhookEvent e
+		 JOIN WebhookSubscription s ON s.id = e.subscription_id
+		 WHERE e.status = 'pending' AND e.next_attempt_at <= $1 AND s.active = true
+		 ORDER BY e.id
+		 LIMIT $2
+		 FOR UPDATE OF e SKIP LOCKED`,
+		now, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	pending := make([]model.PendingWebhook, 0)
+	for rows.Next() {
+		var item model.PendingWebhook
+		var payload []byte
+		if err := rows.Scan(&item.Event.ID, &item.Event.SubscriptionID, &item.Event.EventType, &payload, &item.Event.Status, &item.Event.Attempts, &item.Event.NextAttemptAt, &item.Event.LastError, &item.Event.CreateAt, &item.Event.DeliveredAt, &item.URL, &item.Secret); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		item.Event.Payload = payload
+		pending = append(pending, item)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, item := range pending {
+		if _, err := tx.ExecContext(ctx, `UPDATE WebhookEvent SET next_attempt_at = $1 WHERE id = $2`, now.Add(lease), item.Event.ID); err != nil {
+			return nil, err
+		}
+	}
+	return pending, tx.Commit()
+}
+
+// CountPending returns how many events are waiting to be delivered.
+func (r *WebhookRepository) CountPending(ctx context.Context) (int, error) {
+	var count int
+	err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM WebhookEvent WHERE status = 'pending'`).Scan(&count)
+	return count, err
+}
+
+// RecordAttempt logs one HTTP attempt and moves the event to its next state.
+func (r *WebhookRepository) RecordAttempt(ctx context.Context, attempt model.WebhookAttempt, status string, nextAttemptAt time.Time) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO WebhookAttempt (event_id, status_code, error, duration_ms) VALUES ($1, $2, NULLIF($3, ''), $4)`,
+		attempt.EventID, attempt.StatusCode, attempt.Error, attempt.DurationMs); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE WebhookEvent
+		 SET status = $1, attempts = attempts + 1, next_attempt_at = $2, last_error = NULLIF($3, ''),
+		     delivered_at = CASE WHEN $1 = 'delivered' THEN NOW() ELSE delivered_at END
+		 WHERE id = $4`,
+		status, nextAttemptAt, attempt.Error, attempt.EventID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ListEvents returns the most recent outbox events of a subscription with
+// their attempt log.
+func (r *WebhookRepository) ListEvents(ctx context.Context, subscriptionID, limit int) ([]model.WebhookEvent, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT `+webhookEventColumns+` FROM WebhookEvent WHERE subscription_id = $1 ORDER BY create_at DESC LIMIT $2`,
+		subscriptionID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := make([]model.WebhookEvent, 0)
+	indexByID := make(map[int]int)
+	for rows.Next() {
+		event, err := scanWebhookEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		indexByID[event.ID] = len(events)
+		events = append(events, *event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(events) == 0 {
+		return events, nil
+	}
+
+	attemptRows, err := r.db.QueryContext(ctx,
+		`SELECT a.id, a.event_id, a.status_code, COALESCE(a.error, ''), a.duration_ms, a.create_at
+		 FROM WebhookAttempt a
+		 JOIN WebhookEvent e ON e.id = a.event_id
+		 WHERE e.subscription_id = $1 AND e.create_at >= $2
+		 ORDER BY a.id`,
+		subscriptionID, events[len(events)-1].CreateAt)
+	if err != nil {
+		return nil, err
+	}
+	defer attemptRows.Close()
+
+	for attemptRows.Next() {
+		var attempt model.WebhookAttempt
+		if err := attemptRows.Scan(&attempt.ID, &attempt.EventID, &attempt.StatusCode, &attempt.Error, &attempt.DurationMs, &attempt.CreateAt); err != nil {
+			return nil, err
+		}
+		if i, ok := indexByID[attempt.EventID]; ok {
+			events[i].AttemptLog = append(events[i].AttemptLog, attempt)
+		}
+	}
+	return events, attemptRows.Err()
+}
@@ -0,0 +1,56 @@
+package scheduler
+
+import (
+	"context"
+	"log/slog"
+	"reportia/service"
+	"sync"
+	"time"
+)
+
+// RetentionJob purges reports that stayed in the trash longer than the
+// retention period. Purging is idempotent, so every replica may run one.
+type RetentionJob struct {
+	service   *service.ReportService
+	retention time.Duration
+	interval  time.Duration
+
+	wg     sync.WaitGroup
+	cancel context.CancelFunc
+}
+
+func NewRetentionJob(service *service.ReportService, retention, interval time.Duration) *RetentionJob {
+	if interval <= 0 {
+		interval = time.Hour
+	}
+	return &RetentionJob{service: service, retention: retention, interval: interval}
+}
+
+func (j *RetentionJob) Start(ctx context.Context) {
+	ctx, j.cancel = context.WithCancel(ctx)
+
+	j.wg.Add(1)
+	go func() {
+		defer j.wg.Done()
+		ticker := time.NewTicker(j.interval)
+		defer ticker.Stop()
+		for {
+			if _, err := j.service.PurgeDeleted(ctx, j.retention); err != nil && ctx.Err() == nil {
+				slog.ErrorContext(ctx, "retention: could not purge deleted reports", "error", err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop cancels the job and waits for the current purge to finish.
+func (j *RetentionJob) Stop() {
+	if j.cancel != nil {
+		j.cancel()
+	}
+	j.wg.Wait()
+}
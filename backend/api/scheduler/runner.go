@@ -0,0 +1,117 @@
+package scheduler
+
+import (
+	"context"
+	"log/slog"
+	"reportia/logging"
+	"reportia/metrics"
+	"reportia/model"
+	"reportia/repository"
+	"reportia/service"
+	"sync"
+	"time"
+)
+
+// scheduleLockNamespace is the first key of the advisory lock pair; the
+// schedule id is the second, so locks never collide with other subsystems.
+const scheduleLockNamespace int32 = 26
+
+type Runner struct {
+	service  *service.ScheduleService
+	locker   *repository.AdvisoryLocker
+	interval time.Duration
+
+	wg     sync.WaitGroup
+	cancel context.CancelFunc
+}
+
+func NewRunner(service *service.ScheduleService, locker *repository.AdvisoryLocker, interval time.Duration) *Runner {
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	return &Runner{service: service, locker: locker, interval: interval}
+}
+
+// Start polls for due schedules in the background until Stop is called. Safe
+// to run on every replica: each schedule is executed by whichever replica wins
+// its lock.
+func (r *Runner) Start(ctx context.Context) {
+	ctx, r.cancel = context.WithCancel(ctx)
+	slog.Info("scheduler running", "interval", r.interval.String())
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+		for {
+			r.tick(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop cancels polling and waits for in-flight runs to finish.
+func (r *Runner) Stop() {
+	if r.cancel != nil {
+		r.cancel()
+	}
+	r.wg.Wait()
+}
+
+var scheduleQueue = metrics.QueueDepth.WithLabelValues("schedules")
+
+func (r *Runner) tick(ctx context.Context) {
+	due, err := r.service.ListDue(ctx, time.Now())
+	if err != nil {
+		slog.ErrorContext(ctx, "scheduler: could not list due schedules", "error", err)
+		return
+	}
+	// Runs outlive a Stop so that in-flight generations can finish.
+	runCtx := context.WithoutCancel(ctx)
+	for _, sch := range due {
+		r.wg.Add(1)
+		scheduleQueue.Inc()
+		go func(sch model.Schedule) {
+			defer r.wg.Done()
+			defer scheduleQueue.Dec()
+			// Each run gets its own request ID to follow it into the
+			// service and LLM logs.
+			r.runIfLeader(logging.WithRequestID(runCtx, logging.NewRequestID()), sch.ID)
+		}(sch)
+	}
+}
+
+func (r *Runner) runIfLeader(ctx context.Context, id int) {
+	release, ok, err := r.locker.TryLock(ctx, scheduleLockNamespace, int32(id))
+	if err != nil {
+		slog.ErrorContext(ctx, "scheduler: could not lock schedule", "schedule_id", id, "error", err)
+		return
+	}
+	if !ok {
+		return
+	}
+	defer release()
+
+	// Another replica may have finished this occurrence between our listing
+	// and acquiring the lock, so re-check against the current row.
+	sch, err := r.service.Get(ctx, id)
+	if err != nil {
+		slog.ErrorContext(ctx, "scheduler: could not reload schedule", "schedule_id", id, "error", err)
+		return
+	}
+	if sch == nil || !sch.Active || sch.NextRunAt == nil || sch.NextRunAt.After(time.Now()) {
+		return
+	}
+
+	gen, err := r.service.Run(ctx, *sch)
+	if err != nil {
+		slog.ErrorContext(ctx, "scheduler: schedule failed", "schedule_id", id, "error", err)
+		return
+	}
+	slog.InfoContext(ctx, "scheduler: schedule produced generation", "schedule_id", id, "generation_id", gen.ID)
+}
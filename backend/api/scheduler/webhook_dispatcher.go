@@ -0,0 +1,83 @@
+package scheduler
+
+import (
+	"context"
+	"log/slog"
+	"reportia/metrics"
+	"reportia/service"
+	"sync"
+	"time"
+)
+
+// WebhookDispatcher drains the webhook outbox. Events are leased row by row,
+// so every replica can run a dispatcher without sending an event twice.
+type WebhookDispatcher struct {
+	service  *service.WebhookService
+	interval time.Duration
+
+	wg     sync.WaitGroup
+	cancel context.CancelFunc
+}
+
+func NewWebhookDispatcher(service *service.WebhookService, interval time.Duration) *WebhookDispatcher {
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	return &WebhookDispatcher{service: service, interval: interval}
+}
+
+func (d *WebhookDispatcher) Start(ctx context.Context) {
+	ctx, d.cancel = context.WithCancel(ctx)
+
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		ticker := time.NewTicker(d.interval)
+		defer ticker.Stop()
+		for {
+			d.drain(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop cancels polling and waits for the current batch to finish.
+func (d *WebhookDispatcher) Stop() {
+	if d.cancel != nil {
+		d.cancel()
+	}
+	d.wg.Wait()
+}
+
+// drain keeps dispatching full batches so a backlog clears without waiting
+// for the next tick.
+func (d *WebhookDispatcher) drain(ctx context.Context) {
+	defer d.exportPending(ctx)
+	for ctx.Err() == nil {
+		sent, err := d.service.DispatchDue(context.WithoutCancel(ctx))
+		if err != nil {
+			slog.ErrorContext(ctx, "webhook: could not dispatch outbox", "error", err)
+			return
+		}
+		if sent == 0 {
+			return
+		}
+	}
+}
+
+var webhookQueue = metrics.QueueDepth.WithLabelValues("webhooks")
+
+// exportPending publishes the size of the outbox, including the events left
+// for later attempts.
+func (d *WebhookDispatcher) exportPending(ctx context.Context) {
+	pending, err := d.service.Pending(context.WithoutCancel(ctx))
+	if err != nil {
+		slog.WarnContext(ctx, "webhook: could not count pending events", "error", err)
+		return
+	}
+	webhookQueue.Set(float64(pending))
+}
@@ -1,44 +1,312 @@
 package server
 
 import (
+	"context"
 	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"reportia/database"
+	"reportia/datasource"
 	"reportia/handler"
+	"reportia/integration/mail"
+	"reportia/integration/renderer"
+	"reportia/integration/storage"
+	"reportia/integration/webhook"
+	"reportia/metrics"
+	"reportia/middleware"
+	"reportia/migrations"
+	"reportia/netguard"
 	"reportia/repository"
+	"reportia/scheduler"
 	"reportia/service"
+	"time"
 
 	_ "reportia/docs"
 
 	"github.com/gorilla/mux"
-	_ "github.com/lib/pq"
 	httpSwagger "github.com/swaggo/http-swagger"
 )
 
-func (s *Server) RegisterRoutes() {
+func (s *Server) RegisterRoutes() error {
 	s.router.PathPrefix("/swagger/").Handler(httpSwagger.WrapHandler)
 	s.registerHealthRoutes()
+	s.router.Handle("/metrics", metrics.Handler()).Methods("GET")
+	api := s.router.PathPrefix("/api/v1").Subrouter()
+
+	if s.db == nil {
+		s.registerInMemoryRoutes(api)
+		return nil
+	}
 
-	db, err := sql.Open("postgres", s.cfg.DbURL)
+	db, dialect := s.db.DB, s.db.Dialect
+	metrics.RegisterDB(db, string(dialect))
+	if s.cfg.Database.MigrateOnStart {
+		if err := migrate(db, dialect); err != nil {
+			return fmt.Errorf("could not migrate the database: %w", err)
+		}
+	}
+	if dialect == database.SQLite {
+		return s.registerSQLiteRoutes(api, db)
+	}
+
+	generationRepository := repository.NewGenerationRepository(db, dialect)
+	mailer := mail.NewSMTPMailer(mail.SMTPConfig{
+		Host:     s.cfg.SMTP.Host,
+		Port:     s.cfg.SMTP.Port,
+		Username: s.cfg.SMTP.Username,
+		Password: s.cfg.SMTP.Password,
+		From:     s.cfg.SMTP.From,
+		TLSMode:  s.cfg.SMTP.TLSMode,
+	})
+	headlessRenderer := renderer.NewRenderer(s.cfg.Renderer.ChromePath)
+	s.deliveries = service.NewDeliveryService(repository.NewDeliveryRepository(db), mailer, headlessRenderer, service.DeliveryOptions{
+		DryRun:      s.cfg.Delivery.DryRun,
+		MaxAttempts: s.cfg.Delivery.MaxAttempts,
+	})
+	webhookNetwork := netguard.Policy{
+		AllowedHosts: s.cfg.Webhooks.AllowedHosts,
+		AllowPrivate: s.cfg.Webhooks.AllowPrivateNetworks,
+	}
+	webhookService := service.NewWebhookService(repository.NewWebhookRepository(db), webhook.NewSender(10*time.Second, webhookNetwork), service.WebhookOptions{
+		MaxAttempts: s.cfg.Webhooks.MaxAttempts,
+		Network:     webhookNetwork,
+	})
+	s.webhooks = scheduler.NewWebhookDispatcher(webhookService, s.cfg.Webhooks.DispatchInterval)
+	dataSourceService := service.NewDataSourceService(repository.NewDataSourceRepository(db), datasource.Policy{
+		DirectoryRoot: s.cfg.DataSources.DirectoryRoot,
+		Network: netguard.Policy{
+			AllowedHosts: s.cfg.DataSources.AllowedHosts,
+			AllowPrivate: s.cfg.DataSources.AllowPrivateNetworks,
+		},
+	})
+	reportRepository := repository.NewReportRepository(db, dialect)
+	assetService, err := s.newAssetService(db, dialect, reportRepository)
 	if err != nil {
-		panic(err)
+		return err
 	}
+	reportService := service.NewReportService(reportRepository, generationRepository, dataSourceService, s.deliveries, webhookService, assetService, s.llms, s.cfg.Limits.UploadFileTypes)
+	scheduleService := service.NewScheduleService(repository.NewScheduleRepository(db), generationRepository, reportService, s.cfg.Scheduler.SourceDir)
+	s.scheduler = scheduler.NewRunner(scheduleService, repository.NewAdvisoryLocker(db), s.cfg.Scheduler.Interval)
 
-	api := s.router.PathPrefix("/api/v1").Subrouter()
-	s.registerReportRoutes(api, db)
+	shareService := service.NewShareService(repository.NewShareRepository(db, dialect), generationRepository, s.cfg.Share.LinkSecret, s.cfg.Server.PublicBaseURL)
+
+	s.registerReportRoutes(api, reportService)
+	s.registerAssetRoutes(api, assetService)
+	s.registerPreviewRoutes(api, service.NewPreviewService(reportRepository, generationRepository, assetService, headlessRenderer))
+	s.registerScheduleRoutes(api, scheduleService)
+	s.registerDataSourceRoutes(api, dataSourceService)
+	s.registerDeliveryRoutes(api, s.deliveries)
+	s.registerWebhookRoutes(api, webhookService)
+	s.registerShareRoutes(s.router, api, shareService)
+	return nil
+}
+
+// registerInMemoryRoutes serves templates and manual generations without a
+// database, for demos. Everything else needs Postgres and is not registered.
+func (s *Server) registerInMemoryRoutes(api *mux.Router) {
+	slog.Warn("storage mode in-memory: data is lost on restart, only report routes are available")
+	reports, generations := repository.NewMemoryReportStore(), repository.NewMemoryGenerationStore()
+	reportService := service.NewReportService(reports, generations, nil, nil, nil, nil, s.llms, s.cfg.Limits.UploadFileTypes)
+	s.registerReportRoutes(api, reportService)
+	s.registerPreviewRoutes(api, service.NewPreviewService(reports, generations, nil, renderer.NewRenderer(s.cfg.Renderer.ChromePath)))
+}
+
+// registerSQLiteRoutes serves templates, their assets and manual generations
+// from a SQLite file for single-node deployments. Schedules, data sources,
+// deliveries, webhooks and share links rely on Postgres features and are not
+// registered.
+func (s *Server) registerSQLiteRoutes(api *mux.Router, db *sql.DB) error {
+	slog.Info("database driver sqlite: only report and asset routes are available")
+	reportRepository := repository.NewReportRepository(db, database.SQLite)
+	assetService, err := s.newAssetService(db, database.SQLite, reportRepository)
+	if err != nil {
+		return err
+	}
+	generationRepository := repository.NewGenerationRepository(db, database.SQLite)
+	reportService := service.NewReportService(reportRepository, generationRepository, nil, nil, nil, assetService, s.llms, s.cfg.Limits.UploadFileTypes)
+	s.registerReportRoutes(api, reportService)
+	s.registerAssetRoutes(api, assetService)
+	s.registerPreviewRoutes(api, service.NewPreviewService(reportRepository, generationRepository, assetService, renderer.NewRenderer(s.cfg.Renderer.ChromePath)))
+	return nil
+}
+
+func (s *Server) newAssetService(db *sql.DB, dialect database.Dialect, reports repository.ReportStore) (*service.AssetService, error) {
+	assetStorage, err := storage.New(storage.Config{
+		Backend:     s.cfg.Assets.Storage,
+		Dir:         s.cfg.Assets.Dir,
+		S3Endpoint:  s.cfg.Assets.S3.Endpoint,
+		S3Bucket:    s.cfg.Assets.S3.Bucket,
+		S3Prefix:    s.cfg.Assets.S3.Prefix,
+		S3Region:    s.cfg.Assets.S3.Region,
+		S3UseSSL:    s.cfg.Assets.S3.UseSSL,
+		S3AccessKey: s.cfg.Assets.S3.AccessKey,
+		S3SecretKey: s.cfg.Assets.S3.SecretKey,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not set up asset storage: %w", err)
+	}
+	return service.NewAssetService(repository.NewAssetRepository(db, dialect), reports, assetStorage, s.cfg.Server.PublicBaseURL), nil
+}
+
+func migrate(db *sql.DB, dialect database.Dialect) error {
+	migrator, err := migrations.New(db, dialect)
+	if err != nil {
+		return err
+	}
+	applied, err := migrator.Up(context.Background())
+	if err != nil {
+		return err
+	}
+	if applied > 0 {
+		slog.Info("applied database migrations", "count", applied)
+	}
+	return nil
 }
 
 func (s *Server) registerHealthRoutes() {
 	s.router.HandleFunc("/health", handler.Health).Methods("GET")
+	s.router.HandleFunc("/live", handler.Health).Methods("GET")
+
+	ready := handler.NewReadinessHandler(map[string]handler.ReadinessCheck{
+		"shutdown": func(context.Context) error {
+			if s.draining.Load() {
+				return errors.New("shutting down")
+			}
+			return nil
+		},
+		"database": func(ctx context.Context) error {
+			// The in-memory storage mode has no database to wait for.
+			if s.db == nil {
+				return nil
+			}
+			return s.db.PingContext(ctx)
+		},
+		"llm": func(context.Context) error {
+			if len(s.llms.Configured()) == 0 {
+				return errors.New("no LLM provider configured")
+			}
+			return nil
+		},
+	})
+	s.router.HandleFunc("/ready", ready.Ready).Methods("GET")
+}
+
+// corsPolicy allows the configured origins, with narrower rules on the
+// routes that do not take the usual methods or credentials.
+func (s *Server) corsPolicy() middleware.CORSPolicy {
+	return middleware.CORSPolicy{
+		AllowedOrigins:   s.cfg.CORS.AllowedOrigins,
+		AllowedMethods:   s.cfg.CORS.AllowedMethods,
+		AllowedHeaders:   s.cfg.CORS.AllowedHeaders,
+		ExposedHeaders:   s.cfg.CORS.ExposedHeaders,
+		AllowCredentials: s.cfg.CORS.AllowCredentials,
+		MaxAge:           s.cfg.CORS.MaxAge,
+		Routes: map[string]middleware.CORSRule{
+			// Share links are opened by recipients without an account.
+			"/share/{token}":                          {Methods: []string{"GET", "POST"}, Headers: []string{"Content-Type"}},
+			"/api/v1/admin/reports/{id:[0-9]+}":       {Methods: []string{"DELETE"}, Headers: []string{"Authorization", middleware.RequestIDHeader}},
+			"/api/v1/assets/{reportID:[0-9]+}/{name}": {Methods: []string{"GET", "HEAD"}},
+		},
+	}
 }
 
-func (s *Server) registerReportRoutes(r *mux.Router, db *sql.DB) {
-	repo := repository.NewReportRepository(db)
-	service := service.NewReportService(repo)
-	h := handler.NewReportHandler(service)
+func (s *Server) registerReportRoutes(r *mux.Router, service *service.ReportService) {
+	h := handler.NewReportHandler(service, s.cfg.UploadLimits())
 
 	r.HandleFunc("/reports", h.List).Methods("GET")
-	r.HandleFunc("/reports/filter", h.Filter).Methods("GET")
 	r.HandleFunc("/reports", h.Create).Methods("POST")
-	r.HandleFunc("/reports", h.Update).Methods("PUT")
-	r.HandleFunc("/reports/turnonoff", h.TurnOnOff).Methods("POST")
+	r.HandleFunc("/reports/validate", h.ValidateTemplate).Methods("POST")
+	r.HandleFunc("/reports/export", h.Export).Methods("GET")
+	r.HandleFunc("/reports/import", h.Import).Methods("POST")
 	r.HandleFunc("/reports/generate", h.GenerateReportFromFile).Methods("POST")
+	r.HandleFunc("/reports/{id:[0-9]+}", h.Get).Methods("GET")
+	r.HandleFunc("/reports/{id:[0-9]+}", h.Replace).Methods("PUT")
+	r.HandleFunc("/reports/{id:[0-9]+}", h.Patch).Methods("PATCH")
+	r.HandleFunc("/reports/{id:[0-9]+}", h.Delete).Methods("DELETE")
+	r.HandleFunc("/reports/{id:[0-9]+}/restore", h.Restore).Methods("POST")
+
+	// RPC-style routes from before /reports/{id}, kept for older clients.
+	successor := middleware.Deprecated("/api/v1/reports/{id}")
+	r.HandleFunc("/reports/filter", successor(h.Filter)).Methods("GET")
+	r.HandleFunc("/reports", successor(h.Update)).Methods("PUT")
+	r.HandleFunc("/reports/turnonoff", successor(h.TurnOnOff)).Methods("POST")
+
+	if s.cfg.Admin.Token == "" {
+		slog.Info("admin.token (ADMIN_TOKEN) not set, admin routes are disabled")
+	} else {
+		admin := r.PathPrefix("/admin").Subrouter()
+		admin.Use(middleware.AdminToken(s.cfg.Admin.Token))
+		admin.HandleFunc("/reports/{id:[0-9]+}", h.HardDelete).Methods("DELETE")
+	}
+	if s.cfg.Retention.ReportRetention > 0 {
+		s.retention = scheduler.NewRetentionJob(service, s.cfg.Retention.ReportRetention, s.cfg.Retention.Interval)
+	}
+
+	gallery := handler.NewGalleryHandler(service)
+	r.HandleFunc("/gallery", gallery.List).Methods("GET")
+	r.HandleFunc("/gallery/clone", gallery.Clone).Methods("POST")
+}
+
+func (s *Server) registerAssetRoutes(r *mux.Router, service *service.AssetService) {
+	h := handler.NewAssetHandler(service, s.cfg.UploadLimits())
+
+	r.HandleFunc("/reports/assets", h.List).Methods("GET")
+	r.HandleFunc("/reports/assets", h.Upload).Methods("POST")
+	r.HandleFunc("/reports/assets/delete", h.Delete).Methods("POST")
+	r.HandleFunc("/assets/{reportID:[0-9]+}/{name}", h.Serve).Methods("GET", "HEAD")
+}
+
+func (s *Server) registerPreviewRoutes(r *mux.Router, service *service.PreviewService) {
+	h := handler.NewPreviewHandler(service)
+
+	r.HandleFunc("/reports/{id:[0-9]+}/preview", h.Preview).Methods("POST")
+}
+
+func (s *Server) registerScheduleRoutes(r *mux.Router, service *service.ScheduleService) {
+	h := handler.NewScheduleHandler(service)
+
+	r.HandleFunc("/schedules", h.List).Methods("GET")
+	r.HandleFunc("/schedules", h.Create).Methods("POST")
+	r.HandleFunc("/schedules", h.Update).Methods("PUT")
+	r.HandleFunc("/schedules/turnonoff", h.TurnOnOff).Methods("POST")
+	r.HandleFunc("/schedules/runs", h.ListRuns).Methods("GET")
+}
+
+func (s *Server) registerDataSourceRoutes(r *mux.Router, service *service.DataSourceService) {
+	h := handler.NewDataSourceHandler(service)
+
+	r.HandleFunc("/datasources", h.List).Methods("GET")
+	r.HandleFunc("/datasources", h.Create).Methods("POST")
+	r.HandleFunc("/datasources", h.Update).Methods("PUT")
+	r.HandleFunc("/datasources/turnonoff", h.TurnOnOff).Methods("POST")
+}
+
+func (s *Server) registerDeliveryRoutes(r *mux.Router, service *service.DeliveryService) {
+	h := handler.NewDeliveryHandler(service)
+
+	r.HandleFunc("/deliveries", h.ListByGeneration).Methods("GET")
+	r.HandleFunc("/deliveries/rules", h.ListRules).Methods("GET")
+	r.HandleFunc("/deliveries/rules", h.CreateRule).Methods("POST")
+	r.HandleFunc("/deliveries/rules", h.UpdateRule).Methods("PUT")
+	r.HandleFunc("/deliveries/rules/turnonoff", h.TurnOnOffRule).Methods("POST")
+}
+
+func (s *Server) registerWebhookRoutes(r *mux.Router, service *service.WebhookService) {
+	h := handler.NewWebhookHandler(service)
+
+	r.HandleFunc("/webhooks", h.List).Methods("GET")
+	r.HandleFunc("/webhooks", h.Create).Methods("POST")
+	r.HandleFunc("/webhooks", h.Update).Methods("PUT")
+	r.HandleFunc("/webhooks/turnonoff", h.TurnOnOff).Methods("POST")
+	r.HandleFunc("/webhooks/deliveries", h.ListDeliveries).Methods("GET")
+}
+
+func (s *Server) registerShareRoutes(public *mux.Router, r *mux.Router, service *service.ShareService) {
+	h := handler.NewShareHandler(service)
+
+	r.HandleFunc("/shares", h.ListByGeneration).Methods("GET")
+	r.HandleFunc("/shares", h.Create).Methods("POST")
+	r.HandleFunc("/shares/revoke", h.Revoke).Methods("POST")
+	public.HandleFunc("/share/{token}", h.View).Methods("GET", "POST")
 }
@@ -0,0 +1,113 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reportia/config"
+	"reportia/middleware"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+const testOrigin = "https://app.example.com"
+
+// corsTestServer registers the routes that have CORS rules of their own.
+// Preflight requests never reach the handlers, so no service is needed.
+func corsTestServer(t *testing.T) (*Server, http.Handler) {
+	t.Helper()
+	s := &Server{
+		cfg: &config.Config{
+			CORS: config.CORSConfig{
+				AllowedOrigins: []string{testOrigin},
+				AllowedMethods: []string{"GET", "POST", "PUT", "PATCH", "DELETE"},
+				AllowedHeaders: []string{"Content-Type", "Authorization", "X-Request-ID"},
+			},
+			Admin: config.AdminConfig{Token: "admin-token"},
+		},
+		router: mux.NewRouter(),
+	}
+	api := s.router.PathPrefix("/api/v1").Subrouter()
+	s.registerReportRoutes(api, nil)
+	s.registerAssetRoutes(api, nil)
+	s.registerShareRoutes(s.router, api, nil)
+
+	cors, err := middleware.CORS(s.router, s.corsPolicy())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, cors(s.router)
+}
+
+func TestCORSRoutesAreRegistered(t *testing.T) {
+	s, _ := corsTestServer(t)
+	templates := map[string]bool{}
+	err := s.router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		if template, err := route.GetPathTemplate(); err == nil {
+			templates[template] = true
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for template := range s.corsPolicy().Routes {
+		if !templates[template] {
+			t.Errorf("CORS rule for %s matches no registered route", template)
+		}
+	}
+}
+
+func TestCORSPreflightPerRoute(t *testing.T) {
+	s, handler := corsTestServer(t)
+
+	tests := []struct {
+		template    string
+		path        string
+		method      string
+		wantMethods string
+		wantHeaders string
+	}{
+		{"/share/{token}", "/share/abc", "POST", "GET, POST", "Content-Type"},
+		{"/api/v1/admin/reports/{id:[0-9]+}", "/api/v1/admin/reports/7", "DELETE", "DELETE", "Authorization, X-Request-ID"},
+		{"/api/v1/assets/{reportID:[0-9]+}/{name}", "/api/v1/assets/7/logo.png", "GET", "GET, HEAD", "Content-Type, Authorization, X-Request-ID"},
+	}
+	if len(tests) != len(s.corsPolicy().Routes) {
+		t.Fatalf("%d CORS rules are configured, %d are tested", len(s.corsPolicy().Routes), len(tests))
+	}
+	for _, tc := range tests {
+		t.Run(tc.template, func(t *testing.T) {
+			if _, ok := s.corsPolicy().Routes[tc.template]; !ok {
+				t.Fatalf("no CORS rule for %s", tc.template)
+			}
+			req := httptest.NewRequest(http.MethodOptions, tc.path, nil)
+			req.Header.Set("Origin", testOrigin)
+			req.Header.Set("Access-Control-Request-Method", tc.method)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusNoContent {
+				t.Errorf("status %d, want %d", rec.Code, http.StatusNoContent)
+			}
+			if got := rec.Header().Get("Access-Control-Allow-Origin"); got != testOrigin {
+				t.Errorf("Access-Control-Allow-Origin %q, want %q", got, testOrigin)
+			}
+			if got := rec.Header().Get("Access-Control-Allow-Methods"); got != tc.wantMethods {
+				t.Errorf("Access-Control-Allow-Methods %q, want %q", got, tc.wantMethods)
+			}
+			if got := rec.Header().Get("Access-Control-Allow-Headers"); got != tc.wantHeaders {
+				t.Errorf("Access-Control-Allow-Headers %q, want %q", got, tc.wantHeaders)
+			}
+		})
+	}
+
+	// Routes without a rule of their own get the policy defaults.
+	req := httptest.NewRequest(http.MethodOptions, "/api/v1/reports/7", nil)
+	req.Header.Set("Origin", testOrigin)
+	req.Header.Set("Access-Control-Request-Method", "PATCH")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if got, want := rec.Header().Get("Access-Control-Allow-Methods"), "GET, POST, PUT, PATCH, DELETE"; got != want {
+		t.Errorf("default Access-Control-Allow-Methods %q, want %q", got, want)
+	}
+}
@@ -1,33 +1,192 @@
 package server
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"reportia/config"
+	"reportia/database"
+	"reportia/integration/llm"
+	"reportia/integration/llm/google"
+	"reportia/logging"
+	"reportia/metrics"
 	"reportia/middleware"
+	"reportia/scheduler"
+	"reportia/service"
+	"slices"
+	"sync/atomic"
+	"time"
 
 	"github.com/gorilla/mux"
 )
 
 type Server struct {
-	cfg    *config.Config
-	router *mux.Router
+	cfg        *config.Config
+	router     *mux.Router
+	db         *database.DB
+	llms       *llm.Providers
+	scheduler  *scheduler.Runner
+	webhooks   *scheduler.WebhookDispatcher
+	retention  *scheduler.RetentionJob
+	deliveries *service.DeliveryService
+	// draining is set once shutdown starts, failing the readiness probe so
+	// that load balancers stop routing new requests here.
+	draining atomic.Bool
 }
 
-func New(cfg *config.Config) *Server {
+// New builds the server on db, which is nil in the in-memory storage mode.
+// The caller owns db and closes it once Start has returned.
+func New(cfg *config.Config, db *database.DB) (*Server, error) {
 	s := &Server{
 		cfg:    cfg,
 		router: mux.NewRouter(),
+		db:     db,
+		llms: llm.NewProviders(llm.Config{
+			OpenAI:    providerConfig(cfg.LLM.OpenAI),
+			Anthropic: providerConfig(cfg.LLM.Anthropic),
+			Gemini:    geminiConfig(cfg.LLM.Gemini),
+		}),
 	}
-	s.RegisterRoutes()
-	return s
+	declareModels(cfg.LLM)
+	if err := s.RegisterRoutes(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func providerConfig(cfg config.LLMProviderConfig) llm.ProviderConfig {
+	return llm.ProviderConfig{APIKey: cfg.APIKey, DefaultModel: cfg.DefaultModel}
+}
+
+// declareModels lists the models labelled by name in the LLM metrics: the
+// default and configured models of every provider.
+func declareModels(cfg config.LLMConfig) {
+	metrics.SetModels("openai", append([]string{cfg.OpenAI.DefaultModel}, cfg.OpenAI.Models...)...)
+	metrics.SetModels("anthropic", append([]string{cfg.Anthropic.DefaultModel}, cfg.Anthropic.Models...)...)
+	metrics.SetModels("gemini", append([]string{cfg.Gemini.DefaultModel}, cfg.Gemini.Models...)...)
+}
+
+// geminiConfig converts the Gemini settings to the types of the SDK.
+func geminiConfig(cfg config.GeminiConfig) google.Config {
+	gemini := google.Config{
+		APIKey:          cfg.APIKey,
+		Model:           cfg.DefaultModel,
+		BaseURL:         cfg.BaseURL,
+		MaxOutputTokens: int32(cfg.MaxOutputTokens),
+		SafetyThreshold: cfg.SafetyThreshold,
+		FileTimeout:     cfg.FileTimeout,
+	}
+	if cfg.Temperature != nil {
+		temperature := float32(*cfg.Temperature)
+		gemini.Temperature = &temperature
+	}
+	return gemini
+}
+
+// Start serves requests and runs the background jobs until ctx is done, then
+// drains both within the shutdown timeout. It returns nil after a clean
+// shutdown.
+func (s *Server) Start(ctx context.Context) error {
+	addr := fmt.Sprintf(":%s", s.cfg.Server.Port)
+	handler := middleware.Logging(middleware.LoggingOptions{
+		Redaction: logging.Redaction{
+			Headers: append(slices.Clone(s.cfg.Log.RedactHeaders), logging.DefaultRedactedHeaders...),
+			Fields:  append(slices.Clone(s.cfg.Log.RedactFields), logging.DefaultRedactedFields...),
+		},
+		BodyLimit: s.cfg.Log.BodyLimit,
+	})(s.router)
+	handler = middleware.Metrics(s.router)(handler)
+	handler = middleware.Tracing(s.router)(handler)
+	handler = middleware.RequestID(handler)
+	cors, err := middleware.CORS(s.router, s.corsPolicy())
+	if err != nil {
+		return err
+	}
+	handler = cors(handler)
+
+	// Jobs get a context of their own: they are stopped explicitly once the
+	// HTTP server has drained, not as soon as the signal arrives.
+	jobsCtx := context.WithoutCancel(ctx)
+	if s.cfg.Scheduler.Enabled && s.scheduler != nil {
+		s.scheduler.Start(jobsCtx)
+	}
+	if s.webhooks != nil {
+		s.webhooks.Start(jobsCtx)
+	}
+	if s.retention != nil {
+		s.retention.Start(jobsCtx)
+	}
+
+	httpServer := &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: s.cfg.Server.ReadHeaderTimeout,
+		ReadTimeout:       s.cfg.Server.ReadTimeout,
+		WriteTimeout:      s.cfg.Server.WriteTimeout,
+		IdleTimeout:       s.cfg.Server.IdleTimeout,
+	}
+	serveErr := make(chan error, 1)
+	go func() {
+		slog.Info("server running", "addr", addr)
+		serveErr <- httpServer.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serveErr:
+		s.stopJobs(context.Background())
+		return err
+	case <-ctx.Done():
+	}
+
+	slog.Info("shutting down", "timeout", s.cfg.Server.ShutdownTimeout.String())
+	s.draining.Store(true)
+	// Keep accepting requests while load balancers notice the failing
+	// readiness probe; Shutdown closes the listener right away.
+	time.Sleep(s.cfg.Server.ShutdownDelay)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), s.cfg.Server.ShutdownTimeout)
+	defer cancel()
+
+	err = httpServer.Shutdown(shutdownCtx)
+	if err != nil {
+		// Past the deadline: cut the remaining connections, which cancels
+		// the contexts of their requests.
+		httpServer.Close()
+		err = fmt.Errorf("requests still running at the shutdown deadline: %w", err)
+	}
+	if jobsErr := s.stopJobs(shutdownCtx); jobsErr != nil {
+		err = errors.Join(err, jobsErr)
+	}
+	if err == nil {
+		slog.Info("shutdown complete")
+	}
+	return err
 }
 
-func (s *Server) Start() error {
-	addr := fmt.Sprintf(":%s", s.cfg.Port)
-	log.Printf("Server running on %s", addr)
-	handler := middleware.Logging(s.router)
-	handler = middleware.CORS(s.cfg.AllowedOrigin)(handler)
-	return http.ListenAndServe(addr, handler)
+// stopJobs stops the background jobs and waits for their in-flight work,
+// such as scheduled generations and deliveries, until ctx is done.
+func (s *Server) stopJobs(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if s.scheduler != nil {
+			s.scheduler.Stop()
+		}
+		if s.webhooks != nil {
+			s.webhooks.Stop()
+		}
+		if s.retention != nil {
+			s.retention.Stop()
+		}
+		// Deliveries are enqueued by generations, so they are waited for
+		// once the scheduler and the requests have finished.
+		s.deliveries.Wait()
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("background jobs still running at the shutdown deadline: %w", ctx.Err())
+	}
 }
@@ -0,0 +1,229 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"path"
+	"regexp"
+	"reportia/apperror"
+	"reportia/integration/storage"
+	"reportia/model"
+	"reportia/repository"
+	"reportia/templatelint"
+	"strings"
+)
+
+var (
+	ErrAssetNotFound = apperror.New(apperror.NotFound, "asset_not_found", "asset not found")
+	ErrInvalidAsset  = apperror.New(apperror.Validation, "invalid_asset", "invalid asset")
+)
+
+// assetTypes maps the accepted file extensions to their content type.
+var assetTypes = map[string]string{
+	".png":   "image/png",
+	".jpg":   "image/jpeg",
+	".jpeg":  "image/jpeg",
+	".gif":   "image/gif",
+	".webp":  "image/webp",
+	".svg":   "image/svg+xml",
+	".woff":  "font/woff",
+	".woff2": "font/woff2",
+	".ttf":   "font/ttf",
+	".otf":   "font/otf",
+}
+
+var assetToken = regexp.MustCompile(`\{\{\s*` + templatelint.AssetPrefix + `([a-z0-9][a-z0-9_-]*)\s*\}\}`)
+
+type AssetService struct {
+	repo          *repository.AssetRepository
+	reports       repository.ReportStore
+	storage       storage.Storage
+	publicBaseURL string
+}
+
+func NewAssetService(repo *repository.AssetRepository, reports repository.ReportStore, storage storage.Storage, publicBaseURL string) *AssetService {
+	return &AssetService{repo: repo, reports: reports, storage: storage, publicBaseURL: strings.TrimRight(publicBaseURL, "/")}
+}
+
+func (s *AssetService) List(ctx context.Context, reportID int) ([]model.Asset, error) {
+	assets, err := s.repo.ListByReport(ctx, reportID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range assets {
+		assets[i].URL = s.URL(assets[i])
+	}
+	return assets, nil
+}
+
+// URL is where the asset is served. It carries a content version, so that
+// the asset can be cached for good and a new upload changes the URL.
+func (s *AssetService) URL(asset model.Asset) string {
+	return fmt.Sprintf("%s/api/v1/assets/%d/%s?v=%s", s.publicBaseURL, asset.ReportID, asset.Name, AssetVersion(asset))
+}
+
+// AssetVersion identifies the content of an asset in its URL.
+func AssetVersion(asset model.Asset) string {
+	return asset.Checksum[:12]
+}
+
+// Upload stores an asset of the report, replacing the content of the asset
+// with the same name if there is one.
+func (s *AssetService) Upload(ctx context.Context, reportID int, name, fileName string, content []byte) (*model.Asset, error) {
+	if !templatelint.AssetName.MatchString(name) {
+		return nil, fmt.Errorf("%w: name must be 1 to 60 lowercase letters, digits, '-' or '_'", ErrInvalidAsset)
+	}
+	if len(content) == 0 || len(content) > model.MaxAssetSize {
+		return nil, fmt.Errorf("%w: size must be between 1 and %d bytes", ErrInvalidAsset, model.MaxAssetSize)
+	}
+	contentType, err := detectAssetType(fileName, content)
+	if err != nil {
+		return nil, err
+	}
+	found, _, _, err := s.reports.Search(ctx, model.ReportQuery{ID: reportID, Page: 1, PageSize: 1})
+	if err != nil {
+		return nil, err
+	}
+	if len(found) == 0 {
+		return nil, ErrReportNotFound
+	}
+
+	sum := sha256.Sum256(content)
+	checksum := hex.EncodeToString(sum[:])
+	asset := model.Asset{
+		ReportID:    reportID,
+		Name:        name,
+		FileName:    path.Base(fileName),
+		ContentType: contentType,
+		Size:        len(content),
+		Checksum:    checksum,
+		StorageKey:  fmt.Sprintf("reports/%d/%s-%s", reportID, name, checksum[:16]),
+	}
+	if err := s.storage.Put(ctx, asset.StorageKey, content, contentType); err != nil {
+		return nil, &apperror.Error{Kind: apperror.Upstream, Code: "asset_storage_failed", Message: "could not store asset", Err: err}
+	}
+
+	existing, err := s.repo.GetByName(ctx, reportID, name)
+	var saved *model.Asset
+	if err == nil && existing == nil {
+		saved, err = s.repo.Create(ctx, asset)
+	} else if err == nil {
+		asset.ID = existing.ID
+		saved, err = s.repo.Replace(ctx, asset)
+	}
+	if err != nil {
+		if existing == nil || existing.StorageKey != asset.StorageKey {
+			s.deleteObject(ctx, asset.StorageKey)
+		}
+		return nil, err
+	}
+	if existing != nil && existing.StorageKey != asset.StorageKey {
+		s.deleteObject(ctx, existing.StorageKey)
+	}
+	saved.URL = s.URL(*saved)
+	return saved, nil
+}
+
+func (s *AssetService) Delete(ctx context.Context, id int) error {
+	asset, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if asset == nil {
+		return ErrAssetNotFound
+	}
+	if err := s.repo.Delete(ctx, id); err != nil {
+		return err
+	}
+	s.deleteObject(ctx, asset.StorageKey)
+	return nil
+}
+
+// deleteObject removes content no asset points at anymore. A failure only
+// leaves an orphan object behind, so it is logged rather than returned.
+func (s *AssetService) deleteObject(ctx context.Context, key string) {
+	if err := s.storage.Delete(context.WithoutCancel(ctx), key); err != nil {
+		slog.WarnContext(ctx, "asset: could not delete object", "key", key, "error", err)
+	}
+}
+
+// Open returns the asset and its content, to be closed by the caller.
+func (s *AssetService) Open(ctx context.Context, reportID int, name string) (*model.Asset, io.ReadCloser, error) {
+	asset, err := s.repo.GetByName(ctx, reportID, name)
+	if err != nil {
+		return nil, nil, err
+	}
+	if asset == nil {
+		return nil, nil, ErrAssetNotFound
+	}
+	content, err := s.storage.Open(ctx, asset.StorageKey)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, nil, ErrAssetNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return asset, content, nil
+}
+
+// Content reads the whole content of an asset.
+func (s *AssetService) Content(ctx context.Context, asset model.Asset) ([]byte, error) {
+	content, err := s.storage.Open(ctx, asset.StorageKey)
+	if err != nil {
+		return nil, err
+	}
+	defer content.Close()
+	return io.ReadAll(io.LimitReader(content, model.MaxAssetSize+1))
+}
+
+// Resolve replaces the {{ASSET:name}} tokens of a report's HTML with the URLs
+// of its assets. Tokens naming no asset are left as they are. A nil service,
+// as used by the in-memory storage mode, resolves nothing.
+func (s *AssetService) Resolve(ctx context.Context, reportID int, html string) (string, error) {
+	if s == nil || !assetToken.MatchString(html) {
+		return html, nil
+	}
+	assets, err := s.repo.ListByReport(ctx, reportID)
+	if err != nil {
+		return "", err
+	}
+	urls := make(map[string]string, len(assets))
+	for _, asset := range assets {
+		urls[asset.Name] = s.URL(asset)
+	}
+	return assetToken.ReplaceAllStringFunc(html, func(token string) string {
+		if url, ok := urls[assetToken.FindStringSubmatch(token)[1]]; ok {
+			return url
+		}
+		return token
+	}), nil
+}
+
+// detectAssetType accepts images and fonts only, and checks that the content
+// matches the extension so that nothing else is served under an image type.
+func detectAssetType(fileName string, content []byte) (string, error) {
+	contentType, ok := assetTypes[strings.ToLower(path.Ext(fileName))]
+	if !ok {
+		return "", fmt.Errorf("%w: only png, jpg, gif, webp, svg, woff, woff2, ttf and otf files are accepted", ErrInvalidAsset)
+	}
+	matches := false
+	switch contentType {
+	case "image/svg+xml":
+		matches = strings.Contains(strings.ToLower(string(content[:min(len(content), 4096)])), "<svg")
+	case "font/ttf", "font/otf":
+		sniffed := http.DetectContentType(content)
+		matches = sniffed == "font/ttf" || sniffed == "font/otf"
+	default:
+		matches = http.DetectContentType(content) == contentType
+	}
+	if !matches {
+		return "", fmt.Errorf("%w: content does not match the %s extension", ErrInvalidAsset, path.Ext(fileName))
+	}
+	return contentType, nil
+}
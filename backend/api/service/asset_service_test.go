@@ -0,0 +1,106 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reportia/database"
+	"reportia/integration/storage"
+	"reportia/migrations"
+	"reportia/model"
+	"reportia/repository"
+	"strings"
+	"testing"
+	"time"
+)
+
+var pngContent = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+// newAssetService stores assets of one report in a SQLite database and a
+// local directory, which it returns.
+func newAssetService(t *testing.T) (*AssetService, int, string) {
+	t.Helper()
+	ctx := context.Background()
+	dsn := "file:" + filepath.Join(t.TempDir(), "reportia.db") + "?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)"
+	db, err := database.Open(ctx, database.Options{Driver: "sqlite", URL: dsn, MaxOpenConns: 1, ConnectTimeout: 5 * time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	migrator, err := migrations.New(db.DB, db.Dialect)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrator.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	reports := repository.NewReportRepository(db.DB, db.Dialect)
+	report, err := reports.Create(ctx, model.Report{Name: "Sales", Template: "<!-- DYNAMIC BODY CONTENT HERE -->", UserMail: "owner@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := filepath.Join(t.TempDir(), "assets")
+	local, err := storage.NewLocalStorage(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewAssetService(repository.NewAssetRepository(db.DB, db.Dialect), reports, local, "http://reportia.test"), report.ID, dir
+}
+
+func TestAssetUploadNames(t *testing.T) {
+	s, reportID, _ := newAssetService(t)
+	for _, tc := range []struct {
+		name     string
+		fileName string
+		content  []byte
+		valid    bool
+	}{
+		{"logo", "logo.png", pngContent, true},
+		{"logo_2-dark", "Logo.PNG", pngContent, true},
+		{"../logo", "logo.png", pngContent, false},
+		{"reports/1/logo", "logo.png", pngContent, false},
+		{"Logo", "logo.png", pngContent, false},
+		{"", "logo.png", pngContent, false},
+		{strings.Repeat("a", 61), "logo.png", pngContent, false},
+		{"logo", "logo.html", []byte("<html></html>"), false},
+		{"logo", "logo.png", []byte("<svg></svg>"), false},
+		{"logo", "logo.png", nil, false},
+	} {
+		_, err := s.Upload(context.Background(), reportID, tc.name, tc.fileName, tc.content)
+		if tc.valid && err != nil {
+			t.Errorf("%q %q: got %v, want no error", tc.name, tc.fileName, err)
+		}
+		if !tc.valid && !errors.Is(err, ErrInvalidAsset) {
+			t.Errorf("%q %q: got %v, want ErrInvalidAsset", tc.name, tc.fileName, err)
+		}
+	}
+}
+
+// TestAssetUploadFileName checks that the client's file name never reaches
+// the storage key.
+func TestAssetUploadFileName(t *testing.T) {
+	s, reportID, dir := newAssetService(t)
+	asset, err := s.Upload(context.Background(), reportID, "logo", "../../../etc/logo.png", pngContent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if asset.FileName != "logo.png" {
+		t.Errorf("got file name %q, want logo.png", asset.FileName)
+	}
+	if want := fmt.Sprintf("reports/%d/logo-", reportID); !strings.HasPrefix(asset.StorageKey, want) {
+		t.Errorf("got storage key %q, want it to start with %q", asset.StorageKey, want)
+	}
+	if matches, _ := filepath.Glob(filepath.Join(dir, "reports", "*", "logo-*")); len(matches) != 1 {
+		t.Errorf("got stored files %v", matches)
+	}
+
+	_, content, err := s.Open(context.Background(), reportID, "../logo")
+	if !errors.Is(err, ErrAssetNotFound) {
+		t.Errorf("opening ../logo: got %v, want ErrAssetNotFound", err)
+	}
+	if content != nil {
+		content.Close()
+	}
+}
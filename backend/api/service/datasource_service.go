@@ -0,0 +1,79 @@
+package service
+
+import (
+	"context"
+	"reportia/apperror"
+	"reportia/datasource"
+	"reportia/model"
+	"reportia/repository"
+)
+
+var (
+	ErrDataSourceNotFound = apperror.New(apperror.NotFound, "data_source_not_found", "data source not found")
+	ErrDataSourceInactive = apperror.New(apperror.Inactive, "data_source_inactive", "the data source that you select was inactive")
+)
+
+type DataSourceService struct {
+	repo   *repository.DataSourceRepository
+	policy datasource.Policy
+}
+
+func NewDataSourceService(repo *repository.DataSourceRepository, policy datasource.Policy) *DataSourceService {
+	return &DataSourceService{repo: repo, policy: policy}
+}
+
+func (s *DataSourceService) List(ctx context.Context) ([]model.DataSource, error) {
+	return s.repo.List(ctx)
+}
+
+func (s *DataSourceService) Create(ctx context.Context, ds model.DataSource) (*model.DataSource, error) {
+	if _, err := datasource.New(ds.Kind, ds.Config, s.policy); err != nil {
+		return nil, apperror.Wrap(apperror.Validation, "invalid_data_source", err)
+	}
+	return s.repo.Create(ctx, ds)
+}
+
+func (s *DataSourceService) Update(ctx context.Context, ds model.DataSource) (*model.DataSource, error) {
+	if _, err := datasource.New(ds.Kind, ds.Config, s.policy); err != nil {
+		return nil, apperror.Wrap(apperror.Validation, "invalid_data_source", err)
+	}
+	updated, err := s.repo.Update(ctx, ds)
+	if err != nil {
+		return nil, err
+	}
+	if updated == nil {
+		return nil, ErrDataSourceNotFound
+	}
+	return updated, nil
+}
+
+func (s *DataSourceService) TurnOnOff(ctx context.Context, id int, active bool) error {
+	if id == 0 {
+		return errIDRequired
+	}
+	return notFoundOnNoRows(s.repo.TurnOnOff(ctx, id, active), ErrDataSourceNotFound)
+}
+
+// Fetch pulls a fresh snapshot from the data source. The caller owns the
+// returned reader and must close it.
+func (s *DataSourceService) Fetch(ctx context.Context, id int) (*datasource.Data, error) {
+	ds, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if ds == nil {
+		return nil, ErrDataSourceNotFound
+	}
+	if !ds.Active {
+		return nil, ErrDataSourceInactive
+	}
+	connector, err := datasource.New(ds.Kind, ds.Config, s.policy)
+	if err != nil {
+		return nil, apperror.Wrap(apperror.Validation, "invalid_data_source", err)
+	}
+	data, err := connector.Fetch(ctx)
+	if err != nil {
+		return nil, apperror.Wrap(apperror.Upstream, "data_source_failed", err)
+	}
+	return data, nil
+}
@@ -0,0 +1,194 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"reportia/apperror"
+	"reportia/integration/mail"
+	"reportia/integration/renderer"
+	"reportia/metrics"
+	"reportia/model"
+	"reportia/repository"
+	"sync"
+	"time"
+)
+
+const deliveryTimeout = 5 * time.Minute
+
+var ErrDeliveryRuleNotFound = apperror.New(apperror.NotFound, "delivery_rule_not_found", "delivery rule not found")
+
+type DeliveryOptions struct {
+	DryRun      bool
+	MaxAttempts int
+	RetryDelay  time.Duration
+}
+
+type DeliveryService struct {
+	repo     *repository.DeliveryRepository
+	mailer   mail.Mailer
+	renderer renderer.Renderer
+	options  DeliveryOptions
+	wg       sync.WaitGroup
+}
+
+func NewDeliveryService(repo *repository.DeliveryRepository, mailer mail.Mailer, renderer renderer.Renderer, options DeliveryOptions) *DeliveryService {
+	if options.MaxAttempts <= 0 {
+		options.MaxAttempts = 3
+	}
+	if options.RetryDelay <= 0 {
+		options.RetryDelay = 2 * time.Second
+	}
+	return &DeliveryService{repo: repo, mailer: mailer, renderer: renderer, options: options}
+}
+
+func (s *DeliveryService) ListRules(ctx context.Context) ([]model.DeliveryRule, error) {
+	return s.repo.ListRules(ctx)
+}
+
+func (s *DeliveryService) CreateRule(ctx context.Context, rule model.DeliveryRule) (*model.DeliveryRule, error) {
+	if (rule.ReportID == nil) == (rule.ScheduleID == nil) {
+		return nil, apperror.Validationf("delivery_target_required", "exactly one of report_id or schedule_id is required")
+	}
+	return s.repo.CreateRule(ctx, rule)
+}
+
+func (s *DeliveryService) UpdateRule(ctx context.Context, rule model.DeliveryRule) (*model.DeliveryRule, error) {
+	updated, err := s.repo.UpdateRule(ctx, rule)
+	if err != nil {
+		return nil, err
+	}
+	if updated == nil {
+		return nil, ErrDeliveryRuleNotFound
+	}
+	return updated, nil
+}
+
+func (s *DeliveryService) TurnOnOffRule(ctx context.Context, id int, active bool) error {
+	if id == 0 {
+		return errIDRequired
+	}
+	return notFoundOnNoRows(s.repo.TurnOnOffRule(ctx, id, active), ErrDeliveryRuleNotFound)
+}
+
+func (s *DeliveryService) ListByGeneration(ctx context.Context, generationID int) ([]model.Delivery, error) {
+	return s.repo.ListByGeneration(ctx, generationID)
+}
+
+var deliveryQueue = metrics.QueueDepth.WithLabelValues("deliveries")
+
+// Enqueue emails a successful generation to every matching rule in the
+// background, so the caller does not wait on SMTP. A nil service, as used
+// by the in-memory storage mode, delivers nothing. The deliveries keep the
+// values of ctx, such as the request ID, but not its cancellation.
+func (s *DeliveryService) Enqueue(ctx context.Context, gen model.Generation) {
+	if s == nil {
+		return
+	}
+	s.wg.Add(1)
+	deliveryQueue.Inc()
+	go func() {
+		defer s.wg.Done()
+		defer deliveryQueue.Dec()
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), deliveryTimeout)
+		defer cancel()
+		if err := s.Deliver(ctx, gen); err != nil {
+			slog.ErrorContext(ctx, "delivery failed", "generation_id", gen.ID, "error", err)
+		}
+	}()
+}
+
+// Wait blocks until all enqueued deliveries have finished.
+func (s *DeliveryService) Wait() {
+	if s == nil {
+		return
+	}
+	s.wg.Wait()
+}
+
+func (s *DeliveryService) Deliver(ctx context.Context, gen model.Generation) error {
+	rules, err := s.repo.ListActiveRulesFor(ctx, gen.ReportID, gen.ScheduleID)
+	if err != nil {
+		return err
+	}
+	for _, rule := range rules {
+		if err := s.deliverRule(ctx, gen, rule); err != nil {
+			slog.ErrorContext(ctx, "delivery rule failed", "generation_id", gen.ID, "rule_id", rule.ID, "error", err)
+		}
+	}
+	return nil
+}
+
+func (s *DeliveryService) deliverRule(ctx context.Context, gen model.Generation, rule model.DeliveryRule) error {
+	delivery, err := s.repo.Create(ctx, gen.ID, rule.ID, rule.Recipients)
+	if err != nil {
+		return err
+	}
+
+	msg, err := s.buildMessage(ctx, gen, rule)
+	if err != nil {
+		return s.repo.Finish(ctx, delivery.ID, model.DeliveryStatusFailed, 0, err.Error())
+	}
+	if s.options.DryRun {
+		slog.InfoContext(ctx, "delivery: dry run", "generation_id", gen.ID, "rule_id", rule.ID, "recipients", len(msg.To))
+		return s.repo.Finish(ctx, delivery.ID, model.DeliveryStatusDryRun, 0, "")
+	}
+
+	status, attempts, sendErr := s.sendWithRetry(ctx, msg)
+	errorMessage := ""
+	if sendErr != nil {
+		errorMessage = sendErr.Error()
+	}
+	return s.repo.Finish(ctx, delivery.ID, status, attempts, errorMessage)
+}
+
+func (s *DeliveryService) buildMessage(ctx context.Context, gen model.Generation, rule model.DeliveryRule) (mail.Message, error) {
+	subject := rule.Subject
+	if subject == "" {
+		subject = fmt.Sprintf("Report #%d generated", gen.ReportID)
+	}
+	msg := mail.Message{
+		To:      rule.Recipients,
+		Subject: subject,
+		HTML:    gen.Output,
+	}
+	if rule.AttachPDF {
+		if s.renderer == nil {
+			return msg, renderer.ErrRendererNotConfigured
+		}
+		pdf, err := s.renderer.RenderPDF(ctx, gen.Output)
+		if err != nil {
+			return msg, err
+		}
+		msg.Attachments = append(msg.Attachments, mail.Attachment{
+			FileName:    fmt.Sprintf("report-%d.pdf", gen.ID),
+			ContentType: "application/pdf",
+			Content:     pdf,
+		})
+	}
+	return msg, nil
+}
+
+// sendWithRetry backs off exponentially on transient failures and stops
+// immediately when the server permanently rejects the message.
+func (s *DeliveryService) sendWithRetry(ctx context.Context, msg mail.Message) (string, int, error) {
+	var err error
+	for attempt := 1; attempt <= s.options.MaxAttempts; attempt++ {
+		err = s.mailer.Send(ctx, msg)
+		if err == nil {
+			return model.DeliveryStatusSent, attempt, nil
+		}
+		if mail.IsPermanent(err) {
+			return model.DeliveryStatusBounced, attempt, err
+		}
+		if attempt == s.options.MaxAttempts {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return model.DeliveryStatusFailed, attempt, ctx.Err()
+		case <-time.After(s.options.RetryDelay << (attempt - 1)):
+		}
+	}
+	return model.DeliveryStatusFailed, s.options.MaxAttempts, err
+}
@@ -0,0 +1,18 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"reportia/apperror"
+)
+
+var errIDRequired = apperror.Validationf("id_required", "id is required")
+
+// notFoundOnNoRows replaces the sql.ErrNoRows returned by stores for missing
+// rows with the domain error of the resource.
+func notFoundOnNoRows(err, notFound error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return notFound
+	}
+	return err
+}
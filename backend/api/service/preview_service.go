@@ -0,0 +1,224 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"reportia/apperror"
+	"reportia/integration/renderer"
+	"reportia/model"
+	"reportia/repository"
+	"reportia/templatelint"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+// samplePreviewContent fills the body slot when no generation is chosen. It
+// covers the elements generations usually produce, so that their styles show.
+const samplePreviewContent = `<section>
+<h2>Lorem ipsum dolor sit amet</h2>
+<p>Consectetur adipiscing elit, sed do eiusmod tempor incididunt ut labore et dolore magna aliqua. Ut enim ad minim veniam, quis nostrud exercitation ullamco laboris nisi ut aliquip ex ea commodo consequat.</p>
+<ul>
+<li>Duis aute irure dolor in reprehenderit: <strong>42,7%</strong></li>
+<li>Excepteur sint occaecat cupidatat: <strong>1.284</strong></li>
+<li>Sunt in culpa qui officia deserunt: <strong>R$ 18.930,00</strong></li>
+</ul>
+</section>
+<section>
+<h3>Tabula exempli</h3>
+<table>
+<thead><tr><th>Item</th><th>Q1</th><th>Q2</th><th>Q3</th></tr></thead>
+<tbody>
+<tr><td>Alpha</td><td>120</td><td>135</td><td>150</td></tr>
+<tr><td>Beta</td><td>98</td><td>87</td><td>110</td></tr>
+<tr><td>Gamma</td><td>64</td><td>72</td><td>81</td></tr>
+</tbody>
+</table>
+<p>Nemo enim ipsam voluptatem quia voluptas sit aspernatur aut odit aut fugit, sed quia consequuntur magni dolores eos qui ratione voluptatem sequi nesciunt.</p>
+</section>`
+
+const (
+	DefaultPreviewWidth  = 1280
+	DefaultPreviewHeight = 800
+)
+
+var (
+	ErrGenerationNotFound  = apperror.New(apperror.NotFound, "generation_not_found", "generation not found")
+	ErrRendererUnavailable = apperror.Wrap(apperror.NotImplemented, "renderer_unavailable", renderer.ErrRendererNotConfigured)
+
+	htmlComment      = regexp.MustCompile(`(?s)<!--(.*?)-->`)
+	systemVariableRe = regexp.MustCompile(`\{\{\s*([A-Z_]+)\s*\}\}`)
+)
+
+type PreviewService struct {
+	reports     repository.ReportStore
+	generations repository.GenerationStore
+	assets      *AssetService
+	renderer    renderer.Renderer
+}
+
+func NewPreviewService(reports repository.ReportStore, generations repository.GenerationStore, assets *AssetService, renderer renderer.Renderer) *PreviewService {
+	return &PreviewService{reports: reports, generations: generations, assets: assets, renderer: renderer}
+}
+
+// Render returns the report's template with its system variables and assets
+// resolved and the body slot filled, without calling an LLM. The slot gets
+// sample content, or when generationID is set, what that generation of the
+// report put in the element holding the slot.
+func (s *PreviewService) Render(ctx context.Context, reportID int, generationID *int) (string, error) {
+	found, _, _, err := s.reports.Search(ctx, model.ReportQuery{ID: reportID, Page: 1, PageSize: 1})
+	if err != nil {
+		return "", err
+	}
+	if len(found) == 0 {
+		return "", ErrReportNotFound
+	}
+	template := found[0].Template
+
+	content := samplePreviewContent
+	if generationID != nil {
+		gen, err := s.generations.GetByID(ctx, *generationID)
+		if err != nil {
+			return "", err
+		}
+		if gen == nil || gen.ReportID != reportID || gen.Output == "" {
+			return "", ErrGenerationNotFound
+		}
+		content = slotContent(template, gen.Output)
+	}
+
+	preview := htmlComment.ReplaceAllStringFunc(template, func(comment string) string {
+		if templatelint.IsBodySlot(htmlComment.FindStringSubmatch(comment)[1]) {
+			return content
+		}
+		return comment
+	})
+	preview = fillSystemVariables(preview, time.Now())
+	return s.assets.Resolve(ctx, reportID, preview)
+}
+
+// RenderPNG takes a screenshot of the preview with the headless renderer.
+func (s *PreviewService) RenderPNG(ctx context.Context, reportID int, generationID *int, width, height int) ([]byte, error) {
+	if s.renderer == nil {
+		return nil, ErrRendererUnavailable
+	}
+	if width <= 0 {
+		width = DefaultPreviewWidth
+	}
+	if height <= 0 {
+		height = DefaultPreviewHeight
+	}
+	preview, err := s.Render(ctx, reportID, generationID)
+	if err != nil {
+		return nil, err
+	}
+	png, err := s.renderer.RenderPNG(ctx, preview, width, height)
+	if errors.Is(err, renderer.ErrRendererNotConfigured) {
+		return nil, ErrRendererUnavailable
+	}
+	return png, err
+}
+
+// fillSystemVariables replaces the variables every template may use, see
+// templatelint.KnownVariables.
+func fillSystemVariables(template string, now time.Time) string {
+	values := map[string]string{
+		"DATE_TODAY": now.Format("02/01/2006"),
+	}
+	return systemVariableRe.ReplaceAllStringFunc(template, func(token string) string {
+		if value, ok := values[systemVariableRe.FindStringSubmatch(token)[1]]; ok {
+			return value
+		}
+		return token
+	})
+}
+
+// elementKey identifies an element along the path to the body slot.
+type elementKey struct {
+	tag, id, class string
+}
+
+// slotContent extracts from a generation the content of the element that
+// holds the body slot in the template, found by following the same path of
+// tags, ids and classes. It falls back to the whole body of the generation.
+func slotContent(template, output string) string {
+	templateDoc, err := html.Parse(strings.NewReader(template))
+	if err != nil {
+		return output
+	}
+	outputDoc, err := html.Parse(strings.NewReader(output))
+	if err != nil {
+		return output
+	}
+
+	var path []elementKey
+	var walk func(n *html.Node, parents []elementKey) bool
+	walk = func(n *html.Node, parents []elementKey) bool {
+		if n.Type == html.CommentNode && templatelint.IsBodySlot(n.Data) {
+			path = parents
+			return true
+		}
+		if n.Type == html.ElementNode {
+			parents = append(parents, keyOf(n))
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if walk(c, parents) {
+				return true
+			}
+		}
+		return false
+	}
+	walk(templateDoc, nil)
+
+	if container := follow(outputDoc, path); container != nil {
+		return innerHTML(container)
+	}
+	if body := follow(outputDoc, []elementKey{{tag: "html"}, {tag: "body"}}); body != nil {
+		return innerHTML(body)
+	}
+	return output
+}
+
+func keyOf(n *html.Node) elementKey {
+	key := elementKey{tag: n.Data}
+	for _, attr := range n.Attr {
+		switch attr.Key {
+		case "id":
+			key.id = attr.Val
+		case "class":
+			key.class = attr.Val
+		}
+	}
+	return key
+}
+
+// follow descends from n through the first child element matching each key
+// of the path in turn.
+func follow(n *html.Node, path []elementKey) *html.Node {
+	if len(path) == 0 {
+		return nil
+	}
+	for _, key := range path {
+		var next *html.Node
+		for c := n.FirstChild; c != nil && next == nil; c = c.NextSibling {
+			if c.Type == html.ElementNode && keyOf(c) == key {
+				next = c
+			}
+		}
+		if next == nil {
+			return nil
+		}
+		n = next
+	}
+	return n
+}
+
+func innerHTML(n *html.Node) string {
+	var b strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		html.Render(&b, c)
+	}
+	return b.String()
+}
@@ -0,0 +1,299 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"reportia/apperror"
+	"reportia/gallery"
+	"reportia/model"
+	"reportia/templatebundle"
+	"reportia/templatelint"
+	"time"
+)
+
+// Conflict strategies applied when an imported template has the same name as
+// one the user already owns.
+const (
+	ImportSkip      = "skip"
+	ImportOverwrite = "overwrite"
+	ImportRename    = "rename"
+)
+
+// Import actions reported per template.
+const (
+	ImportCreated     = "created"
+	ImportRenamed     = "renamed"
+	ImportOverwritten = "overwritten"
+	ImportSkipped     = "skipped"
+	ImportFailed      = "failed"
+)
+
+var ErrStarterNotFound = apperror.New(apperror.NotFound, "starter_not_found", "starter template not found")
+
+// ImportResult tells what happened to one template of an imported bundle.
+// ReportID is the created, overwritten or conflicting report.
+type ImportResult struct {
+	Name     string               `json:"name"`
+	Action   string               `json:"action"`
+	ReportID int                  `json:"report_id,omitempty"`
+	Error    string               `json:"error,omitempty"`
+	Issues   []templatelint.Issue `json:"issues,omitempty"`
+}
+
+// Export bundles the reports with the given ids, or every report of userMail
+// when no id is given, into a zip. Reports are exported as they are now; no
+// earlier version of a template is kept to export.
+func (s *ReportService) Export(ctx context.Context, ids []int, userMail *string) ([]byte, error) {
+	var reports []model.Report
+	if len(ids) > 0 {
+		if len(ids) > templatebundle.MaxTemplates {
+			return nil, apperror.Validationf("too_many_templates", "export at most %d templates at once", templatebundle.MaxTemplates)
+		}
+		for _, id := range ids {
+			found, _, _, err := s.repo.Search(ctx, model.ReportQuery{ID: id, UserMail: userMail, Page: 1, PageSize: 1})
+			if err != nil {
+				return nil, err
+			}
+			if len(found) == 0 {
+				return nil, fmt.Errorf("%w: %d", ErrReportNotFound, id)
+			}
+			reports = append(reports, found[0])
+		}
+	} else {
+		if userMail == nil {
+			return nil, apperror.Validationf("ids_or_user_mail_required", "ids or user_mail is required")
+		}
+		found, total, _, err := s.repo.Search(ctx, model.ReportQuery{UserMail: userMail, SortBy: "id", Page: 1, PageSize: templatebundle.MaxTemplates})
+		if err != nil {
+			return nil, err
+		}
+		if total > templatebundle.MaxTemplates {
+			return nil, apperror.Validationf("too_many_templates", "user has %d templates, export at most %d at once by id", total, templatebundle.MaxTemplates)
+		}
+		reports = found
+	}
+
+	entries := make([]templatebundle.Entry, 0, len(reports))
+	for _, rep := range reports {
+		assets, err := s.exportAssets(ctx, rep.ID)
+		if err != nil {
+			return nil, err
+		}
+		updateAt := rep.UpdateAt
+		entries = append(entries, templatebundle.Entry{
+			Metadata: model.TemplateMetadata{
+				Name:             rep.Name,
+				Description:      rep.Description,
+				Tags:             rep.Tags,
+				Category:         rep.Category,
+				DefaultLLM:       rep.DefaultLLM,
+				DefaultModel:     rep.DefaultModel,
+				DefaultPrompt:    rep.DefaultPrompt,
+				Thumbnail:        rep.Thumbnail,
+				AllowedFileTypes: rep.AllowedFileTypes,
+				UpdateAt:         &updateAt,
+			},
+			Template: rep.Template,
+			Assets:   assets,
+		})
+	}
+
+	var buf bytes.Buffer
+	if err := templatebundle.Write(&buf, entries, time.Now()); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (s *ReportService) exportAssets(ctx context.Context, reportID int) ([]templatebundle.Asset, error) {
+	if s.assets == nil {
+		return nil, nil
+	}
+	assets, err := s.assets.List(ctx, reportID)
+	if err != nil {
+		return nil, err
+	}
+	exported := make([]templatebundle.Asset, 0, len(assets))
+	for _, asset := range assets {
+		content, err := s.assets.Content(ctx, asset)
+		if err != nil {
+			return nil, fmt.Errorf("asset %s of report %d: %w", asset.Name, reportID, err)
+		}
+		exported = append(exported, templatebundle.Asset{Name: asset.Name, FileName: asset.FileName, Content: content})
+	}
+	return exported, nil
+}
+
+// Import creates the templates of a zip bundle for userMail. A template
+// conflicts with a report of the user having the same non-empty name; the
+// conflict strategy decides whether it is skipped, overwrites that report or
+// is created under a free name. Templates are imported one by one: a template
+// failing validation is reported and does not stop the others. Assets are
+// uploaded after their template and ignored when assets are not available.
+func (s *ReportService) Import(ctx context.Context, bundle []byte, userMail, conflict string) ([]ImportResult, error) {
+	if userMail == "" {
+		return nil, apperror.Validationf("user_mail_required", "user_mail is required")
+	}
+	if conflict == "" {
+		conflict = ImportSkip
+	}
+	if conflict != ImportSkip && conflict != ImportOverwrite && conflict != ImportRename {
+		return nil, apperror.Validationf("invalid_conflict_strategy", "conflict must be %s, %s or %s", ImportSkip, ImportOverwrite, ImportRename)
+	}
+	entries, err := templatebundle.Read(bytes.NewReader(bundle), int64(len(bundle)))
+	if err != nil {
+		return nil, apperror.Wrap(apperror.Validation, "invalid_bundle", err)
+	}
+
+	results := make([]ImportResult, 0, len(entries))
+	for _, entry := range entries {
+		result, err := s.importEntry(ctx, entry, userMail, conflict)
+		if err != nil {
+			if result.Action == "" || result.Action == ImportSkipped {
+				result.Action = ImportFailed
+			}
+			result.Error = err.Error()
+			var lintErr *templatelint.Error
+			if errors.As(err, &lintErr) {
+				result.Error = "template is invalid"
+				result.Issues = lintErr.Issues
+			}
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+func (s *ReportService) importEntry(ctx context.Context, entry templatebundle.Entry, userMail, conflict string) (ImportResult, error) {
+	meta := entry.Metadata
+	result := ImportResult{Name: meta.Name}
+
+	existing, err := s.findByName(ctx, meta.Name, userMail)
+	if err != nil {
+		return result, err
+	}
+	action := ImportCreated
+	if existing != nil {
+		result.ReportID = existing.ID
+		switch conflict {
+		case ImportSkip:
+			result.Action = ImportSkipped
+			return result, nil
+		case ImportOverwrite:
+			rep, err := s.Update(ctx, model.ReportUpdate{
+				ID:               existing.ID,
+				Template:         entry.Template,
+				DataSourceID:     existing.DataSourceID,
+				Name:             &meta.Name,
+				Description:      &meta.Description,
+				Tags:             &meta.Tags,
+				Category:         &meta.Category,
+				DefaultLLM:       &meta.DefaultLLM,
+				DefaultModel:     &meta.DefaultModel,
+				DefaultPrompt:    &meta.DefaultPrompt,
+				Thumbnail:        &meta.Thumbnail,
+				AllowedFileTypes: &meta.AllowedFileTypes,
+			})
+			if err != nil {
+				return result, err
+			}
+			result.Action = ImportOverwritten
+			result.ReportID = rep.ID
+			return result, s.importAssets(ctx, rep.ID, entry.Assets)
+		case ImportRename:
+			if meta.Name, err = s.freeName(ctx, meta.Name, userMail); err != nil {
+				return result, err
+			}
+			result.Name = meta.Name
+			action = ImportRenamed
+		}
+	}
+
+	rep, err := s.createFromMetadata(ctx, meta, entry.Template, userMail)
+	if err != nil {
+		return result, err
+	}
+	result.Action = action
+	result.ReportID = rep.ID
+	return result, s.importAssets(ctx, rep.ID, entry.Assets)
+}
+
+func (s *ReportService) importAssets(ctx context.Context, reportID int, assets []templatebundle.Asset) error {
+	if s.assets == nil {
+		return nil
+	}
+	for _, asset := range assets {
+		if _, err := s.assets.Upload(ctx, reportID, asset.Name, asset.FileName, asset.Content); err != nil {
+			return fmt.Errorf("asset %s: %w", asset.Name, err)
+		}
+	}
+	return nil
+}
+
+// findByName returns nil when the user has no report with that name. Unnamed
+// templates never conflict.
+func (s *ReportService) findByName(ctx context.Context, name, userMail string) (*model.Report, error) {
+	if name == "" {
+		return nil, nil
+	}
+	found, _, _, err := s.repo.Search(ctx, model.ReportQuery{Name: &name, UserMail: &userMail, SortBy: "id", Page: 1, PageSize: 1})
+	if err != nil || len(found) == 0 {
+		return nil, err
+	}
+	return &found[0], nil
+}
+
+// freeName appends the first free " (n)" suffix to name.
+func (s *ReportService) freeName(ctx context.Context, name, userMail string) (string, error) {
+	for n := 2; n <= templatebundle.MaxTemplates+1; n++ {
+		candidate := fmt.Sprintf("%s (%d)", name, n)
+		existing, err := s.findByName(ctx, candidate, userMail)
+		if err != nil {
+			return "", err
+		}
+		if existing == nil {
+			return candidate, nil
+		}
+	}
+	return "", fmt.Errorf("no free name left for %q", name)
+}
+
+func (s *ReportService) createFromMetadata(ctx context.Context, meta model.TemplateMetadata, template, userMail string) (*model.Report, error) {
+	return s.Create(ctx, model.Report{
+		Name:             meta.Name,
+		Description:      meta.Description,
+		Tags:             meta.Tags,
+		Category:         meta.Category,
+		Template:         template,
+		DefaultLLM:       meta.DefaultLLM,
+		DefaultModel:     meta.DefaultModel,
+		DefaultPrompt:    meta.DefaultPrompt,
+		Thumbnail:        meta.Thumbnail,
+		AllowedFileTypes: meta.AllowedFileTypes,
+		UserMail:         userMail,
+	})
+}
+
+// ListStarters returns the starter templates of the built-in gallery.
+func (s *ReportService) ListStarters() ([]gallery.Starter, error) {
+	return gallery.List()
+}
+
+// CloneStarter creates a report for userMail from a gallery starter. An empty
+// name keeps the starter's name.
+func (s *ReportService) CloneStarter(ctx context.Context, slug, userMail, name string) (*model.Report, error) {
+	starter, err := gallery.Get(slug)
+	if err != nil {
+		return nil, err
+	}
+	if starter == nil {
+		return nil, ErrStarterNotFound
+	}
+	meta := starter.TemplateMetadata
+	if name != "" {
+		meta.Name = name
+	}
+	return s.createFromMetadata(ctx, meta, starter.Template, userMail)
+}
@@ -0,0 +1,131 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"reportia/apperror"
+	"reportia/model"
+	"reportia/repository"
+	"reportia/templatebundle"
+	"testing"
+	"time"
+)
+
+const bundleTemplate = "<html><body><h1>imported</h1><!-- DYNAMIC BODY CONTENT HERE --></body></html>"
+
+func bundle(t *testing.T, entries ...templatebundle.Entry) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := templatebundle.Write(&buf, entries, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestImportConflicts(t *testing.T) {
+	ctx := context.Background()
+	imported := bundle(t,
+		templatebundle.Entry{Metadata: model.TemplateMetadata{Name: "Sales", Category: "imported"}, Template: bundleTemplate},
+		templatebundle.Entry{Metadata: model.TemplateMetadata{Name: "Stock"}, Template: bundleTemplate},
+		templatebundle.Entry{Metadata: model.TemplateMetadata{Name: "Broken"}, Template: "<div>"},
+	)
+
+	for _, tc := range []struct {
+		conflict string
+		want     []ImportResult
+		// names are the names of the user's reports after the import.
+		names []string
+	}{
+		{"", []ImportResult{
+			{Name: "Sales", Action: ImportSkipped, ReportID: 1},
+			{Name: "Stock", Action: ImportCreated, ReportID: 4},
+			{Name: "Broken", Action: ImportFailed},
+		}, []string{"Sales", "Sales (2)", "Other", "Stock"}},
+		{ImportOverwrite, []ImportResult{
+			{Name: "Sales", Action: ImportOverwritten, ReportID: 1},
+			{Name: "Stock", Action: ImportCreated, ReportID: 4},
+			{Name: "Broken", Action: ImportFailed},
+		}, []string{"Sales", "Sales (2)", "Other", "Stock"}},
+		{ImportRename, []ImportResult{
+			{Name: "Sales (3)", Action: ImportRenamed, ReportID: 4},
+			{Name: "Stock", Action: ImportCreated, ReportID: 5},
+			{Name: "Broken", Action: ImportFailed},
+		}, []string{"Sales", "Sales (2)", "Other", "Sales (3)", "Stock"}},
+	} {
+		t.Run(tc.conflict, func(t *testing.T) {
+			s := NewReportService(repository.NewMemoryReportStore(), nil, nil, nil, nil, nil, nil, nil)
+			for _, rep := range []model.Report{
+				{Name: "Sales", Template: bundleTemplate, UserMail: "owner@example.com"},
+				{Name: "Sales (2)", Template: bundleTemplate, UserMail: "owner@example.com"},
+				// Names only conflict within a user.
+				{Name: "Other", Template: bundleTemplate, UserMail: "someone@example.com"},
+			} {
+				if _, err := s.Create(ctx, rep); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			results, err := s.Import(ctx, imported, "owner@example.com", tc.conflict)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(results) != len(tc.want) {
+				t.Fatalf("got %+v, want %+v", results, tc.want)
+			}
+			for i, want := range tc.want {
+				got := results[i]
+				if got.Name != want.Name || got.Action != want.Action || got.ReportID != want.ReportID {
+					t.Errorf("result %d: got %+v, want %+v", i, got, want)
+				}
+			}
+			if broken := results[2]; broken.Error != "template is invalid" || len(broken.Issues) == 0 {
+				t.Errorf("invalid template: got %+v, want its lint issues", broken)
+			}
+
+			reports, err := s.List(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			names := map[string]model.Report{}
+			for _, rep := range reports {
+				names[rep.Name] = rep
+			}
+			if len(reports) != len(tc.names) {
+				t.Errorf("got %d reports, want %v", len(reports), tc.names)
+			}
+			for _, name := range tc.names {
+				if _, ok := names[name]; !ok {
+					t.Errorf("no report named %q", name)
+				}
+			}
+			if overwritten := names["Sales"].Category == "imported"; overwritten != (tc.conflict == ImportOverwrite) {
+				t.Errorf("report Sales overwritten: %v", overwritten)
+			}
+		})
+	}
+}
+
+func TestImportRejects(t *testing.T) {
+	s := NewReportService(repository.NewMemoryReportStore(), nil, nil, nil, nil, nil, nil, nil)
+	valid := bundle(t, templatebundle.Entry{Metadata: model.TemplateMetadata{Name: "Sales"}, Template: bundleTemplate})
+	for _, tc := range []struct {
+		name     string
+		bundle   []byte
+		userMail string
+		conflict string
+		code     string
+	}{
+		{"no user", valid, "", "", "user_mail_required"},
+		{"unknown strategy", valid, "owner@example.com", "merge", "invalid_conflict_strategy"},
+		{"not a bundle", []byte("not a zip"), "owner@example.com", "", "invalid_bundle"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := s.Import(context.Background(), tc.bundle, tc.userMail, tc.conflict)
+			var appErr *apperror.Error
+			if !errors.As(err, &appErr) || appErr.Code != tc.code {
+				t.Errorf("got %v, want %s", err, tc.code)
+			}
+		})
+	}
+}
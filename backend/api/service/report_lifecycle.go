@@ -0,0 +1,85 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"reportia/model"
+	"time"
+)
+
+// purgeBatchSize is how many trashed reports PurgeDeleted lists at a time.
+const purgeBatchSize = 100
+
+// Delete moves a report to the trash. It stops being listed, generated or
+// edited until it is restored, and is purged for good by the retention job.
+func (s *ReportService) Delete(ctx context.Context, id int) error {
+	return notFoundOnNoRows(s.repo.SoftDelete(ctx, id), ErrReportNotFound)
+}
+
+// Restore takes a report back out of the trash.
+func (s *ReportService) Restore(ctx context.Context, id int) (*model.Report, error) {
+	if err := s.repo.Restore(ctx, id); err != nil {
+		return nil, notFoundOnNoRows(err, ErrReportNotFound)
+	}
+	found, _, _, err := s.repo.Search(ctx, model.ReportQuery{ID: id, Page: 1, PageSize: 1})
+	if err != nil {
+		return nil, err
+	}
+	if len(found) == 0 {
+		return nil, ErrReportNotFound
+	}
+	return &found[0], nil
+}
+
+// HardDelete removes a report, in the trash or not, with its schedules,
+// generations, deliveries, share links and assets. Asset files are deleted
+// from storage once the rows are gone.
+func (s *ReportService) HardDelete(ctx context.Context, id int) error {
+	var assets []model.Asset
+	if s.assets != nil {
+		var err error
+		if assets, err = s.assets.repo.ListByReport(ctx, id); err != nil {
+			return err
+		}
+	}
+	if err := s.repo.HardDelete(ctx, id); err != nil {
+		return notFoundOnNoRows(err, ErrReportNotFound)
+	}
+	for _, asset := range assets {
+		s.assets.deleteObject(ctx, asset.StorageKey)
+	}
+	return nil
+}
+
+// PurgeDeleted hard deletes the reports that have been in the trash for
+// longer than retention and returns how many were removed.
+func (s *ReportService) PurgeDeleted(ctx context.Context, retention time.Duration) (int, error) {
+	before := time.Now().Add(-retention)
+	purged := 0
+	for ctx.Err() == nil {
+		ids, err := s.repo.ListDeletedBefore(ctx, before, purgeBatchSize)
+		if err != nil {
+			return purged, err
+		}
+		for _, id := range ids {
+			// Another replica may have purged the report already.
+			err := s.HardDelete(ctx, id)
+			if errors.Is(err, ErrReportNotFound) {
+				continue
+			}
+			if err != nil {
+				return purged, fmt.Errorf("report %d: %w", id, err)
+			}
+			purged++
+		}
+		if len(ids) < purgeBatchSize {
+			break
+		}
+	}
+	if purged > 0 {
+		slog.InfoContext(ctx, "retention: purged deleted reports", "count", purged)
+	}
+	return purged, ctx.Err()
+}
@@ -0,0 +1,132 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"reportia/model"
+	"reportia/repository"
+	"slices"
+	"testing"
+	"time"
+)
+
+// batchingStore records the batches PurgeDeleted lists, and can have reports
+// purged by another replica between the listing and the delete.
+type batchingStore struct {
+	*repository.MemoryReportStore
+	batches []int
+	// purgedElsewhere are deleted before HardDelete runs, as if by another
+	// replica.
+	purgedElsewhere map[int]bool
+	// afterBatch runs once a batch is listed.
+	afterBatch func()
+}
+
+func (s *batchingStore) ListDeletedBefore(ctx context.Context, before time.Time, limit int) ([]int, error) {
+	ids, err := s.MemoryReportStore.ListDeletedBefore(ctx, before, limit)
+	s.batches = append(s.batches, len(ids))
+	if s.afterBatch != nil {
+		s.afterBatch()
+	}
+	return ids, err
+}
+
+func (s *batchingStore) HardDelete(ctx context.Context, id int) error {
+	if s.purgedElsewhere[id] {
+		s.MemoryReportStore.HardDelete(ctx, id)
+		return sql.ErrNoRows
+	}
+	return s.MemoryReportStore.HardDelete(ctx, id)
+}
+
+// newTrash creates trashed reports and one live report.
+func newTrash(t *testing.T, trashed int) *batchingStore {
+	t.Helper()
+	ctx := context.Background()
+	store := &batchingStore{MemoryReportStore: repository.NewMemoryReportStore()}
+	for i := range trashed + 1 {
+		rep, err := store.Create(ctx, model.Report{Name: "report", Template: "<!-- DYNAMIC BODY CONTENT HERE -->", UserMail: "owner@example.com"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i < trashed {
+			if err := store.SoftDelete(ctx, rep.ID); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	return store
+}
+
+func TestPurgeDeletedBatches(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		trashed   int
+		retention time.Duration
+		purged    int
+		batches   []int
+	}{
+		{"empty trash", 0, -time.Hour, 0, []int{0}},
+		{"one batch", 3, -time.Hour, 3, []int{3}},
+		{"full batches", 2 * purgeBatchSize, -time.Hour, 2 * purgeBatchSize, []int{purgeBatchSize, purgeBatchSize, 0}},
+		{"partial last batch", 2*purgeBatchSize + 1, -time.Hour, 2*purgeBatchSize + 1, []int{purgeBatchSize, purgeBatchSize, 1}},
+		{"within retention", 3, time.Hour, 0, []int{0}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			store := newTrash(t, tc.trashed)
+			s := NewReportService(store, nil, nil, nil, nil, nil, nil, nil)
+
+			purged, err := s.PurgeDeleted(context.Background(), tc.retention)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if purged != tc.purged {
+				t.Errorf("got %d purged, want %d", purged, tc.purged)
+			}
+			if !slices.Equal(store.batches, tc.batches) {
+				t.Errorf("got batches %v, want %v", store.batches, tc.batches)
+			}
+			// The live report is never purged.
+			if reports, _ := s.List(context.Background()); len(reports) != 1 {
+				t.Errorf("got %d live reports, want 1", len(reports))
+			}
+		})
+	}
+}
+
+func TestPurgeDeletedSkipsReportsPurgedElsewhere(t *testing.T) {
+	store := newTrash(t, purgeBatchSize+10)
+	store.purgedElsewhere = map[int]bool{}
+	for id := 1; id <= purgeBatchSize+10; id += 2 {
+		store.purgedElsewhere[id] = true
+	}
+	s := NewReportService(store, nil, nil, nil, nil, nil, nil, nil)
+
+	purged, err := s.PurgeDeleted(context.Background(), -time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (purgeBatchSize + 10) / 2; purged != want {
+		t.Errorf("got %d purged, want %d", purged, want)
+	}
+	if want := []int{purgeBatchSize, 10}; !slices.Equal(store.batches, want) {
+		t.Errorf("got batches %v, want %v", store.batches, want)
+	}
+}
+
+func TestPurgeDeletedStopsWhenCancelled(t *testing.T) {
+	store := newTrash(t, 3*purgeBatchSize)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	store.afterBatch = cancel
+	s := NewReportService(store, nil, nil, nil, nil, nil, nil, nil)
+
+	purged, err := s.PurgeDeleted(ctx, -time.Hour)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("got %v, want context.Canceled", err)
+	}
+	if purged != purgeBatchSize || len(store.batches) != 1 {
+		t.Errorf("got %d purged in batches %v, want one batch of %d", purged, store.batches, purgeBatchSize)
+	}
+}
@@ -3,140 +3,441 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
 	"io"
+	"log/slog"
+	"mime"
+	"os"
+	"path/filepath"
+	"reportia/apperror"
+	"reportia/datasource"
 	"reportia/helper"
 	LLMFactory "reportia/integration/llm"
+	"reportia/metrics"
 	"reportia/model"
 	const_model "reportia/model/const"
 	"reportia/repository"
+	"reportia/templatelint"
+	"reportia/tracing"
+	"reportia/upload"
+	"slices"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type ReportService struct {
-	repo *repository.ReportRepository
+	repo        repository.ReportStore
+	generations repository.GenerationStore
+	dataSources *DataSourceService
+	deliveries  *DeliveryService
+	webhooks    *WebhookService
+	assets      *AssetService
+	llms        *LLMFactory.Providers
+	// fileTypes is the allowlist of uploads for the reports without one.
+	fileTypes []string
 }
 
-func NewReportService(repo *repository.ReportRepository) *ReportService {
-	return &ReportService{repo: repo}
+func NewReportService(repo repository.ReportStore, generations repository.GenerationStore, dataSources *DataSourceService, deliveries *DeliveryService, webhooks *WebhookService, assets *AssetService, llms *LLMFactory.Providers, fileTypes []string) *ReportService {
+	return &ReportService{repo: repo, generations: generations, dataSources: dataSources, deliveries: deliveries, webhooks: webhooks, assets: assets, llms: llms, fileTypes: fileTypes}
 }
 
+var (
+	ErrReportNotFound = apperror.New(apperror.NotFound, "report_not_found", "report not found")
+	ErrReportInactive = apperror.New(apperror.Inactive, "report_inactive", "the report that you select was inactive")
+)
+
 func (s *ReportService) List(ctx context.Context) ([]model.Report, error) {
-	return s.repo.List(ctx)
+	reports, _, _, err := s.repo.Search(ctx, model.ReportQuery{SortDesc: true, Page: 1, PageSize: 1000})
+	return reports, err
 }
 
-func (s *ReportService) ListWithPagination(ctx context.Context, page, pageSize int) (*model.PaginatedReports, error) {
-	if page <= 0 {
-		page = 1
+// Search lists reports matching q. Page defaults to 1 and PageSize to 10,
+// capped at 100; reports are sorted by creation date unless q sorts otherwise.
+func (s *ReportService) Search(ctx context.Context, q model.ReportQuery) (*model.PaginatedReports, error) {
+	if q.Page <= 0 {
+		q.Page = 1
 	}
-	if pageSize <= 0 {
-		pageSize = 10
+	if q.PageSize <= 0 {
+		q.PageSize = 10
 	}
-	if pageSize > 100 {
-		pageSize = 100
+	if q.PageSize > 100 {
+		q.PageSize = 100
 	}
 
-	reports, err := s.repo.ListWithPagination(ctx, page, pageSize)
-	if err != nil {
-		return nil, err
+	reports, totalCount, nextCursor, err := s.repo.Search(ctx, q)
+	if errors.Is(err, repository.ErrInvalidReportQuery) {
+		return nil, apperror.Wrap(apperror.Validation, "invalid_report_query", err)
 	}
-
-	totalCount, err := s.repo.GetTotalCount(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	totalPages := (totalCount + pageSize - 1) / pageSize
+	page := q.Page
+	if q.Cursor != "" {
+		page = 0
+	}
+	totalPages := (totalCount + q.PageSize - 1) / q.PageSize
 
 	return &model.PaginatedReports{
 		Reports:    reports,
 		TotalCount: totalCount,
 		Page:       page,
-		PageSize:   pageSize,
+		PageSize:   q.PageSize,
 		TotalPages: totalPages,
+		NextCursor: nextCursor,
 	}, nil
 }
 
+// Get returns a report, active or not, unless it is in the trash.
+func (s *ReportService) Get(ctx context.Context, id int) (*model.Report, error) {
+	found, _, _, err := s.repo.Search(ctx, model.ReportQuery{ID: id, Page: 1, PageSize: 1})
+	if err != nil {
+		return nil, err
+	}
+	if len(found) == 0 {
+		return nil, ErrReportNotFound
+	}
+	return &found[0], nil
+}
+
 func (s *ReportService) Filter(ctx context.Context, id int, userMail *string) (*model.Report, error) {
 	rep, err := s.repo.Filter(ctx, id, userMail)
-	if err == repository.ErrReportInactive {
-		return nil, errors.New("the report that you select was inactive")
+	if errors.Is(err, repository.ErrReportInactive) {
+		return nil, ErrReportInactive
 	}
 	return rep, err
 }
 
 func (s *ReportService) FilterWithPagination(ctx context.Context, id int, userMail *string, page, pageSize int) (*model.PaginatedReports, error) {
-	if page <= 0 {
-		page = 1
+	paginated, err := s.Search(ctx, model.ReportQuery{ID: id, UserMail: userMail, Page: page, PageSize: pageSize})
+	if err != nil {
+		return nil, err
+	}
+	for _, rep := range paginated.Reports {
+		if !rep.Active {
+			return nil, ErrReportInactive
+		}
 	}
-	if pageSize <= 0 {
-		pageSize = 10
+	return paginated, nil
+}
+
+func (s *ReportService) Create(ctx context.Context, rep model.Report) (*model.Report, error) {
+	if rep.Template == "" || rep.UserMail == "" {
+		return nil, apperror.Validationf("template_and_user_mail_required", "template and user_mail are required")
 	}
-	if pageSize > 100 {
-		pageSize = 100
+	if err := templatelint.Check(rep.Template); err != nil {
+		return nil, err
+	}
+	rep.Template = helper.RemoveAllSpacesAndBreakingLines(rep.Template)
+	rep.Tags = normalizeTags(rep.Tags)
+	fileTypes, err := normalizeFileTypes(rep.AllowedFileTypes)
+	if err != nil {
+		return nil, err
 	}
+	rep.AllowedFileTypes = fileTypes
+	return s.repo.Create(ctx, rep)
+}
 
-	reports, totalCount, err := s.repo.FilterWithPagination(ctx, id, userMail, page, pageSize)
-	if err == repository.ErrReportInactive {
-		return nil, errors.New("the report that you select was inactive")
+func (s *ReportService) Update(ctx context.Context, upd model.ReportUpdate) (*model.Report, error) {
+	if upd.ID == 0 || upd.Template == "" {
+		return nil, apperror.Validationf("id_and_template_required", "id and template are required")
+	}
+	if err := templatelint.Check(upd.Template); err != nil {
+		return nil, err
 	}
+	upd.Template = helper.RemoveAllSpacesAndBreakingLines(upd.Template)
+	return s.save(ctx, upd)
+}
+
+// Patch changes the fields set in patch. The template is linted only when it
+// is replaced.
+func (s *ReportService) Patch(ctx context.Context, id int, patch model.ReportPatch) (*model.Report, error) {
+	rep, err := s.Get(ctx, id)
 	if err != nil {
 		return nil, err
 	}
+	upd := model.ReportUpdate{
+		ID:               id,
+		Template:         rep.Template,
+		DataSourceID:     rep.DataSourceID,
+		Name:             patch.Name,
+		Description:      patch.Description,
+		Tags:             patch.Tags,
+		Category:         patch.Category,
+		DefaultLLM:       patch.DefaultLLM,
+		DefaultModel:     patch.DefaultModel,
+		DefaultPrompt:    patch.DefaultPrompt,
+		Thumbnail:        patch.Thumbnail,
+		AllowedFileTypes: patch.AllowedFileTypes,
+	}
+	if patch.Template != nil {
+		if err := templatelint.Check(*patch.Template); err != nil {
+			return nil, err
+		}
+		upd.Template = helper.RemoveAllSpacesAndBreakingLines(*patch.Template)
+	}
+	if patch.DataSourceID != nil {
+		upd.DataSourceID = patch.DataSourceID
+	}
+	content := patch
+	content.Active = nil
+	if content != (model.ReportPatch{}) {
+		if rep, err = s.save(ctx, upd); err != nil {
+			return nil, err
+		}
+	}
+	if patch.Active != nil && *patch.Active != rep.Active {
+		if err := s.TurnOnOff(ctx, id, *patch.Active); err != nil {
+			return nil, err
+		}
+		rep.Active = *patch.Active
+	}
+	return rep, nil
+}
 
-	totalPages := (totalCount + pageSize - 1) / pageSize
+// save stores an update and announces it.
+func (s *ReportService) save(ctx context.Context, upd model.ReportUpdate) (*model.Report, error) {
+	if upd.Tags != nil {
+		tags := normalizeTags(*upd.Tags)
+		upd.Tags = &tags
+	}
+	if upd.AllowedFileTypes != nil {
+		fileTypes, err := normalizeFileTypes(*upd.AllowedFileTypes)
+		if err != nil {
+			return nil, err
+		}
+		upd.AllowedFileTypes = &fileTypes
+	}
+	rep, err := s.repo.Update(ctx, upd)
+	if err != nil {
+		return nil, notFoundOnNoRows(err, ErrReportNotFound)
+	}
+	s.webhooks.Publish(ctx, model.EventTemplateUpdated, map[string]any{
+		"report_id": rep.ID,
+		"update_at": rep.UpdateAt,
+	})
+	return rep, nil
+}
 
-	return &model.PaginatedReports{
-		Reports:    reports,
-		TotalCount: totalCount,
-		Page:       page,
-		PageSize:   pageSize,
-		TotalPages: totalPages,
-	}, nil
+// ValidateTemplate lints a template without saving it. Templates are linted
+// as sent, before whitespace is stripped, so issue positions match the input.
+func (s *ReportService) ValidateTemplate(template string) templatelint.Result {
+	return templatelint.Lint(template)
 }
 
-func (s *ReportService) Create(ctx context.Context, template, userMail string) (*model.Report, error) {
-	if template == "" || userMail == "" {
-		return nil, errors.New("template and user_mail are required")
+// normalizeTags lowercases and trims tags, dropping empty and repeated ones,
+// so that tag filters match regardless of how a tag was typed.
+func normalizeTags(tags []string) []string {
+	normalized := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag != "" && !slices.Contains(normalized, tag) {
+			normalized = append(normalized, tag)
+		}
 	}
-	template = helper.RemoveAllSpacesAndBreakingLines(template)
-	return s.repo.Create(ctx, template, userMail)
+	return normalized
 }
 
-func (s *ReportService) Update(ctx context.Context, id int, template string) (*model.Report, error) {
-	if id == 0 || template == "" {
-		return nil, errors.New("id and template are required")
+// normalizeFileTypes lowercases and deduplicates an upload allowlist and
+// rejects the entries that are neither extensions nor content types.
+func normalizeFileTypes(fileTypes []string) ([]string, error) {
+	normalized := normalizeTags(fileTypes)
+	if err := upload.ValidAllowlist(normalized); err != nil {
+		return nil, apperror.Validationf("invalid_allowed_file_types", "%s", err)
 	}
-	template = helper.RemoveAllSpacesAndBreakingLines(template)
-	return s.repo.Update(ctx, id, template)
+	return normalized, nil
 }
 
 func (s *ReportService) TurnOnOff(ctx context.Context, id int, active bool) error {
 	if id == 0 {
-		return errors.New("id is required")
+		return errIDRequired
 	}
-	return s.repo.TurnOnOff(ctx, id, active)
+	return notFoundOnNoRows(s.repo.TurnOnOff(ctx, id, active), ErrReportNotFound)
+}
+
+// GenerationInput identifies the template and LLM used for a generation.
+// ScheduleID is set when the generation is triggered by a schedule. Empty
+// LLM, Model and Prompt fall back to the template's defaults.
+type GenerationInput struct {
+	ReportID   int
+	ScheduleID *int
+	Prompt     string
+	LLM        string
+	Model      string
 }
 
-func (s *ReportService) GenerateReportFromFile(ctx context.Context, idReportTemplate int, promptUser string, llm string, model string, file io.Reader, fileName string, fileType string) (string, error) {
-	if file == nil || fileName == "" || fileType == "" {
-		return "", errors.New("file, fileName and fileType are required")
+type fetchInputFunc func(ctx context.Context, reportModel *model.Report) (*datasource.Data, error)
+
+// GenerateReportFromFile runs an uploaded file through the pipeline. The file
+// must match the allowlist of the report, or the server's when the report
+// has none; it is rejected before any generation is recorded.
+func (s *ReportService) GenerateReportFromFile(ctx context.Context, in GenerationInput, file *upload.File) (*model.Generation, error) {
+	if file == nil {
+		return nil, apperror.Validationf("file_required", "file is required")
+	}
+	accept := func(reportModel *model.Report) error {
+		allowlist := reportModel.AllowedFileTypes
+		if len(allowlist) == 0 {
+			allowlist = s.fileTypes
+		}
+		return upload.Allowed(file, allowlist)
 	}
-	reportModel, err := s.repo.Filter(ctx, idReportTemplate, nil)
+	return s.generate(ctx, in, accept, func(ctx context.Context, _ *model.Report) (*datasource.Data, error) {
+		return &datasource.Data{Reader: io.NopCloser(file), FileName: file.Name, FileType: file.ContentType}, nil
+	})
+}
+
+// GenerateReportFromPath reads the input from a file on the server, as used by
+// schedules pointing at a fixed export location.
+func (s *ReportService) GenerateReportFromPath(ctx context.Context, in GenerationInput, path string) (*model.Generation, error) {
+	if path == "" {
+		return nil, apperror.Validationf("path_required", "path is required")
+	}
+	return s.generate(ctx, in, nil, func(ctx context.Context, _ *model.Report) (*datasource.Data, error) {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("could not open source file: %w", err)
+		}
+		fileName := filepath.Base(path)
+		fileType := mime.TypeByExtension(filepath.Ext(fileName))
+		if fileType == "" {
+			fileType = "text/plain"
+		}
+		return &datasource.Data{Reader: file, FileName: fileName, FileType: fileType}, nil
+	})
+}
+
+// GenerateReportFromDataSource fetches fresh data and runs it through the same
+// pipeline as an uploaded file. When dataSourceID is nil the report's own data
+// source is used.
+func (s *ReportService) GenerateReportFromDataSource(ctx context.Context, in GenerationInput, dataSourceID *int) (*model.Generation, error) {
+	return s.generate(ctx, in, nil, func(ctx context.Context, reportModel *model.Report) (*datasource.Data, error) {
+		if dataSourceID == nil {
+			dataSourceID = reportModel.DataSourceID
+		}
+		if dataSourceID == nil {
+			return nil, apperror.Validationf("data_source_required", "report has no data source, upload a file instead")
+		}
+		if s.dataSources == nil {
+			return nil, apperror.New(apperror.NotImplemented, "data_sources_unavailable", "data sources are not available in this storage mode")
+		}
+		return s.dataSources.Fetch(ctx, *dataSourceID)
+	})
+}
+
+// generate records a Generation around fetching the input and running the LLM
+// pipeline, so failures are persisted alongside successes. accept, when set,
+// may reject the request for the report before anything is recorded. The
+// returned generation is non-nil whenever it was recorded, even if err is set.
+func (s *ReportService) generate(ctx context.Context, in GenerationInput, accept func(*model.Report) error, fetch fetchInputFunc) (gen *model.Generation, err error) {
+	ctx, span := tracing.Start(ctx, "report.generate", trace.WithAttributes(attribute.Int("report.id", in.ReportID)))
+	defer func() { tracing.End(span, err) }()
+
+	reportModel, err := s.Filter(ctx, in.ReportID, nil)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	if reportModel == nil {
-		return "", errors.New("report not found")
+		return nil, ErrReportNotFound
+	}
+	if accept != nil {
+		if err := accept(reportModel); err != nil {
+			return nil, err
+		}
+	}
+	if in.LLM == "" {
+		in.LLM = reportModel.DefaultLLM
+	}
+	if in.Model == "" {
+		in.Model = reportModel.DefaultModel
+	}
+	if in.Prompt == "" {
+		in.Prompt = reportModel.DefaultPrompt
+	}
+
+	gen, err = s.generations.Start(ctx, in.ReportID, in.ScheduleID, in.LLM, in.Model)
+	if err != nil {
+		return nil, err
 	}
-	promptToLLM := const_model.GetPromptToGenerateAnalysisFromFile(reportModel.Template, promptUser)
-	llmInstance := LLMFactory.NewLLM(llm)
-	responseLLM, err := llmInstance.GenerateAnalisysFromReportFile(ctx, promptToLLM, model, file, fileName, fileType)
+
+	output, genErr := s.generateHTML(ctx, reportModel, in, fetch)
+	if genErr != nil {
+		slog.WarnContext(ctx, "generation failed", "report_id", in.ReportID, "generation_id", gen.ID, "llm", in.LLM, "error", genErr)
+		gen.Status = model.GenerationStatusFailed
+		gen.Error = genErr.Error()
+	} else {
+		gen.Status = model.GenerationStatusSucceeded
+		gen.Output = output
+	}
+	if err := s.generations.Finish(ctx, gen.ID, gen.Status, gen.Output, gen.Error); err != nil {
+		return nil, err
+	}
+	finishedAt := time.Now()
+	gen.FinishedAt = &finishedAt
+	trigger := "manual"
+	if in.ScheduleID != nil {
+		trigger = "schedule"
+	}
+	metrics.Generations.WithLabelValues(gen.Status, trigger).Inc()
+	s.publishGeneration(ctx, gen)
+	if genErr != nil {
+		return gen, genErr
+	}
+	s.deliveries.Enqueue(ctx, *gen)
+	return gen, nil
+}
+
+func (s *ReportService) publishGeneration(ctx context.Context, gen *model.Generation) {
+	eventType := model.EventGenerationSucceeded
+	if gen.Status == model.GenerationStatusFailed {
+		eventType = model.EventGenerationFailed
+	}
+	s.webhooks.Publish(ctx, eventType, map[string]any{
+		"generation_id": gen.ID,
+		"report_id":     gen.ReportID,
+		"schedule_id":   gen.ScheduleID,
+		"llm":           gen.LLM,
+		"model":         gen.Model,
+		"status":        gen.Status,
+		"error":         gen.Error,
+		"started_at":    gen.StartedAt,
+		"finished_at":   gen.FinishedAt,
+	})
+}
+
+func (s *ReportService) generateHTML(ctx context.Context, reportModel *model.Report, in GenerationInput, fetch fetchInputFunc) (string, error) {
+	fetchCtx, span := tracing.Start(ctx, "report.fetch_input")
+	data, err := fetch(fetchCtx, reportModel)
+	tracing.End(span, err)
 	if err != nil {
 		return "", err
 	}
+	defer data.Reader.Close()
+
+	promptToLLM := const_model.GetPromptToGenerateAnalysisFromFile(reportModel.Template, in.Prompt)
+	llmInstance := s.llms.New(in.LLM)
+	responseLLM, err := LLMFactory.GenerateAnalisysFromReportFile(llmInstance, ctx, promptToLLM, in.Model, data.Reader, data.FileName, data.FileType)
+	if errors.Is(err, LLMFactory.ErrLLMNotConfigured) {
+		return "", apperror.Wrap(apperror.Validation, "llm_required", err)
+	}
+	if err != nil {
+		return "", apperror.Wrap(apperror.Upstream, "llm_failed", err)
+	}
+	return s.postProcess(ctx, reportModel.ID, responseLLM)
+}
+
+// postProcess turns the raw LLM answer into the stored HTML.
+func (s *ReportService) postProcess(ctx context.Context, reportID int, responseLLM string) (output string, err error) {
+	ctx, span := tracing.Start(ctx, "report.post_process")
+	defer func() { tracing.End(span, err) }()
+
 	responseLLMWithoutSpecialBlockingLLMCharacters := helper.RemoveSpecialBlockingLLMCharacters(responseLLM)
 	htmlReport, err := helper.FormatHTML(responseLLMWithoutSpecialBlockingLLMCharacters)
 	if err != nil {
-		return "", errors.New("error formatting HTML: " + err.Error())
+		return "", &apperror.Error{Kind: apperror.Upstream, Code: "llm_invalid_output", Message: "error formatting HTML", Err: err}
 	}
-	return htmlReport, nil
+	return s.assets.Resolve(ctx, reportID, htmlReport)
 }
@@ -0,0 +1,172 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"reportia/apperror"
+	"reportia/helper"
+	"reportia/model"
+	"reportia/repository"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+const defaultScheduleTimezone = "UTC"
+
+var ErrScheduleNotFound = apperror.New(apperror.NotFound, "schedule_not_found", "schedule not found")
+
+type ScheduleService struct {
+	repo          *repository.ScheduleRepository
+	generations   repository.GenerationStore
+	reportService *ReportService
+	// sourceDir confines the source_path of schedules.
+	sourceDir string
+}
+
+func NewScheduleService(repo *repository.ScheduleRepository, generations repository.GenerationStore, reportService *ReportService, sourceDir string) *ScheduleService {
+	return &ScheduleService{repo: repo, generations: generations, reportService: reportService, sourceDir: sourceDir}
+}
+
+func (s *ScheduleService) List(ctx context.Context) ([]model.Schedule, error) {
+	return s.repo.List(ctx)
+}
+
+func (s *ScheduleService) Get(ctx context.Context, id int) (*model.Schedule, error) {
+	return s.repo.GetByID(ctx, id)
+}
+
+func (s *ScheduleService) ListDue(ctx context.Context, now time.Time) ([]model.Schedule, error) {
+	return s.repo.ListDue(ctx, now)
+}
+
+func (s *ScheduleService) Create(ctx context.Context, sch model.Schedule) (*model.Schedule, error) {
+	if err := s.validateScheduleSource(sch); err != nil {
+		return nil, err
+	}
+	if sch.Timezone == "" {
+		sch.Timezone = defaultScheduleTimezone
+	}
+	nextRunAt, err := NextScheduleRun(sch.CronExpression, sch.Timezone, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	sch.NextRunAt = nextRunAt
+	return s.repo.Create(ctx, sch)
+}
+
+func (s *ScheduleService) Update(ctx context.Context, sch model.Schedule) (*model.Schedule, error) {
+	if err := s.validateScheduleSource(sch); err != nil {
+		return nil, err
+	}
+	if sch.Timezone == "" {
+		sch.Timezone = defaultScheduleTimezone
+	}
+	nextRunAt, err := NextScheduleRun(sch.CronExpression, sch.Timezone, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	sch.NextRunAt = nextRunAt
+	updated, err := s.repo.Update(ctx, sch)
+	if err != nil {
+		return nil, err
+	}
+	if updated == nil {
+		return nil, ErrScheduleNotFound
+	}
+	return updated, nil
+}
+
+func (s *ScheduleService) TurnOnOff(ctx context.Context, id int, active bool) error {
+	if id == 0 {
+		return errIDRequired
+	}
+	sch, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if sch == nil {
+		return ErrScheduleNotFound
+	}
+	var nextRunAt *time.Time
+	if active {
+		nextRunAt, err = NextScheduleRun(sch.CronExpression, sch.Timezone, time.Now())
+		if err != nil {
+			return err
+		}
+	}
+	return s.repo.TurnOnOff(ctx, id, active, nextRunAt)
+}
+
+func (s *ScheduleService) ListRuns(ctx context.Context, id, limit int) ([]model.Generation, error) {
+	if limit <= 0 {
+		limit = 20
+	}
+	if limit > 100 {
+		limit = 100
+	}
+	return s.generations.ListBySchedule(ctx, id, limit)
+}
+
+// Run advances the schedule to its next occurrence before generating, so a
+// crash mid-run never causes the same occurrence to be executed twice.
+func (s *ScheduleService) Run(ctx context.Context, sch model.Schedule) (*model.Generation, error) {
+	startedAt := time.Now()
+	nextRunAt, err := NextScheduleRun(sch.CronExpression, sch.Timezone, startedAt)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.MarkRun(ctx, sch.ID, startedAt, nextRunAt); err != nil {
+		return nil, err
+	}
+
+	in := GenerationInput{
+		ReportID:   sch.ReportID,
+		ScheduleID: &sch.ID,
+		Prompt:     sch.Prompt,
+		LLM:        sch.LLM,
+		Model:      sch.Model,
+	}
+	if sch.DataSourceID != nil {
+		return s.reportService.GenerateReportFromDataSource(ctx, in, sch.DataSourceID)
+	}
+	// The path was checked when the schedule was saved; it is checked again
+	// since a symlink may have been planted since.
+	path, err := helper.ResolveWithin(s.sourceDir, sch.SourcePath)
+	if err != nil {
+		return nil, apperror.Wrap(apperror.Validation, "source_path_forbidden", err)
+	}
+	return s.reportService.GenerateReportFromPath(ctx, in, path)
+}
+
+// validateScheduleSource requires one source, and a source_path inside the
+// schedules source directory.
+func (s *ScheduleService) validateScheduleSource(sch model.Schedule) error {
+	if (sch.SourcePath == "") == (sch.DataSourceID == nil) {
+		return apperror.Validationf("schedule_source_required", "exactly one of source_path or data_source_id is required")
+	}
+	if sch.SourcePath != "" {
+		if _, err := helper.ResolveWithin(s.sourceDir, sch.SourcePath); err != nil {
+			return apperror.Wrap(apperror.Validation, "source_path_forbidden", err)
+		}
+	}
+	return nil
+}
+
+// NextScheduleRun returns the first occurrence of a standard five-field cron
+// expression strictly after from, evaluated in the given IANA timezone.
+func NextScheduleRun(expression, timezone string, from time.Time) (*time.Time, error) {
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, &apperror.Error{Kind: apperror.Validation, Code: "invalid_timezone", Message: fmt.Sprintf("invalid timezone %q", timezone), Err: err}
+	}
+	schedule, err := cron.ParseStandard(expression)
+	if err != nil {
+		return nil, &apperror.Error{Kind: apperror.Validation, Code: "invalid_cron_expression", Message: fmt.Sprintf("invalid cron expression %q", expression), Err: err}
+	}
+	next := schedule.Next(from.In(location))
+	if next.IsZero() {
+		return nil, apperror.Validationf("invalid_cron_expression", "cron expression %q never fires", expression)
+	}
+	return &next, nil
+}
@@ -0,0 +1,217 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"reportia/apperror"
+	"reportia/model"
+	"reportia/repository"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	defaultShareLinkHours = 7 * 24
+	// After maxSharePasswordAttempts wrong passwords in a row a link is
+	// locked for sharePasswordLockout, and again after every wrong password
+	// until the right one is entered.
+	maxSharePasswordAttempts = 5
+	sharePasswordLockout     = 15 * time.Minute
+)
+
+var (
+	ErrShareLinkNotFound        = apperror.New(apperror.NotFound, "share_link_not_found", "share link not found")
+	ErrShareLinkGone            = apperror.New(apperror.Gone, "share_link_gone", "share link expired or no longer available")
+	ErrShareLinkPasswordInvalid = apperror.New(apperror.Unauthorized, "share_link_password_invalid", "share link password is invalid")
+	ErrShareLinkLocked          = apperror.New(apperror.TooManyRequests, "share_link_locked", "too many wrong passwords, try again later")
+)
+
+type ShareService struct {
+	repo        *repository.ShareRepository
+	generations repository.GenerationStore
+	secret      []byte
+	baseURL     string
+}
+
+func NewShareService(repo *repository.ShareRepository, generations repository.GenerationStore, secret string, baseURL string) *ShareService {
+	return &ShareService{repo: repo, generations: generations, secret: []byte(secret), baseURL: strings.TrimSuffix(baseURL, "/")}
+}
+
+// Create mints a link for a successful generation. The token is returned only
+// here; the database keeps a hash of its random part.
+func (s *ShareService) Create(ctx context.Context, generationID int, expiresInHours int, password string, maxViews *int, userMail string) (*model.ShareLink, error) {
+	gen, err := s.generations.GetByID(ctx, generationID)
+	if err != nil {
+		return nil, err
+	}
+	if gen == nil {
+		return nil, ErrGenerationNotFound
+	}
+	if gen.Status != model.GenerationStatusSucceeded {
+		return nil, apperror.New(apperror.Conflict, "generation_not_shareable", "only successful generations can be shared")
+	}
+	if expiresInHours <= 0 {
+		expiresInHours = defaultShareLinkHours
+	}
+
+	nonceBytes := make([]byte, 24)
+	if _, err := rand.Read(nonceBytes); err != nil {
+		return nil, err
+	}
+	nonce := base64.RawURLEncoding.EncodeToString(nonceBytes)
+
+	link := model.ShareLink{
+		GenerationID: generationID,
+		TokenHash:    hashNonce(nonce),
+		ExpiresAt:    time.Now().Add(time.Duration(expiresInHours) * time.Hour),
+		MaxViews:     maxViews,
+		UserMail:     userMail,
+	}
+	if password != "" {
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, err
+		}
+		link.PasswordHash = string(hash)
+	}
+
+	created, err := s.repo.Create(ctx, link)
+	if err != nil {
+		return nil, err
+	}
+	created.Token = s.signToken(created.ID, nonce)
+	created.URL = s.baseURL + "/share/" + created.Token
+	return created, nil
+}
+
+func (s *ShareService) ListByGeneration(ctx context.Context, generationID int) ([]model.ShareLink, error) {
+	return s.repo.ListByGeneration(ctx, generationID)
+}
+
+func (s *ShareService) Revoke(ctx context.Context, id int) error {
+	if id == 0 {
+		return errIDRequired
+	}
+	return notFoundOnNoRows(s.repo.Revoke(ctx, id), ErrShareLinkNotFound)
+}
+
+// Inspect verifies the token and reports whether a password is needed,
+// without counting a view.
+func (s *ShareService) Inspect(ctx context.Context, token string) (*model.ShareLink, error) {
+	link, err := s.resolve(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	if !shareLinkAvailable(link, time.Now()) {
+		return nil, ErrShareLinkGone
+	}
+	return link, nil
+}
+
+// View checks the password, counts the view and returns the shared HTML.
+// Password attempts are refused while the link is locked, even with the
+// right password.
+func (s *ShareService) View(ctx context.Context, token string, password string) (string, error) {
+	link, err := s.resolve(ctx, token)
+	if err != nil {
+		return "", err
+	}
+	if link.PasswordHash != "" {
+		now := time.Now()
+		allowed, err := s.repo.ClaimPasswordAttempt(ctx, link.ID, now, maxSharePasswordAttempts, now.Add(sharePasswordLockout))
+		if err != nil {
+			return "", err
+		}
+		if !allowed {
+			return "", ErrShareLinkLocked
+		}
+		if bcrypt.CompareHashAndPassword([]byte(link.PasswordHash), []byte(password)) != nil {
+			return "", ErrShareLinkPasswordInvalid
+		}
+	}
+	ok, err := s.repo.RegisterView(ctx, link.ID, time.Now())
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", ErrShareLinkGone
+	}
+
+	gen, err := s.generations.GetByID(ctx, link.GenerationID)
+	if err != nil {
+		return "", err
+	}
+	if gen == nil {
+		return "", ErrShareLinkGone
+	}
+	return gen.Output, nil
+}
+
+func (s *ShareService) resolve(ctx context.Context, token string) (*model.ShareLink, error) {
+	id, nonce, ok := s.verifyToken(token)
+	if !ok {
+		return nil, ErrShareLinkNotFound
+	}
+	link, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if link == nil || subtle.ConstantTimeCompare([]byte(link.TokenHash), []byte(hashNonce(nonce))) != 1 {
+		return nil, ErrShareLinkNotFound
+	}
+	if link.RevokedAt != nil {
+		return nil, ErrShareLinkNotFound
+	}
+	return link, nil
+}
+
+func shareLinkAvailable(link *model.ShareLink, now time.Time) bool {
+	if !link.ExpiresAt.After(now) {
+		return false
+	}
+	return link.MaxViews == nil || link.ViewCount < *link.MaxViews
+}
+
+// Tokens have the form "<id>.<nonce>.<signature>", the signature being an
+// HMAC-SHA256 of "<id>.<nonce>" under the server secret, so forged tokens are
+// rejected before touching the database.
+func (s *ShareService) signToken(id int, nonce string) string {
+	payload := fmt.Sprintf("%d.%s", id, nonce)
+	return payload + "." + s.signature(payload)
+}
+
+func (s *ShareService) verifyToken(token string) (int, string, bool) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return 0, "", false
+	}
+	payload := parts[0] + "." + parts[1]
+	if !hmac.Equal([]byte(parts[2]), []byte(s.signature(payload))) {
+		return 0, "", false
+	}
+	id, err := strconv.Atoi(parts[0])
+	if err != nil || id <= 0 {
+		return 0, "", false
+	}
+	return id, parts[1], true
+}
+
+func (s *ShareService) signature(payload string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func hashNonce(nonce string) string {
+	sum := sha256.Sum256([]byte(nonce))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,205 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"reportia/apperror"
+	"reportia/integration/webhook"
+	"reportia/model"
+	"reportia/netguard"
+	"reportia/repository"
+	"time"
+)
+
+const (
+	webhookLease     = 5 * time.Minute
+	webhookBatchSize = 50
+	webhookMaxDelay  = time.Hour
+)
+
+var ErrWebhookNotFound = apperror.New(apperror.NotFound, "webhook_not_found", "webhook subscription not found")
+
+type WebhookOptions struct {
+	MaxAttempts int
+	RetryDelay  time.Duration
+	// Network bounds the URLs subscriptions may point to; the sender
+	// enforces it again when connecting.
+	Network netguard.Policy
+}
+
+type WebhookService struct {
+	repo    *repository.WebhookRepository
+	sender  *webhook.Sender
+	options WebhookOptions
+}
+
+func NewWebhookService(repo *repository.WebhookRepository, sender *webhook.Sender, options WebhookOptions) *WebhookService {
+	if options.MaxAttempts <= 0 {
+		options.MaxAttempts = 8
+	}
+	if options.RetryDelay <= 0 {
+		options.RetryDelay = 10 * time.Second
+	}
+	return &WebhookService{repo: repo, sender: sender, options: options}
+}
+
+func (s *WebhookService) List(ctx context.Context) ([]model.WebhookSubscription, error) {
+	subscriptions, err := s.repo.ListSubscriptions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range subscriptions {
+		subscriptions[i].Secret = ""
+	}
+	return subscriptions, nil
+}
+
+// Create generates a secret when none is given. The returned subscription is
+// the only place the secret is ever exposed.
+func (s *WebhookService) Create(ctx context.Context, sub model.WebhookSubscription) (*model.WebhookSubscription, error) {
+	if err := s.options.Network.CheckURL(sub.URL); err != nil {
+		return nil, apperror.Wrap(apperror.Validation, "invalid_webhook_url", err)
+	}
+	if sub.Secret == "" {
+		secret := make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return nil, err
+		}
+		sub.Secret = hex.EncodeToString(secret)
+	}
+	return s.repo.CreateSubscription(ctx, sub)
+}
+
+func (s *WebhookService) Update(ctx context.Context, sub model.WebhookSubscription) (*model.WebhookSubscription, error) {
+	if err := s.options.Network.CheckURL(sub.URL); err != nil {
+		return nil, apperror.Wrap(apperror.Validation, "invalid_webhook_url", err)
+	}
+	updated, err := s.repo.UpdateSubscription(ctx, sub)
+	if err != nil {
+		return nil, err
+	}
+	if updated == nil {
+		return nil, ErrWebhookNotFound
+	}
+	updated.Secret = ""
+	return updated, nil
+}
+
+func (s *WebhookService) TurnOnOff(ctx context.Context, id int, active bool) error {
+	if id == 0 {
+		return errIDRequired
+	}
+	return notFoundOnNoRows(s.repo.TurnOnOffSubscription(ctx, id, active), ErrWebhookNotFound)
+}
+
+func (s *WebhookService) ListEvents(ctx context.Context, subscriptionID, limit int) ([]model.WebhookEvent, error) {
+	if limit <= 0 {
+		limit = 20
+	}
+	if limit > 100 {
+		limit = 100
+	}
+	return s.repo.ListEvents(ctx, subscriptionID, limit)
+}
+
+// Publish stores the event in the outbox for every subscribed endpoint. It
+// never fails the caller: a lost notification must not undo a generation.
+// A nil service publishes nothing.
+func (s *WebhookService) Publish(ctx context.Context, eventType string, data any) {
+	if s == nil {
+		return
+	}
+	if err := s.publish(ctx, eventType, data); err != nil {
+		slog.ErrorContext(ctx, "webhook: could not publish event", "event_type", eventType, "error", err)
+	}
+}
+
+func (s *WebhookService) publish(ctx context.Context, eventType string, data any) error {
+	subscriptions, err := s.repo.ListActiveSubscriptionsFor(ctx, eventType)
+	if err != nil {
+		return err
+	}
+	if len(subscriptions) == 0 {
+		return nil
+	}
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	ids := make([]int, 0, len(subscriptions))
+	for _, sub := range subscriptions {
+		ids = append(ids, sub.ID)
+	}
+	return s.repo.Enqueue(ctx, eventType, payload, ids)
+}
+
+// DispatchDue sends every due outbox event once and returns how many were
+// attempted.
+func (s *WebhookService) DispatchDue(ctx context.Context) (int, error) {
+	pending, err := s.repo.ClaimDue(ctx, time.Now(), webhookLease, webhookBatchSize)
+	if err != nil {
+		return 0, err
+	}
+	for _, item := range pending {
+		if err := s.dispatch(ctx, item); err != nil {
+			slog.ErrorContext(ctx, "webhook: could not record attempt", "event_id", item.Event.ID, "error", err)
+		}
+	}
+	return len(pending), nil
+}
+
+// Pending returns the number of outbox events not delivered yet.
+func (s *WebhookService) Pending(ctx context.Context) (int, error) {
+	return s.repo.CountPending(ctx)
+}
+
+func (s *WebhookService) dispatch(ctx context.Context, item model.PendingWebhook) error {
+	body, err := json.Marshal(map[string]any{
+		"id":         item.Event.ID,
+		"type":       item.Event.EventType,
+		"created_at": item.Event.CreateAt,
+		"data":       item.Event.Payload,
+	})
+	if err != nil {
+		return err
+	}
+
+	startedAt := time.Now()
+	statusCode, sendErr := s.sender.Send(ctx, item.URL, item.Secret, item.Event.ID, item.Event.EventType, body)
+	attempt := model.WebhookAttempt{
+		EventID:    item.Event.ID,
+		DurationMs: int(time.Since(startedAt).Milliseconds()),
+	}
+	if statusCode != 0 {
+		attempt.StatusCode = &statusCode
+	}
+
+	status := model.WebhookEventDelivered
+	nextAttemptAt := time.Now()
+	if sendErr != nil {
+		attempt.Error = sendErr.Error()
+		attempts := item.Event.Attempts + 1
+		if attempts >= s.options.MaxAttempts {
+			status = model.WebhookEventFailed
+		} else {
+			status = model.WebhookEventPending
+			nextAttemptAt = nextAttemptAt.Add(s.backoff(attempts))
+		}
+	}
+	return s.repo.RecordAttempt(ctx, attempt, status, nextAttemptAt)
+}
+
+// backoff doubles the delay after every failed attempt, capped at an hour.
+func (s *WebhookService) backoff(attempts int) time.Duration {
+	delay := s.options.RetryDelay
+	for i := 1; i < attempts && delay < webhookMaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > webhookMaxDelay {
+		delay = webhookMaxDelay
+	}
+	return delay
+}
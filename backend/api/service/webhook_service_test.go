@@ -0,0 +1,59 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"reportia/apperror"
+	"reportia/model"
+	"reportia/netguard"
+	"testing"
+	"time"
+)
+
+func TestWebhookBackoff(t *testing.T) {
+	s := NewWebhookService(nil, nil, WebhookOptions{RetryDelay: 10 * time.Second})
+	for _, tc := range []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, 10 * time.Second},
+		{2, 20 * time.Second},
+		{3, 40 * time.Second},
+		{8, 1280 * time.Second},
+		{9, 2560 * time.Second},
+		{10, time.Hour},
+		{50, time.Hour},
+	} {
+		if got := s.backoff(tc.attempts); got != tc.want {
+			t.Errorf("attempt %d: got %s, want %s", tc.attempts, got, tc.want)
+		}
+	}
+}
+
+// TestWebhookURLPolicy only covers refused URLs, which are checked before
+// the repository is used.
+func TestWebhookURLPolicy(t *testing.T) {
+	s := NewWebhookService(nil, nil, WebhookOptions{
+		Network: netguard.Policy{AllowedHosts: []string{".hooks.example.com"}},
+	})
+	for _, url := range []string{
+		"http://127.0.0.1:8080/hook",
+		"http://169.254.169.254/latest/meta-data",
+		"http://[::ffff:10.0.0.1]/hook",
+		"https://example.com/hook",
+		"ftp://ci.hooks.example.com/hook",
+		"not a url",
+	} {
+		sub := model.WebhookSubscription{ID: 1, URL: url}
+		for name, save := range map[string]func(context.Context, model.WebhookSubscription) (*model.WebhookSubscription, error){
+			"create": s.Create,
+			"update": s.Update,
+		} {
+			_, err := save(context.Background(), sub)
+			var appErr *apperror.Error
+			if !errors.As(err, &appErr) || appErr.Kind != apperror.Validation || appErr.Code != "invalid_webhook_url" {
+				t.Errorf("%s %s: got %v, want invalid_webhook_url", name, url, err)
+			}
+		}
+	}
+}
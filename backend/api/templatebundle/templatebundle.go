@@ -0,0 +1,228 @@
+// Package templatebundle reads and writes the zip bundles used to move
+// templates between environments.
+//
+// A bundle holds a manifest.json listing one directory per template. Each
+// directory has a metadata.json with the model.TemplateMetadata of the
+// template, a template.html with its markup and the files of the assets the
+// metadata lists:
+//
+//	manifest.json
+//	templates/001-sales-summary/metadata.json
+//	templates/001-sales-summary/template.html
+//	templates/001-sales-summary/assets/logo/logo.png
+//
+// The starter gallery uses the same directory layout.
+package templatebundle
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"regexp"
+	"reportia/model"
+	"reportia/templatelint"
+	"strings"
+	"time"
+)
+
+const (
+	Format  = "reportia-templates"
+	Version = 1
+
+	// MaxTemplates is the most templates a bundle may hold, and MaxAssets the
+	// most assets per template.
+	MaxTemplates = 100
+	MaxAssets    = 20
+
+	manifestFile = "manifest.json"
+	metadataFile = "metadata.json"
+	templateFile = "template.html"
+
+	maxMetadataSize = 64 * 1024
+)
+
+var ErrInvalidBundle = errors.New("invalid template bundle")
+
+// Entry is one template of a bundle. Metadata.Assets is filled in from
+// Assets when writing.
+type Entry struct {
+	Metadata model.TemplateMetadata
+	Template string
+	Assets   []Asset
+}
+
+type Asset struct {
+	Name     string
+	FileName string
+	Content  []byte
+}
+
+type manifest struct {
+	Format     string    `json:"format"`
+	Version    int       `json:"version"`
+	ExportedAt time.Time `json:"exported_at"`
+	Templates  []string  `json:"templates"`
+}
+
+// Write writes the entries as a zip bundle.
+func Write(w io.Writer, entries []Entry, exportedAt time.Time) error {
+	if len(entries) > MaxTemplates {
+		return fmt.Errorf("a bundle holds at most %d templates", MaxTemplates)
+	}
+	zw := zip.NewWriter(w)
+	m := manifest{Format: Format, Version: Version, ExportedAt: exportedAt.UTC(), Templates: make([]string, 0, len(entries))}
+
+	for i, entry := range entries {
+		dir := fmt.Sprintf("templates/%03d-%s", i+1, slug(entry.Metadata.Name))
+		m.Templates = append(m.Templates, dir)
+		entry.Metadata.Assets = nil
+		for _, asset := range entry.Assets {
+			fileName := path.Base(asset.FileName)
+			if !fs.ValidPath(fileName) || fileName == "." || fileName == "/" {
+				fileName = asset.Name
+			}
+			entry.Metadata.Assets = append(entry.Metadata.Assets, model.TemplateAsset{Name: asset.Name, FileName: fileName})
+			if err := writeFile(zw, path.Join(dir, "assets", asset.Name, fileName), asset.Content); err != nil {
+				return err
+			}
+		}
+		metadata, err := json.MarshalIndent(entry.Metadata, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := writeFile(zw, path.Join(dir, metadataFile), metadata); err != nil {
+			return err
+		}
+		if err := writeFile(zw, path.Join(dir, templateFile), []byte(entry.Template)); err != nil {
+			return err
+		}
+	}
+
+	content, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeFile(zw, manifestFile, content); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+func writeFile(zw *zip.Writer, name string, content []byte) error {
+	f, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: time.Now()})
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(content)
+	return err
+}
+
+// Read parses a zip bundle. Errors about the bundle itself wrap
+// ErrInvalidBundle.
+func Read(r io.ReaderAt, size int64) ([]Entry, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidBundle, err)
+	}
+
+	var m manifest
+	content, err := readFile(zr, manifestFile, maxMetadataSize)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(content, &m); err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrInvalidBundle, manifestFile, err)
+	}
+	if m.Format != Format || m.Version != Version {
+		return nil, fmt.Errorf("%w: unsupported format %q version %d", ErrInvalidBundle, m.Format, m.Version)
+	}
+	if len(m.Templates) > MaxTemplates {
+		return nil, fmt.Errorf("%w: a bundle holds at most %d templates", ErrInvalidBundle, MaxTemplates)
+	}
+
+	entries := make([]Entry, 0, len(m.Templates))
+	for _, dir := range m.Templates {
+		entry, err := ReadEntry(zr, dir)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// ReadEntry reads the template stored in dir. Templates larger than the lint
+// limit are truncated just past it, so that linting rejects them without the
+// whole file being decompressed.
+func ReadEntry(fsys fs.FS, dir string) (Entry, error) {
+	if !fs.ValidPath(dir) || dir == "." {
+		return Entry{}, fmt.Errorf("%w: invalid template directory %q", ErrInvalidBundle, dir)
+	}
+	var entry Entry
+	metadata, err := readFile(fsys, path.Join(dir, metadataFile), maxMetadataSize)
+	if err != nil {
+		return Entry{}, err
+	}
+	if err := json.Unmarshal(metadata, &entry.Metadata); err != nil {
+		return Entry{}, fmt.Errorf("%w: %s: %v", ErrInvalidBundle, path.Join(dir, metadataFile), err)
+	}
+	template, err := readFile(fsys, path.Join(dir, templateFile), templatelint.MaxSize+1)
+	if err != nil && !errors.Is(err, errTooLarge) {
+		return Entry{}, err
+	}
+	entry.Template = string(template)
+
+	if len(entry.Metadata.Assets) > MaxAssets {
+		return Entry{}, fmt.Errorf("%w: %s has more than %d assets", ErrInvalidBundle, dir, MaxAssets)
+	}
+	for _, asset := range entry.Metadata.Assets {
+		name := path.Join(dir, "assets", asset.Name, asset.FileName)
+		if !fs.ValidPath(name) || path.Dir(name) != path.Join(dir, "assets", asset.Name) {
+			return Entry{}, fmt.Errorf("%w: invalid asset %q", ErrInvalidBundle, asset.Name)
+		}
+		content, err := readFile(fsys, name, model.MaxAssetSize)
+		if err != nil {
+			return Entry{}, err
+		}
+		entry.Assets = append(entry.Assets, Asset{Name: asset.Name, FileName: asset.FileName, Content: content})
+	}
+	return entry, nil
+}
+
+var errTooLarge = errors.New("file too large")
+
+// readFile reads at most limit bytes of a file. It returns what it read along
+// with errTooLarge when the file is longer.
+func readFile(fsys fs.FS, name string, limit int64) ([]byte, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidBundle, err)
+	}
+	defer f.Close()
+
+	content, err := io.ReadAll(io.LimitReader(f, limit+1))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrInvalidBundle, name, err)
+	}
+	if int64(len(content)) > limit {
+		return content[:limit], fmt.Errorf("%w: %s: %w", ErrInvalidBundle, name, errTooLarge)
+	}
+	return content, nil
+}
+
+var nonSlug = regexp.MustCompile(`[^a-z0-9]+`)
+
+func slug(name string) string {
+	s := strings.Trim(nonSlug.ReplaceAllString(strings.ToLower(name), "-"), "-")
+	if len(s) > 40 {
+		s = strings.TrimRight(s[:40], "-")
+	}
+	if s == "" {
+		return "template"
+	}
+	return s
+}
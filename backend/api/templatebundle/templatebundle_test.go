@@ -0,0 +1,176 @@
+package templatebundle_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reportia/model"
+	"reportia/templatebundle"
+	"reportia/templatelint"
+	"strings"
+	"testing"
+	"time"
+)
+
+const template = "<html><body><!-- DYNAMIC BODY CONTENT HERE --></body></html>"
+
+// zipFiles writes a bundle file by file, so that tests can build bundles
+// Write would refuse to produce.
+func zipFiles(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		f, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Write([]byte(content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func manifestJSON(dirs ...string) string {
+	content, _ := json.Marshal(map[string]any{"format": templatebundle.Format, "version": templatebundle.Version, "templates": dirs})
+	return string(content)
+}
+
+func metadataJSON(meta model.TemplateMetadata) string {
+	content, _ := json.Marshal(meta)
+	return string(content)
+}
+
+func read(bundle []byte) ([]templatebundle.Entry, error) {
+	return templatebundle.Read(bytes.NewReader(bundle), int64(len(bundle)))
+}
+
+func TestWriteRead(t *testing.T) {
+	entries := []templatebundle.Entry{
+		{
+			Metadata: model.TemplateMetadata{Name: "Sales summary", Tags: []string{"sales"}, Category: "finance"},
+			Template: template,
+			Assets:   []templatebundle.Asset{{Name: "logo", FileName: "../../logo.png", Content: []byte("png")}},
+		},
+		{Metadata: model.TemplateMetadata{Name: "Sales summary"}, Template: template},
+	}
+	var buf bytes.Buffer
+	if err := templatebundle.Write(&buf, entries, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := read(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].Metadata.Name != "Sales summary" || got[0].Template != template || got[1].Metadata.Name != "Sales summary" {
+		t.Fatalf("got %+v", got)
+	}
+	// Directory components are dropped from asset file names.
+	if assets := got[0].Assets; len(assets) != 1 || assets[0].FileName != "logo.png" || string(assets[0].Content) != "png" {
+		t.Errorf("got assets %+v", assets)
+	}
+}
+
+func TestWriteLimit(t *testing.T) {
+	entries := make([]templatebundle.Entry, templatebundle.MaxTemplates+1)
+	if err := templatebundle.Write(&bytes.Buffer{}, entries, time.Now()); err == nil {
+		t.Error("wrote a bundle past the template limit")
+	}
+}
+
+func TestRead(t *testing.T) {
+	metadata := metadataJSON(model.TemplateMetadata{Name: "Sales"})
+	valid := map[string]string{
+		"manifest.json":                     manifestJSON("templates/001-sales"),
+		"templates/001-sales/metadata.json": metadata,
+		"templates/001-sales/template.html": template,
+	}
+	with := func(changes map[string]string) map[string]string {
+		files := map[string]string{}
+		for name, content := range valid {
+			files[name] = content
+		}
+		for name, content := range changes {
+			if content == "" {
+				delete(files, name)
+			} else {
+				files[name] = content
+			}
+		}
+		return files
+	}
+	tooManyAssets := model.TemplateMetadata{Name: "Sales"}
+	for i := range templatebundle.MaxAssets + 1 {
+		tooManyAssets.Assets = append(tooManyAssets.Assets, model.TemplateAsset{Name: fmt.Sprintf("a%d", i), FileName: "a.png"})
+	}
+	manyDirs := make([]string, templatebundle.MaxTemplates+1)
+	for i := range manyDirs {
+		manyDirs[i] = "templates/001-sales"
+	}
+
+	for _, tc := range []struct {
+		name    string
+		files   map[string]string
+		wantErr string
+	}{
+		{"valid", valid, ""},
+		{"no manifest", with(map[string]string{"manifest.json": ""}), "manifest.json"},
+		{"other format", with(map[string]string{"manifest.json": `{"format":"other","version":1}`}), "unsupported format"},
+		{"newer version", with(map[string]string{"manifest.json": `{"format":"reportia-templates","version":2}`}), "unsupported format"},
+		{"too many templates", with(map[string]string{"manifest.json": manifestJSON(manyDirs...)}), "at most 100 templates"},
+		{"directory out of the bundle", with(map[string]string{"manifest.json": manifestJSON("../001-sales")}), "invalid template directory"},
+		{"absolute directory", with(map[string]string{"manifest.json": manifestJSON("/templates/001-sales")}), "invalid template directory"},
+		{"missing template", with(map[string]string{"templates/001-sales/template.html": ""}), "template.html"},
+		{"metadata too large", with(map[string]string{"templates/001-sales/metadata.json": strings.Repeat(" ", 64*1024+1)}), "too large"},
+		{"too many assets", with(map[string]string{"templates/001-sales/metadata.json": metadataJSON(tooManyAssets)}), "more than 20 assets"},
+		{"asset out of its directory", with(map[string]string{
+			"templates/001-sales/metadata.json": metadataJSON(model.TemplateMetadata{Assets: []model.TemplateAsset{{Name: "logo", FileName: "../../../metadata.json"}}}),
+		}), "invalid asset"},
+		{"asset in a subdirectory", with(map[string]string{
+			"templates/001-sales/metadata.json":            metadataJSON(model.TemplateMetadata{Assets: []model.TemplateAsset{{Name: "logo", FileName: "dir/logo.png"}}}),
+			"templates/001-sales/assets/logo/dir/logo.png": "png",
+		}), "invalid asset"},
+		{"asset too large", with(map[string]string{
+			"templates/001-sales/metadata.json":        metadataJSON(model.TemplateMetadata{Assets: []model.TemplateAsset{{Name: "logo", FileName: "logo.png"}}}),
+			"templates/001-sales/assets/logo/logo.png": strings.Repeat("x", model.MaxAssetSize+1),
+		}), "too large"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := read(zipFiles(t, tc.files))
+			switch {
+			case tc.wantErr == "" && err != nil:
+				t.Errorf("got %v, want no error", err)
+			case tc.wantErr != "" && (!errors.Is(err, templatebundle.ErrInvalidBundle) || !strings.Contains(err.Error(), tc.wantErr)):
+				t.Errorf("got %v, want an invalid bundle error with %q", err, tc.wantErr)
+			}
+		})
+	}
+
+	if _, err := templatebundle.Read(strings.NewReader("not a zip"), 9); !errors.Is(err, templatebundle.ErrInvalidBundle) {
+		t.Errorf("not a zip: got %v, want ErrInvalidBundle", err)
+	}
+}
+
+// TestReadTruncatesLargeTemplates checks that a template past the lint limit
+// is read just far enough for linting to reject it.
+func TestReadTruncatesLargeTemplates(t *testing.T) {
+	entries, err := read(zipFiles(t, map[string]string{
+		"manifest.json":                     manifestJSON("templates/001-sales"),
+		"templates/001-sales/metadata.json": metadataJSON(model.TemplateMetadata{Name: "Sales"}),
+		"templates/001-sales/template.html": strings.Repeat("x", 4*templatelint.MaxSize),
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := len(entries[0].Template); got != templatelint.MaxSize+1 {
+		t.Errorf("got %d bytes, want %d", got, templatelint.MaxSize+1)
+	}
+	if err := templatelint.Check(entries[0].Template); err == nil {
+		t.Error("truncated template passed the lint check")
+	}
+}
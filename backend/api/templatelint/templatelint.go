@@ -0,0 +1,326 @@
+// Package templatelint checks report templates before they are saved, so that
+// problems surface with a line and column instead of at generation time.
+package templatelint
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+)
+
+// MaxSize is the largest template accepted, in bytes.
+const MaxSize = 512 * 1024
+
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// Issue codes.
+const (
+	CodeTooLarge         = "too_large"
+	CodeMalformed        = "malformed_html"
+	CodeUnexpectedEndTag = "unexpected_end_tag"
+	CodeUnclosedTag      = "unclosed_tag"
+	CodeMissingBodySlot  = "missing_body_slot"
+	CodeDuplicateSlot    = "duplicate_body_slot"
+	CodeUnknownVariable  = "unknown_variable"
+	CodeInvalidAsset     = "invalid_asset_reference"
+	CodeExternalResource = "external_resource"
+	CodeInlineScript     = "inline_script"
+)
+
+// BodySlot is the comment marking where generated content goes. It is matched
+// ignoring case and whitespace, since saved templates are stored without
+// whitespace.
+const BodySlot = "<!-- DYNAMIC BODY CONTENT HERE -->"
+
+// KnownVariables are the {{VARIABLES}} a template may use.
+var KnownVariables = map[string]bool{
+	"DATE_TODAY": true,
+}
+
+// AssetPrefix starts the {{ASSET:name}} variables referencing an uploaded
+// asset of the template by name.
+const AssetPrefix = "ASSET:"
+
+// AssetName is the pattern asset names must match.
+var AssetName = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,59}$`)
+
+type Issue struct {
+	Severity string `json:"severity"`
+	Code     string `json:"code"`
+	Message  string `json:"message"`
+	Line     int    `json:"line"`
+	Column   int    `json:"column"`
+}
+
+type Result struct {
+	Valid  bool    `json:"valid"`
+	Issues []Issue `json:"issues"`
+}
+
+// Error is returned when a template has error-level issues.
+type Error struct {
+	Issues []Issue
+}
+
+func (e *Error) Error() string {
+	for _, issue := range e.Issues {
+		if issue.Severity == SeverityError {
+			return fmt.Sprintf("template is invalid: %s (line %d, column %d)", issue.Message, issue.Line, issue.Column)
+		}
+	}
+	return "template is invalid"
+}
+
+// Check lints the template and returns an *Error when it has error-level
+// issues. Warnings alone do not fail the check.
+func Check(template string) error {
+	result := Lint(template)
+	if result.Valid {
+		return nil
+	}
+	return &Error{Issues: result.Issues}
+}
+
+var (
+	variablePattern = regexp.MustCompile(`\{\{\s*([^{}\s]+)\s*\}\}`)
+	whitespace      = regexp.MustCompile(`\s+`)
+	externalURL     = regexp.MustCompile(`(?i)^\s*(https?:)?//`)
+	cssExternalURL  = regexp.MustCompile(`(?i)(url\(\s*['"]?\s*(https?:)?//|@import\s+['"]?\s*(https?:)?//)`)
+)
+
+// Elements whose end tag may be omitted.
+var optionalEndTags = map[string]bool{
+	"html": true, "head": true, "body": true, "p": true, "li": true, "dt": true, "dd": true,
+	"option": true, "optgroup": true, "thead": true, "tbody": true, "tfoot": true, "tr": true,
+	"td": true, "th": true, "colgroup": true, "caption": true, "rb": true, "rt": true, "rtc": true, "rp": true,
+}
+
+// Elements that never have content.
+var voidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true, "hr": true, "img": true,
+	"input": true, "link": true, "meta": true, "source": true, "track": true, "wbr": true,
+}
+
+// Attributes loading a resource, per element.
+var resourceAttributes = map[string]string{
+	"script": "src", "img": "src", "iframe": "src", "embed": "src", "source": "src",
+	"audio": "src", "video": "src", "track": "src", "link": "href", "object": "data",
+}
+
+type openElement struct {
+	name   string
+	offset int
+}
+
+type linter struct {
+	source string
+	lines  []int
+	issues []Issue
+}
+
+// Lint parses the template and reports every issue it finds, sorted by
+// position.
+func Lint(template string) Result {
+	l := &linter{source: template, lines: lineStarts(template)}
+
+	if len(template) > MaxSize {
+		l.add(SeverityError, CodeTooLarge, 0, "template is %d bytes, the maximum is %d", len(template), MaxSize)
+		return l.result()
+	}
+
+	l.checkVariables()
+	l.checkMarkup()
+
+	sort.SliceStable(l.issues, func(i, j int) bool {
+		if l.issues[i].Line != l.issues[j].Line {
+			return l.issues[i].Line < l.issues[j].Line
+		}
+		return l.issues[i].Column < l.issues[j].Column
+	})
+	return l.result()
+}
+
+func (l *linter) result() Result {
+	valid := true
+	for _, issue := range l.issues {
+		if issue.Severity == SeverityError {
+			valid = false
+		}
+	}
+	issues := l.issues
+	if issues == nil {
+		issues = []Issue{}
+	}
+	return Result{Valid: valid, Issues: issues}
+}
+
+func (l *linter) add(severity, code string, offset int, format string, args ...any) {
+	line, column := l.position(offset)
+	l.issues = append(l.issues, Issue{
+		Severity: severity,
+		Code:     code,
+		Message:  fmt.Sprintf(format, args...),
+		Line:     line,
+		Column:   column,
+	})
+}
+
+func (l *linter) checkVariables() {
+	for _, match := range variablePattern.FindAllStringSubmatchIndex(l.source, -1) {
+		name := l.source[match[2]:match[3]]
+		if asset, ok := strings.CutPrefix(name, AssetPrefix); ok {
+			if !AssetName.MatchString(asset) {
+				l.add(SeverityError, CodeInvalidAsset, match[0], "invalid asset name %q: use lowercase letters, digits, '-' and '_'", asset)
+			}
+			continue
+		}
+		if !KnownVariables[name] {
+			l.add(SeverityError, CodeUnknownVariable, match[0], "unknown variable {{%s}}", name)
+		}
+	}
+}
+
+// checkMarkup walks the tokens keeping track of their offset in the source,
+// since the tokenizer does not report positions itself.
+func (l *linter) checkMarkup() {
+	tokenizer := html.NewTokenizer(strings.NewReader(l.source))
+	var stack []openElement
+	var slots []int
+	offset := 0
+	inlineScriptAt := -1
+
+	for {
+		tokenType := tokenizer.Next()
+		raw := len(tokenizer.Raw())
+		if tokenType == html.ErrorToken {
+			if err := tokenizer.Err(); err != io.EOF {
+				l.add(SeverityError, CodeMalformed, offset, "could not parse HTML: %v", err)
+			}
+			break
+		}
+		token := tokenizer.Token()
+		if tokenType != html.TextToken {
+			inlineScriptAt = -1
+		}
+
+		switch tokenType {
+		case html.StartTagToken, html.SelfClosingTagToken:
+			l.checkAttributes(token, offset)
+			if token.Data == "script" && tokenType == html.StartTagToken && attribute(token, "src") == "" {
+				inlineScriptAt = offset
+			}
+			if tokenType == html.StartTagToken && !voidElements[token.Data] {
+				stack = append(stack, openElement{name: token.Data, offset: offset})
+			}
+		case html.EndTagToken:
+			stack = l.closeElement(stack, token.Data, offset)
+		case html.TextToken:
+			if inlineScriptAt >= 0 && strings.TrimSpace(token.Data) != "" {
+				l.add(SeverityWarning, CodeInlineScript, inlineScriptAt, "inline <script> in the template; the generated report runs its own scripts")
+			}
+			if len(stack) > 0 && stack[len(stack)-1].name == "style" && cssExternalURL.MatchString(token.Data) {
+				l.add(SeverityWarning, CodeExternalResource, offset, "stylesheet loads an external resource")
+			}
+		case html.CommentToken:
+			if IsBodySlot(token.Data) {
+				slots = append(slots, offset)
+			}
+		}
+		offset += raw
+	}
+
+	for _, element := range stack {
+		if !optionalEndTags[element.name] {
+			l.add(SeverityError, CodeUnclosedTag, element.offset, "<%s> is never closed", element.name)
+		}
+	}
+
+	switch {
+	case len(slots) == 0:
+		l.add(SeverityError, CodeMissingBodySlot, 0, "template has no %s slot for the generated content", BodySlot)
+	case len(slots) > 1:
+		for _, slot := range slots[1:] {
+			l.add(SeverityError, CodeDuplicateSlot, slot, "template must have exactly one %s slot", BodySlot)
+		}
+	}
+}
+
+// closeElement pops the stack up to the matching start tag. Elements closed
+// implicitly on the way must be ones whose end tag is optional.
+func (l *linter) closeElement(stack []openElement, name string, offset int) []openElement {
+	for i := len(stack) - 1; i >= 0; i-- {
+		if stack[i].name != name {
+			continue
+		}
+		for _, element := range stack[i+1:] {
+			if !optionalEndTags[element.name] {
+				l.add(SeverityError, CodeUnclosedTag, element.offset, "<%s> is not closed before </%s>", element.name, name)
+			}
+		}
+		return stack[:i]
+	}
+	if !voidElements[name] {
+		l.add(SeverityError, CodeUnexpectedEndTag, offset, "</%s> has no matching start tag", name)
+	}
+	return stack
+}
+
+func (l *linter) checkAttributes(token html.Token, offset int) {
+	if attr, ok := resourceAttributes[token.Data]; ok {
+		if value := attribute(token, attr); externalURL.MatchString(value) {
+			l.add(SeverityWarning, CodeExternalResource, offset, "<%s> loads external resource %s", token.Data, value)
+		}
+	}
+	for _, attr := range token.Attr {
+		if strings.HasPrefix(attr.Key, "on") {
+			l.add(SeverityWarning, CodeInlineScript, offset, "inline event handler %s on <%s>", attr.Key, token.Data)
+		}
+		if attr.Key == "style" && cssExternalURL.MatchString(attr.Val) {
+			l.add(SeverityWarning, CodeExternalResource, offset, "inline style on <%s> loads an external resource", token.Data)
+		}
+	}
+}
+
+func attribute(token html.Token, key string) string {
+	for _, attr := range token.Attr {
+		if attr.Key == key {
+			return attr.Val
+		}
+	}
+	return ""
+}
+
+// IsBodySlot reports whether the text of an HTML comment is the body slot.
+func IsBodySlot(comment string) bool {
+	normalize := func(s string) string { return strings.ToUpper(whitespace.ReplaceAllString(s, "")) }
+	return normalize(comment) == normalize(strings.TrimSuffix(strings.TrimPrefix(BodySlot, "<!--"), "-->"))
+}
+
+func lineStarts(source string) []int {
+	starts := []int{0}
+	for i, c := range source {
+		if c == '\n' {
+			starts = append(starts, i+1)
+		}
+	}
+	return starts
+}
+
+// position converts a byte offset into a 1-based line and a 1-based column
+// counted in characters.
+func (l *linter) position(offset int) (int, int) {
+	line := sort.Search(len(l.lines), func(i int) bool { return l.lines[i] > offset }) - 1
+	if line < 0 {
+		line = 0
+	}
+	column := utf8.RuneCountInString(l.source[l.lines[line]:offset]) + 1
+	return line + 1, column
+}
@@ -0,0 +1,141 @@
+package templatelint_test
+
+import (
+	"errors"
+	"reportia/templatelint"
+	"strings"
+	"testing"
+)
+
+const slot = "<!-- DYNAMIC BODY CONTENT HERE -->"
+
+type issue struct {
+	severity string
+	code     string
+	line     int
+	column   int
+}
+
+func TestLint(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		template string
+		valid    bool
+		issues   []issue
+	}{
+		{
+			name:     "valid",
+			template: "<html><body><h1>{{DATE_TODAY}}</h1><img src=\"{{ASSET:logo}}\">" + slot + "</body></html>",
+			valid:    true,
+		},
+		{
+			name:     "optional end tags",
+			template: "<ul><li>one<li>two</ul><p>text\n" + slot,
+			valid:    true,
+		},
+		{
+			name:     "slot without whitespace and in lower case",
+			template: "<div><!--dynamic body content here--></div>",
+			valid:    true,
+		},
+		{
+			name:     "missing slot",
+			template: "<div></div>",
+			issues:   []issue{{templatelint.SeverityError, templatelint.CodeMissingBodySlot, 1, 1}},
+		},
+		{
+			name:     "duplicate slot",
+			template: slot + "\n  " + slot,
+			issues:   []issue{{templatelint.SeverityError, templatelint.CodeDuplicateSlot, 2, 3}},
+		},
+		{
+			name:     "unclosed tag",
+			template: slot + "\n<div><span>text</div>",
+			issues:   []issue{{templatelint.SeverityError, templatelint.CodeUnclosedTag, 2, 6}},
+		},
+		{
+			name:     "never closed",
+			template: slot + "\n<section>",
+			issues:   []issue{{templatelint.SeverityError, templatelint.CodeUnclosedTag, 2, 1}},
+		},
+		{
+			name:     "unexpected end tag",
+			template: slot + "</div><br></br>",
+			issues:   []issue{{templatelint.SeverityError, templatelint.CodeUnexpectedEndTag, 1, 35}},
+		},
+		{
+			name:     "unknown variable",
+			template: slot + "\n<p>{{ TOTAL }}</p>",
+			issues:   []issue{{templatelint.SeverityError, templatelint.CodeUnknownVariable, 2, 4}},
+		},
+		{
+			name:     "invalid asset name",
+			template: slot + "<img src=\"{{ASSET:Logo.png}}\">",
+			issues:   []issue{{templatelint.SeverityError, templatelint.CodeInvalidAsset, 1, 45}},
+		},
+		{
+			name:     "external resources",
+			template: slot + "\n<script src=\"https://cdn.example.com/a.js\"></script>\n<link href=\"//fonts.example.com/f.css\" rel=\"stylesheet\">\n<style>@import 'https://example.com/a.css';</style>\n<div style=\"background: url(http://example.com/bg.png)\"></div>",
+			valid:    true,
+			issues: []issue{
+				{templatelint.SeverityWarning, templatelint.CodeExternalResource, 2, 1},
+				{templatelint.SeverityWarning, templatelint.CodeExternalResource, 3, 1},
+				{templatelint.SeverityWarning, templatelint.CodeExternalResource, 4, 8},
+				{templatelint.SeverityWarning, templatelint.CodeExternalResource, 5, 1},
+			},
+		},
+		{
+			name:     "inline scripts",
+			template: slot + "\n<script>alert(1)</script><button onclick=\"go()\">go</button><script></script>",
+			valid:    true,
+			issues: []issue{
+				{templatelint.SeverityWarning, templatelint.CodeInlineScript, 2, 1},
+				{templatelint.SeverityWarning, templatelint.CodeInlineScript, 2, 26},
+			},
+		},
+		{
+			name:     "columns count characters",
+			template: "<p>été</p>{{NAME}}" + slot,
+			issues:   []issue{{templatelint.SeverityError, templatelint.CodeUnknownVariable, 1, 11}},
+		},
+		{
+			name:     "too large",
+			template: strings.Repeat(" ", templatelint.MaxSize+1),
+			issues:   []issue{{templatelint.SeverityError, templatelint.CodeTooLarge, 1, 1}},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			result := templatelint.Lint(tc.template)
+			if result.Valid != tc.valid {
+				t.Errorf("got valid %v, want %v", result.Valid, tc.valid)
+			}
+			got := make([]issue, len(result.Issues))
+			for i, is := range result.Issues {
+				got[i] = issue{is.Severity, is.Code, is.Line, is.Column}
+			}
+			if len(got) != len(tc.issues) {
+				t.Fatalf("got issues %v, want %v", got, tc.issues)
+			}
+			for i := range got {
+				if got[i] != tc.issues[i] {
+					t.Errorf("issue %d: got %v, want %v", i, got[i], tc.issues[i])
+				}
+			}
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+	if err := templatelint.Check(slot + "<script>alert(1)</script>"); err != nil {
+		t.Errorf("warnings only: got %v, want no error", err)
+	}
+
+	err := templatelint.Check("<p>{{NAME}}</p>")
+	var lintErr *templatelint.Error
+	if !errors.As(err, &lintErr) || len(lintErr.Issues) != 2 {
+		t.Fatalf("got %v, want two issues", err)
+	}
+	if want := "template is invalid: template has no"; !strings.HasPrefix(err.Error(), want) {
+		t.Errorf("got %q, want it to start with %q", err.Error(), want)
+	}
+}
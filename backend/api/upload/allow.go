@@ -0,0 +1,57 @@
+package upload
+
+import (
+	"fmt"
+	"path/filepath"
+	"reportia/apperror"
+	"strings"
+)
+
+// Allowed checks f against allowlist, whose entries are extensions such as
+// .csv and content types such as text/csv or text/*. When the list has
+// extensions the name of f must end with one of them, and when it has types
+// the sniffed type of f must match one of them.
+func Allowed(f *File, allowlist []string) error {
+	var types, extensions []string
+	for _, entry := range allowlist {
+		if strings.HasPrefix(entry, ".") {
+			extensions = append(extensions, entry)
+		} else {
+			types = append(types, entry)
+		}
+	}
+	ext := filepath.Ext(f.Name)
+	extOK := len(extensions) == 0
+	for _, allowed := range extensions {
+		extOK = extOK || strings.EqualFold(allowed, ext)
+	}
+	typeOK := len(types) == 0
+	for _, allowed := range types {
+		prefix, wildcard := strings.CutSuffix(allowed, "/*")
+		typeOK = typeOK || strings.EqualFold(allowed, f.ContentType) ||
+			wildcard && strings.HasPrefix(strings.ToLower(f.ContentType), strings.ToLower(prefix)+"/")
+	}
+	if !extOK || !typeOK {
+		return apperror.New(apperror.UnsupportedMediaType, "file_type_not_allowed",
+			fmt.Sprintf("%s (%s) is not accepted, allowed: %s", f.Name, f.ContentType, strings.Join(allowlist, ", ")))
+	}
+	return nil
+}
+
+// ValidAllowlist reports the first entry of allowlist that is neither an
+// extension nor a content type.
+func ValidAllowlist(allowlist []string) error {
+	for _, entry := range allowlist {
+		if ext, ok := strings.CutPrefix(entry, "."); ok {
+			if ext == "" || strings.ContainsAny(ext, "./\\ ") {
+				return fmt.Errorf("%q is not an extension such as .csv", entry)
+			}
+			continue
+		}
+		major, minor, ok := strings.Cut(entry, "/")
+		if !ok || major == "" || major == "*" || minor == "" || strings.ContainsAny(entry, " ;,") || strings.Contains(minor, "/") {
+			return fmt.Errorf("%q is not a content type such as text/csv or text/*", entry)
+		}
+	}
+	return nil
+}
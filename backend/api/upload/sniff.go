@@ -0,0 +1,180 @@
+package upload
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"reportia/apperror"
+	"strings"
+)
+
+// Content types that http.DetectContentType reports as text/plain or
+// application/zip, refined from the extension or the archive entries.
+const (
+	TypeText     = "text/plain"
+	TypeCSV      = "text/csv"
+	TypeTSV      = "text/tab-separated-values"
+	TypeMarkdown = "text/markdown"
+	TypeJSON     = "application/json"
+	TypeZip      = "application/zip"
+	TypeXLSX     = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	TypeDOCX     = "application/vnd.openxmlformats-officedocument.wordprocessingml.document"
+	TypePPTX     = "application/vnd.openxmlformats-officedocument.presentationml.presentation"
+)
+
+// extensionTypes are the types the content of a file must have when its
+// name has one of these extensions.
+var extensionTypes = map[string]string{
+	".txt":      TypeText,
+	".csv":      TypeCSV,
+	".tsv":      TypeTSV,
+	".md":       TypeMarkdown,
+	".markdown": TypeMarkdown,
+	".json":     TypeJSON,
+	".zip":      TypeZip,
+	".xlsx":     TypeXLSX,
+	".docx":     TypeDOCX,
+	".pptx":     TypePPTX,
+	".pdf":      "application/pdf",
+	".html":     "text/html",
+	".htm":      "text/html",
+	".xml":      "text/xml",
+	".png":      "image/png",
+	".jpg":      "image/jpeg",
+	".jpeg":     "image/jpeg",
+	".gif":      "image/gif",
+	".webp":     "image/webp",
+}
+
+// officeEntries identify office documents, which are zip archives.
+var officeEntries = []struct{ name, contentType string }{
+	{"xl/workbook.xml", TypeXLSX},
+	{"word/document.xml", TypeDOCX},
+	{"ppt/presentation.xml", TypePPTX},
+}
+
+// inspect sets the content type of f from its content and checks archives,
+// CSV and JSON files in full, so that no reader of the file meets a zip bomb
+// or a malformed table later on.
+func inspect(f *File, limits Limits) error {
+	if f.Size == 0 {
+		return apperror.Validationf("empty_file", "the file is empty")
+	}
+	head := make([]byte, 512)
+	n, err := io.ReadFull(f, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return err
+	}
+	contentType, _, _ := mime.ParseMediaType(http.DetectContentType(head[:n]))
+	ext := strings.ToLower(filepath.Ext(f.Name))
+
+	err = nil
+	switch contentType {
+	case TypeZip:
+		contentType, err = inspectZip(f, limits)
+	case TypeText:
+		if refined, ok := extensionTypes[ext]; ok && (strings.HasPrefix(refined, "text/") || refined == TypeJSON) {
+			contentType = refined
+		}
+		err = inspectText(f, contentType)
+	}
+	if err != nil {
+		return err
+	}
+	if expected, ok := extensionTypes[ext]; ok && expected != contentType {
+		return apperror.New(apperror.UnsupportedMediaType, "content_type_mismatch",
+			fmt.Sprintf("%s has the %s extension but its content is %s", f.Name, ext, contentType))
+	}
+	f.ContentType = contentType
+	_, err = f.Seek(0, io.SeekStart)
+	return err
+}
+
+// inspectZip decompresses every entry, since the sizes an archive declares
+// can lie, stopping at the limits.
+func inspectZip(f *File, limits Limits) (string, error) {
+	archive, err := zip.NewReader(f, f.Size)
+	if err != nil {
+		return "", apperror.Wrap(apperror.Validation, "malformed_zip", err)
+	}
+	if len(archive.File) > limits.MaxZipEntries {
+		return "", apperror.Validationf("archive_too_many_entries",
+			"the archive has %d entries, the maximum is %d", len(archive.File), limits.MaxZipEntries)
+	}
+	tooLarge := apperror.New(apperror.TooLarge, "archive_too_large",
+		fmt.Sprintf("the archive expands to more than %d bytes", limits.MaxUncompressedSize))
+	remaining := limits.MaxUncompressedSize
+	for _, entry := range archive.File {
+		if entry.UncompressedSize64 > uint64(remaining) {
+			return "", tooLarge
+		}
+		content, err := entry.Open()
+		if err != nil {
+			return "", apperror.Wrap(apperror.Validation, "malformed_zip", err)
+		}
+		n, err := io.Copy(io.Discard, io.LimitReader(content, remaining+1))
+		content.Close()
+		if err != nil {
+			return "", apperror.Wrap(apperror.Validation, "malformed_zip", err)
+		}
+		if remaining -= n; remaining < 0 {
+			return "", tooLarge
+		}
+	}
+
+	for _, office := range officeEntries {
+		for _, entry := range archive.File {
+			if entry.Name == office.name {
+				return office.contentType, nil
+			}
+		}
+	}
+	return TypeZip, nil
+}
+
+// inspectText parses CSV, TSV and JSON files in full.
+func inspectText(f *File, contentType string) error {
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	switch contentType {
+	case TypeCSV, TypeTSV:
+		reader := csv.NewReader(bufio.NewReader(f))
+		if contentType == TypeTSV {
+			reader.Comma = '\t'
+		}
+		reader.ReuseRecord = true
+		for {
+			_, err := reader.Read()
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				return apperror.Validationf("malformed_csv", "%s is not valid CSV: %v", f.Name, parseErr)
+			}
+			if err != nil {
+				return err
+			}
+		}
+	case TypeJSON:
+		dec := json.NewDecoder(bufio.NewReader(f))
+		for {
+			_, err := dec.Token()
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			if err != nil {
+				return apperror.Validationf("malformed_json", "%s is not valid JSON: %v", f.Name, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+// Package upload receives the files posted as multipart forms. The file is
+// streamed to a temporary file rather than held in memory, its type is
+// sniffed from the content instead of trusted from the client, and archives
+// and tabular files are checked before anything else reads them.
+package upload
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reportia/apperror"
+)
+
+const (
+	// maxFormSize bounds the fields sent along with the file.
+	maxFormSize = 1 << 20
+	// maxFields bounds their number.
+	maxFields = 100
+)
+
+// Limits bound what Receive accepts.
+type Limits struct {
+	// MaxSize is the largest file accepted, in bytes.
+	MaxSize int64
+	// MaxZipEntries and MaxUncompressedSize bound zip archives, including
+	// office documents, once decompressed.
+	MaxZipEntries       int
+	MaxUncompressedSize int64
+}
+
+// File is an uploaded file stored in a temporary file, which Close removes.
+type File struct {
+	// Name is the base name sent by the client.
+	Name string
+	// ContentType is sniffed from the content and the extension.
+	ContentType string
+	Size        int64
+	f           *os.File
+}
+
+func (f *File) Read(p []byte) (int, error) { return f.f.Read(p) }
+
+func (f *File) ReadAt(p []byte, off int64) (int, error) { return f.f.ReadAt(p, off) }
+
+func (f *File) Seek(offset int64, whence int) (int64, error) { return f.f.Seek(offset, whence) }
+
+// Close removes the temporary file.
+func (f *File) Close() error {
+	return errors.Join(f.f.Close(), os.Remove(f.f.Name()))
+}
+
+// Form holds the fields of a multipart form and its file, if one was sent.
+type Form struct {
+	Values url.Values
+	File   *File
+}
+
+func (f *Form) Close() error {
+	if f.File == nil {
+		return nil
+	}
+	return f.File.Close()
+}
+
+// errTooLarge stops the copy of a file past Limits.MaxSize.
+var errTooLarge = errors.New("file too large")
+
+// Receive reads the multipart form of r, storing the file sent as field in a
+// temporary file and checking it. The caller must close the returned form.
+// Errors are apperror errors with 4xx kinds, except for I/O failures on the
+// server.
+func Receive(w http.ResponseWriter, r *http.Request, field string, limits Limits) (form *Form, err error) {
+	r.Body = http.MaxBytesReader(w, r.Body, limits.MaxSize+maxFormSize)
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return nil, apperror.Wrap(apperror.Validation, "invalid_multipart", err)
+	}
+
+	form = &Form{Values: url.Values{}}
+	defer func() {
+		if err != nil {
+			form.Close()
+			form = nil
+		}
+	}()
+	var formSize int64
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return form, multipartError(err, limits)
+		}
+		if part.FormName() == field && part.FileName() != "" {
+			if form.File != nil {
+				return form, apperror.Validationf("too_many_files", "only one file may be sent as %s", field)
+			}
+			if form.File, err = store(part, limits.MaxSize); err != nil {
+				return form, multipartError(err, limits)
+			}
+			continue
+		}
+		if part.FileName() != "" {
+			return form, apperror.Validationf("unexpected_file", "unexpected file in field %q", part.FormName())
+		}
+		value, err := io.ReadAll(io.LimitReader(part, maxFormSize-formSize+1))
+		if err != nil {
+			return form, multipartError(err, limits)
+		}
+		formSize += int64(len(value))
+		if formSize > maxFormSize || len(form.Values) >= maxFields {
+			return form, apperror.Validationf("form_too_large", "the form fields are too large")
+		}
+		form.Values.Add(part.FormName(), string(value))
+	}
+
+	if form.File != nil {
+		if err := inspect(form.File, limits); err != nil {
+			return form, err
+		}
+	}
+	return form, nil
+}
+
+// store copies part to a temporary file, up to maxSize bytes.
+func store(part *multipart.Part, maxSize int64) (*File, error) {
+	f, err := os.CreateTemp("", "reportia-upload-*")
+	if err != nil {
+		return nil, err
+	}
+	file := &File{Name: filepath.Base(part.FileName()), f: f}
+	file.Size, err = io.Copy(f, io.LimitReader(part, maxSize+1))
+	if err == nil && file.Size > maxSize {
+		err = errTooLarge
+	}
+	if err == nil {
+		_, err = f.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return file, nil
+}
+
+// multipartError classifies an error met while reading the request body.
+// Failures of the temporary file are the server's; the others come from a
+// malformed or truncated request.
+func multipartError(err error, limits Limits) error {
+	var maxBytesErr *http.MaxBytesError
+	var pathErr *os.PathError
+	switch {
+	case errors.Is(err, errTooLarge), errors.As(err, &maxBytesErr):
+		return apperror.New(apperror.TooLarge, "file_too_large", fmt.Sprintf("file is too large, the maximum is %d bytes", limits.MaxSize))
+	case errors.As(err, &pathErr):
+		return err
+	}
+	return apperror.Wrap(apperror.Validation, "invalid_multipart", err)
+}